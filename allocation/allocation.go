@@ -0,0 +1,82 @@
+package allocation
+
+import (
+	"cmp"
+	"fmt"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"slices"
+)
+
+// Strategies are the supported allocation strategies
+var Strategies = []string{models.AllocatePriority, models.AllocateNearest}
+
+// Order sorts warehouses in the order stock is allocated from them with strategy: by priority,
+// or nearest to region first and then by priority. Ties are broken by ID, so plans are stable.
+func Order(warehouses []models.Warehouse, strategy, region string) error {
+	byPriority := func(a, b models.Warehouse) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.ID, b.ID))
+	}
+
+	switch strategy {
+	case models.AllocatePriority:
+		slices.SortStableFunc(warehouses, byPriority)
+	case models.AllocateNearest:
+		slices.SortStableFunc(warehouses, func(a, b models.Warehouse) int {
+			return cmp.Or(cmp.Compare(models.RegionAffinity(b.Region, region), models.RegionAffinity(a.Region, region)), byPriority(a, b))
+		})
+	default:
+		return fmt.Errorf("unknown allocation strategy %q", strategy)
+	}
+
+	return nil
+}
+
+// Plan picks the warehouses to take the quantity of request from, given the warehouses and
+// the stock of the product in each of them. Warehouses are tried in the order of the
+// strategy. Without split, the first one with the whole quantity available is picked.
+// With split, the available stock of each is taken until the quantity is reached.
+func Plan(warehouses []models.Warehouse, stock []models.WarehouseStock, request models.AllocationRequest) ([]models.Allocation, error) {
+	split := request.Split != nil && *request.Split
+
+	ordered := slices.Clone(warehouses)
+	if err := Order(ordered, request.Strategy, request.Region); err != nil {
+		return nil, err
+	}
+
+	available := make(map[string]int64, len(stock))
+	var total int64
+	for _, s := range stock {
+		available[s.WarehouseID] = s.Available
+	}
+	for _, warehouse := range ordered {
+		total += max(available[warehouse.ID], 0)
+	}
+	if total < request.Quantity {
+		return nil, custom_errors.ErrInsufficientStock
+	}
+
+	allocations := make([]models.Allocation, 0)
+	remaining := request.Quantity
+	for _, warehouse := range ordered {
+		quantity := available[warehouse.ID]
+		if quantity <= 0 {
+			continue
+		}
+
+		if !split {
+			if quantity >= request.Quantity {
+				return []models.Allocation{{WarehouseID: warehouse.ID, Quantity: request.Quantity}}, nil
+			}
+			continue
+		}
+
+		quantity = min(quantity, remaining)
+		allocations = append(allocations, models.Allocation{WarehouseID: warehouse.ID, Quantity: quantity})
+		if remaining -= quantity; remaining == 0 {
+			return allocations, nil
+		}
+	}
+
+	return nil, custom_errors.ErrNoSingleWarehouse
+}
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"simpler-products/config"
+)
+
+// Run executes the subcommand named by args[0], writing its output to out
+func Run(cfg *config.Config, args []string, out io.Writer) error {
+	switch args[0] {
+	case "migrate":
+		return Migrate(cfg, args[1:], out)
+	case "import":
+		return Import(cfg, args[1:], out)
+	case "export":
+		return Export(cfg, args[1:], out)
+	default:
+		return fmt.Errorf("unknown command %q, available commands: migrate, import, export", args[0])
+	}
+}
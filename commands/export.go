@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"simpler-products/config"
+	"simpler-products/exporter"
+	"simpler-products/models"
+	"simpler-products/services"
+	"simpler-products/validators"
+	"slices"
+	"strings"
+)
+
+const exportUsage = "usage: export [-format csv|tsv|ndjson] [-name NAME] [-category ID] [-price-min N] [-price-max N] [-sort FIELDS] [-o FILE]"
+
+// exportFilterFlags maps the filter and sort flags of the export command to the listing parameters
+var exportFilterFlags = map[string]string{
+	"name":      "name",
+	"category":  "category",
+	"price-min": "price_min",
+	"price-max": "price_max",
+	"sort":      "sort",
+}
+
+func Export(cfg *config.Config, args []string, out io.Writer) error {
+	if cfg.DB == nil {
+		return errors.New("exports require a SQL database, DB_DRIVER is set to memory")
+	}
+
+	productsService, ok := cfg.Services.(services.ProductsServiceInterface)
+	if !ok {
+		return errors.New("ProductsServiceInterface not found in services")
+	}
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", models.FormatCSV, "format of the file, csv, tsv or ndjson")
+	path := flags.String("o", "-", "file to write, - writing to stdout")
+	for name := range exportFilterFlags {
+		flags.String(name, "", "same as the "+exportFilterFlags[name]+" parameter of product listings")
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errors.New(exportUsage)
+	}
+	if !slices.Contains(exporter.Formats, *format) {
+		return fmt.Errorf("unknown export format %q, format must be one of: %s", *format, strings.Join(exporter.Formats, ", "))
+	}
+
+	// Filters are validated like the parameters of the listing endpoint
+	params := url.Values{}
+	flags.Visit(func(f *flag.Flag) {
+		if param, ok := exportFilterFlags[f.Name]; ok {
+			params.Set(param, f.Value.String())
+		}
+	})
+	query, invalid := validators.ParseProductQuery(params)
+	if len(invalid) > 0 {
+		messages := make([]string, 0, len(invalid))
+		for _, e := range invalid {
+			messages = append(messages, e["message"])
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+
+	file := out
+	if *path != "-" {
+		f, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		file = f
+	}
+
+	// Stop on Ctrl+C, leaving the file incomplete
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ex := &exporter.Exporter{Service: productsService}
+	exported, err := ex.Export(ctx, file, *format, query.Filter, query.Sort)
+	if err != nil {
+		return err
+	}
+
+	if *path != "-" {
+		fmt.Fprintf(out, "Exported %d products to %s\n", exported, *path)
+	}
+
+	return nil
+}
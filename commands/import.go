@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"simpler-products/config"
+	"simpler-products/importer"
+	"simpler-products/models"
+	"simpler-products/services"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+const importUsage = "usage: import [-format csv|ndjson] [-batch-size N] FILE, - reading from stdin"
+
+// importFormats maps the extensions of the imported files to their format
+var importFormats = map[string]string{
+	".csv":    models.FormatCSV,
+	".ndjson": models.FormatNDJSON,
+	".jsonl":  models.FormatNDJSON,
+}
+
+func Import(cfg *config.Config, args []string, out io.Writer) error {
+	if cfg.DB == nil {
+		return errors.New("imports require a SQL database, DB_DRIVER is set to memory")
+	}
+
+	productsService, ok := cfg.Services.(services.ProductsServiceInterface)
+	if !ok {
+		return errors.New("ProductsServiceInterface not found in services")
+	}
+
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", "", "format of the file, csv or ndjson, guessed from its extension by default")
+	batchSize := flags.Int("batch-size", importer.DefaultBatchSize, "number of rows upserted at once")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *batchSize <= 0 {
+		return errors.New(importUsage)
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = importFormats[strings.ToLower(filepath.Ext(path))]
+	}
+	if !slices.Contains(importer.Formats, *format) {
+		return fmt.Errorf("unknown import format, set -format to one of: %s", strings.Join(importer.Formats, ", "))
+	}
+
+	var file io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		file = f
+	}
+
+	// Stop between batches on Ctrl+C, the rows imported so far are kept
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	im := &importer.Importer{Service: productsService, BatchSize: *batchSize}
+	report, err := im.Import(ctx, file, *format)
+	if report != nil {
+		if err := printImportReport(out, report); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d rows were not imported", report.Failed, report.Rows)
+	}
+
+	return nil
+}
+
+// printImportReport writes the totals of the import and the errors of the failed rows
+func printImportReport(out io.Writer, report *importer.Report) error {
+	fmt.Fprintf(out, "Imported %d of %d rows: %d created, %d updated, %d failed\n",
+		report.Created+report.Updated, report.Rows, report.Created, report.Updated, report.Failed)
+	if len(report.Errors) == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tID\tERRORS")
+	for _, rowError := range report.Errors {
+		messages := make([]string, 0, len(rowError.Errors))
+		for _, e := range rowError.Errors {
+			messages = append(messages, e["message"])
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", rowError.Line, rowError.ID, strings.Join(messages, "; "))
+	}
+	if report.Failed > len(report.Errors) {
+		fmt.Fprintf(w, "...\t\t%d more rows failed\n", report.Failed-len(report.Errors))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"simpler-products/config"
+	"simpler-products/migrations"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: migrate up | migrate down N | migrate status"
+
+// migrateTimeout bounds how long a migration run, including waiting for the lock, may take
+const migrateTimeout = 5 * time.Minute
+
+func Migrate(cfg *config.Config, args []string, out io.Writer) error {
+	if cfg.DB == nil {
+		return errors.New("migrations require a SQL database, DB_DRIVER is set to memory")
+	}
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := migrations.NewMigrator(cfg.DB, cfg.Dialect.Name, cfg.Log)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Applied %d migrations\n", applied)
+	case "down":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of migrations to revert %q, must be a positive number", args[1])
+		}
+
+		reverted, err := migrator.Down(ctx, n)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Reverted %d migrations\n", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, status.AppliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
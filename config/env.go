@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// envInt reads a non-negative integer environment variable, falling back to def when unset
+func envInt(key string, def int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %q, must be a non-negative integer", key, value)
+	}
+
+	return n, nil
+}
+
+// envDuration reads a non-negative duration environment variable such as '5s', falling back to def when unset
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s: %q, must be a non-negative duration such as '5s'", key, value)
+	}
+
+	return d, nil
+}
+
+// envPositiveDuration reads a duration environment variable like envDuration, for the delays
+// that cannot be zero, such as the pauses of a polling loop
+func envPositiveDuration(key string, def time.Duration) (time.Duration, error) {
+	d, err := envDuration(key, def)
+	if err == nil && d == 0 {
+		return 0, fmt.Errorf("invalid %s: %q, must be a positive duration such as '5s'", key, os.Getenv(key))
+	}
+
+	return d, err
+}
@@ -1,29 +1,60 @@
 package config
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"os"
+	"simpler-products/allocation"
 	"simpler-products/database"
+	"simpler-products/idempotency"
+	"simpler-products/metrics"
+	"simpler-products/migrations"
+	"simpler-products/repositories"
+	"simpler-products/search"
 	"simpler-products/services"
+	"simpler-products/tracing"
+	"slices"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
+const (
+	defaultQueryTimeout       = 10 * time.Second
+	defaultHealthCheckTimeout = 2 * time.Second
+)
+
 // Define a generic interface for any service that might be used by the application
 type ServiceContainer interface{}
 
 type Config struct {
-	Port     string
-	DB       *sql.DB
-	Services ServiceContainer
-	Log      *logrus.Logger
+	Port      string
+	DB        *sql.DB
+	DBManager *database.Manager
+	Dialect   repositories.Dialect
+	Services  ServiceContainer
+	Log       *logrus.Logger
+
+	// ShutdownTracing flushes the pending spans, call it on shutdown
+	ShutdownTracing tracing.ShutdownFunc
 }
 
+// Init sets up the application for serving requests
 func Init(log *logrus.Logger) (*Config, error) {
+	return initialize(log, true)
+}
+
+// InitCommand sets up the application for running a subcommand. The schema is left as it is,
+// for 'migrate' to report and change it, and no search index is built.
+func InitCommand(log *logrus.Logger) (*Config, error) {
+	return initialize(log, false)
+}
+
+func initialize(log *logrus.Logger, serve bool) (*Config, error) {
 	// Load environment variables from .env
 	err := godotenv.Load()
 	if err != nil {
@@ -38,6 +69,12 @@ func Init(log *logrus.Logger) (*Config, error) {
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
 	dbName := os.Getenv("DB_NAME")
+	dbDriver := os.Getenv("DB_DRIVER")
+	dbSSLMode := os.Getenv("DB_SSLMODE")
+	autoMigrate := os.Getenv("AUTO_MIGRATE")
+	tracingExporter := os.Getenv("TRACING_EXPORTER")
+	allocationStrategy := os.Getenv("ALLOCATION_STRATEGY")
+	allocationSplit := os.Getenv("ALLOCATION_SPLIT")
 
 	// Set Gin mode and stdout logs based on log level
 	switch logLevel {
@@ -64,26 +101,234 @@ func Init(log *logrus.Logger) (*Config, error) {
 		log.SetLevel(logrus.InfoLevel)
 	}
 
-	// Database setup
-	db, err := database.Init(log, dbUser, dbPassword, dbHost, dbPort, dbName)
+	// Tracing setup, log entries created with a context carry its trace ID
+	shutdownTracing, err := tracing.Init(context.Background(), tracingExporter, log)
+	if err != nil {
+		return nil, err
+	}
+	log.AddHook(tracing.LogrusHook{})
+
+	// Per-request timeout for storage calls
+	queryTimeout, err := envDuration("QUERY_TIMEOUT", defaultQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Timeout of each readiness check
+	healthCheckTimeout, err := envDuration("HEALTH_CHECK_TIMEOUT", defaultHealthCheckTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// How long responses to requests sent with an Idempotency-Key are replayed
+	idempotencyKeyTTL, err := envDuration("IDEMPOTENCY_KEY_TTL", idempotency.DefaultTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Number of suggestions returned at most
+	maxSuggestions, err := envInt("SUGGEST_MAX_RESULTS", services.DefaultMaxSuggestions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Default strategy of the stock allocations, by priority when unset
+	if allocationStrategy != "" && !slices.Contains(allocation.Strategies, allocationStrategy) {
+		return nil, fmt.Errorf("unsupported ALLOCATION_STRATEGY: %s", allocationStrategy)
+	}
+
+	// Connection pool and health watchdog settings
+	dbOptions := database.DefaultOptions()
+	if dbOptions.MaxOpenConns, err = envInt("DB_MAX_OPEN_CONNS", dbOptions.MaxOpenConns); err != nil {
+		return nil, err
+	}
+	if dbOptions.MaxIdleConns, err = envInt("DB_MAX_IDLE_CONNS", dbOptions.MaxIdleConns); err != nil {
+		return nil, err
+	}
+	if dbOptions.ConnMaxLifetime, err = envDuration("DB_CONN_MAX_LIFETIME", dbOptions.ConnMaxLifetime); err != nil {
+		return nil, err
+	}
+	if dbOptions.ConnMaxIdleTime, err = envDuration("DB_CONN_MAX_IDLE_TIME", dbOptions.ConnMaxIdleTime); err != nil {
+		return nil, err
+	}
+	if dbOptions.HealthCheckInterval, err = envPositiveDuration("DB_HEALTH_CHECK_INTERVAL", dbOptions.HealthCheckInterval); err != nil {
+		return nil, err
+	}
+	if dbOptions.MaxBackoff, err = envPositiveDuration("DB_RECONNECT_MAX_BACKOFF", dbOptions.MaxBackoff); err != nil {
+		return nil, err
+	}
+
+	// Storage setup
+	var dbManager *database.Manager
+	var dialect repositories.Dialect
+	switch dbDriver {
+	case "memory":
+		log.Info("Using in-memory storage, data will not be persisted")
+	case "", "mysql":
+		dbManager, err = database.Init(log, "mysql", database.MySQLDSN(dbUser, dbPassword, dbHost, dbPort, dbName), dbOptions)
+		dialect = repositories.MySQL
+	case "postgres":
+		dbManager, err = database.Init(log, database.PostgresDriver, database.PostgresDSN(dbUser, dbPassword, dbHost, dbPort, dbName, dbSSLMode), dbOptions)
+		dialect = repositories.Postgres
+	case "sqlite":
+		// DB_NAME holds the path of the database file
+		dbManager, err = database.Init(log, "sqlite", database.SQLiteDSN(dbName), dbOptions)
+		dialect = repositories.SQLite
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER: %s", dbDriver)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	var db *sql.DB
+	if dbManager != nil {
+		db = dbManager.DB
+	}
+
+	var productRepository repositories.ProductRepository
+	var categoryRepository repositories.CategoryRepository
+	var warehouseRepository repositories.WarehouseRepository
+	var variantRepository repositories.VariantRepository
+	var stockRepository repositories.StockRepository
+	if db != nil {
+		productRepository = repositories.NewSQLProductRepository(db, dialect)
+		categoryRepository = repositories.NewSQLCategoryRepository(db, dialect)
+		warehouseRepository = repositories.NewSQLWarehouseRepository(db, dialect)
+		variantRepository = repositories.NewSQLVariantRepository(db, dialect)
+		stockRepository = repositories.NewSQLStockRepository(db, dialect)
+	} else {
+		productRepository = repositories.NewInMemoryProductRepository()
+		categoryRepository = repositories.NewInMemoryCategoryRepository()
+		warehouseRepository = repositories.NewInMemoryWarehouseRepository()
+		variantRepository = repositories.NewInMemoryVariantRepository()
+		stockRepository = repositories.NewInMemoryStockRepository(productRepository, warehouseRepository, variantRepository)
+	}
+
+	// Apply pending schema migrations on startup when enabled. SQLite files migrate by default,
+	// so that a new file serves requests without any setup.
+	if autoMigrate == "" && dbDriver == "sqlite" {
+		autoMigrate = "true"
+	}
+	if serve && autoMigrate == "true" && db != nil {
+		migrator, err := migrations.NewMigrator(db, dialect.Name, log)
+		if err != nil {
+			return nil, err
+		}
+
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Errorf("Error applying migrations: %v", err)
+			return nil, err
+		}
+		log.Infof("Applied %d migrations", applied)
+	}
+
+	// Full-text search index, kept in sync by the products service from now on
+	searchService := &services.SearchService{
+		Index:          search.NewIndex(),
+		Log:            log,
+		MaxSuggestions: maxSuggestions,
+	}
+	if serve {
+		indexed, err := searchService.BuildIndex(context.Background(), productRepository)
+		if err != nil {
+			// e.g. the schema is not migrated yet, search results stay empty until a restart
+			log.Errorf("Error building the search index: %v", err)
+		} else {
+			log.Infof("Indexed %d products for search", indexed)
+		}
+	}
+
+	// Readiness checks for the storage backend
+	healthService := &services.HealthService{
+		Log:     log,
+		Timeout: healthCheckTimeout,
+	}
+	if db != nil {
+		migrator, err := migrations.NewMigrator(db, dialect.Name, log)
+		if err != nil {
+			return nil, err
+		}
+
+		healthService.RegisterCheck(services.DatabaseHealthCheck(dbManager))
+		healthService.RegisterCheck(services.MigrationsHealthCheck(migrator))
+	}
+
+	// Prometheus collectors, including the connection pool statistics
+	recorder := metrics.NewRecorder()
+	if db != nil {
+		if err := recorder.RegisterDB(db, dialect.Name); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create services and store them in a struct implementing ServiceContainer
 	services := struct {
 		services.ProductsServiceInterface
+		services.CategoriesServiceInterface
+		services.InventoryServiceInterface
+		services.WarehousesServiceInterface
+		services.VariantsServiceInterface
+		services.HealthServiceInterface
+		services.SearchServiceInterface
+		metrics.RecorderInterface
+		idempotency.StoreInterface
 	}{
 		&services.ProductsService{
-			DB:  db,
-			Log: log,
+			Repo:         productRepository,
+			Log:          log,
+			QueryTimeout: queryTimeout,
+			Observer:     recorder,
+			Index:        searchService.Index,
+			Categories:   categoryRepository,
+		},
+		&services.CategoriesService{
+			Repo:         categoryRepository,
+			Products:     productRepository,
+			Log:          log,
+			QueryTimeout: queryTimeout,
+			Observer:     recorder,
 		},
+		&services.InventoryService{
+			Repo:               stockRepository,
+			Warehouses:         warehouseRepository,
+			Variants:           variantRepository,
+			Log:                log,
+			QueryTimeout:       queryTimeout,
+			Observer:           recorder,
+			AllocationStrategy: allocationStrategy,
+			AllocationSplit:    allocationSplit == "true",
+		},
+		&services.WarehousesService{
+			Repo:         warehouseRepository,
+			Stock:        stockRepository,
+			Log:          log,
+			QueryTimeout: queryTimeout,
+			Observer:     recorder,
+		},
+		&services.VariantsService{
+			Repo:         variantRepository,
+			Products:     productRepository,
+			Stock:        stockRepository,
+			Log:          log,
+			QueryTimeout: queryTimeout,
+			Observer:     recorder,
+		},
+		healthService,
+		searchService,
+		recorder,
+		idempotency.NewStore(idempotencyKeyTTL),
 	}
 
 	return &Config{
-		Port:     port,
-		DB:       db,
-		Services: services,
-		Log:      log,
+		Port:      port,
+		DB:        db,
+		DBManager: dbManager,
+		Dialect:   dialect,
+		Services:  services,
+		Log:       log,
+
+		ShutdownTracing: shutdownTracing,
 	}, nil
 }
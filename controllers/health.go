@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"net/http"
+	"simpler-products/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Liveness reports that the process is up and able to serve requests
+func Liveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Set("data", gin.H{"status": services.HealthStatusUp})
+	}
+}
+
+// Readiness reports whether the dependencies needed to serve traffic are healthy
+func Readiness(hs services.HealthServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, ready := hs.CheckReadiness(c.Request.Context())
+		if !ready {
+			c.Status(http.StatusServiceUnavailable)
+		}
+
+		c.Set("data", report)
+	}
+}
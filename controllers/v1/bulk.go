@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/services"
+	"simpler-products/validators"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bulkItem is the result of a bulk operation, at position Index in the request
+type bulkItem struct {
+	Index   int                 `json:"index"`
+	Op      string              `json:"op"`
+	ID      string              `json:"id,omitempty"`
+	Status  int                 `json:"status"`
+	Product *models.Product     `json:"product,omitempty"`
+	Errors  []map[string]string `json:"errors,omitempty"`
+}
+
+// bulkSuccessStatus is the status of each kind of bulk operation when it succeeds
+var bulkSuccessStatus = map[string]int{
+	models.BulkCreate: http.StatusCreated,
+	models.BulkUpdate: http.StatusOK,
+	models.BulkDelete: http.StatusNoContent,
+}
+
+func BulkProducts(ps services.ProductsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request, err := validators.ValidateBulkRequest(c)
+		if err != nil {
+			return
+		}
+		atomic := request.Mode == models.BulkAllOrNothing
+
+		// Report the invalid operations, and apply the valid ones
+		items := make([]bulkItem, len(request.Operations))
+		valid := make([]models.BulkOperation, 0, len(request.Operations))
+		positions := make([]int, 0, len(request.Operations))
+		for i, operation := range request.Operations {
+			items[i] = bulkItem{Index: i, Op: operation.Op, ID: operation.ID}
+			if invalid, ok := request.Invalid[i]; ok {
+				items[i].Status = http.StatusBadRequest
+				items[i].Errors = invalid.Errors
+				continue
+			}
+			valid = append(valid, operation)
+			positions = append(positions, i)
+		}
+
+		// All or nothing, a single invalid operation fails the request before any is applied
+		if atomic && len(request.Invalid) > 0 {
+			for _, i := range positions {
+				items[i].fail(custom_errors.ErrBulkRolledBack)
+			}
+			c.Status(http.StatusBadRequest)
+			c.Set("data", items)
+			return
+		}
+
+		results, err := ps.BulkProducts(c.Request.Context(), valid, atomic)
+		if err != nil {
+			c.Set("errors", err)
+			return
+		}
+
+		status := http.StatusOK
+		for k, result := range results {
+			item := &items[positions[k]]
+			if result.Err != nil {
+				item.fail(result.Err)
+				// A failed transaction is reported with the status of the operation that failed it
+				if atomic && !errors.Is(result.Err, custom_errors.ErrBulkRolledBack) {
+					status = item.Status
+				}
+				continue
+			}
+
+			item.Status = bulkSuccessStatus[item.Op]
+			if result.Product != nil {
+				item.ID = result.Product.ID
+				item.Product = result.Product
+			}
+		}
+
+		c.Status(status)
+		c.Set("data", items)
+	}
+}
+
+// fail reports the error of the operation with its status
+func (item *bulkItem) fail(err error) {
+	switch {
+	case errors.Is(err, custom_errors.ErrProductNotFound):
+		item.Status = http.StatusNotFound
+	case errors.Is(err, custom_errors.ErrVersionMismatch):
+		item.Status = http.StatusPreconditionFailed
+	case errors.Is(err, custom_errors.ErrBulkRolledBack):
+		item.Status = http.StatusFailedDependency
+	default:
+		item.Status = http.StatusInternalServerError
+	}
+	item.Errors = []map[string]string{{"message": err.Error()}}
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/services"
+	"simpler-products/validators"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetAllCategories(cs services.CategoriesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categories, err := cs.ListCategories(c.Request.Context())
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+
+		c.Set("data", categories)
+	}
+}
+
+func GetCategoryTree(cs services.CategoriesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tree, err := cs.GetCategoryTree(c.Request.Context())
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+
+		c.Set("data", tree)
+	}
+}
+
+func GetCategoryById(cs services.CategoriesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateCategoryID(c)
+		if err != nil {
+			return
+		}
+
+		category, err := cs.GetCategory(c.Request.Context(), id)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+
+		c.Set("data", [1]*models.Category{category})
+	}
+}
+
+func AddCategory(cs services.CategoriesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		category, err := validators.ValidateCategory(c)
+		if err != nil {
+			return
+		}
+
+		if err := cs.AddCategory(c.Request.Context(), category); err != nil {
+			categoryError(c, err)
+			return
+		}
+
+		c.Status(http.StatusCreated)
+		c.Set("data", [1]*models.Category{category})
+	}
+}
+
+func UpdateCategory(cs services.CategoriesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateCategoryID(c)
+		if err != nil {
+			return
+		}
+
+		category, err := validators.ValidateCategory(c)
+		if err != nil {
+			return
+		}
+
+		updatedCategory, err := cs.UpdateCategory(c.Request.Context(), id, category)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+
+		c.Set("data", [1]*models.Category{updatedCategory})
+	}
+}
+
+func DeleteCategory(cs services.CategoriesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateCategoryID(c)
+		if err != nil {
+			return
+		}
+
+		if err := cs.DeleteCategory(c.Request.Context(), id); err != nil {
+			categoryError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func GetProductCategories(cs services.CategoriesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		categories, err := cs.GetProductCategories(c.Request.Context(), id)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+
+		c.Set("data", categories)
+	}
+}
+
+func SetProductCategories(cs services.CategoriesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		categoryIDs, err := validators.ValidateProductCategories(c)
+		if err != nil {
+			return
+		}
+
+		categories, err := cs.SetProductCategories(c.Request.Context(), id, categoryIDs)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+
+		c.Set("data", categories)
+	}
+}
+
+// categoryError sets the error of a request on categories, with its status when a category
+// or product is missing, or the change would break the category tree
+func categoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, custom_errors.ErrCategoryNotFound), errors.Is(err, custom_errors.ErrProductNotFound):
+		c.Status(http.StatusNotFound)
+	case errors.Is(err, custom_errors.ErrParentCategoryNotFound),
+		errors.Is(err, custom_errors.ErrCategoryCycle),
+		errors.Is(err, custom_errors.ErrUnknownCategories):
+		c.Status(http.StatusBadRequest)
+	case errors.Is(err, custom_errors.ErrCategoryNotEmpty):
+		c.Status(http.StatusConflict)
+	}
+	c.Set("errors", err)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"simpler-products/exporter"
+	"simpler-products/services"
+	"simpler-products/validators"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ExportProducts(ps services.ProductsServiceInterface) gin.HandlerFunc {
+	ex := &exporter.Exporter{Service: ps}
+
+	return func(c *gin.Context) {
+		format, err := validators.ValidateExportFormat(c)
+		if err != nil {
+			return
+		}
+
+		// The same filters and sort as the listing, without pagination
+		query, err := validators.ValidateProductQuery(c)
+		if err != nil {
+			return
+		}
+
+		w := &exportWriter{c: c, format: format}
+		if _, err := ex.Export(c.Request.Context(), w, format, query.Filter, query.Sort); err != nil {
+			// Once the export is under way, failing can only cut it short
+			if !w.started {
+				productError(c, err)
+			}
+			return
+		}
+
+		// Exports without products are sent too
+		w.start()
+	}
+}
+
+// exportWriter sends the headers of an export along with its first bytes, so that the errors
+// met before any product is read are still reported in the response envelope
+type exportWriter struct {
+	c       *gin.Context
+	format  string
+	started bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.start()
+	return w.c.Writer.Write(p)
+}
+
+func (w *exportWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	filename := fmt.Sprintf("products-%s.%s", time.Now().UTC().Format("20060102T150405Z"), w.format)
+	w.c.Header("Content-Type", exporter.ContentTypes[w.format])
+	w.c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.c.Status(http.StatusOK)
+	w.c.Writer.WriteHeaderNow()
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/importer"
+	"simpler-products/services"
+	"simpler-products/validators"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ImportProducts(ps services.ProductsServiceInterface) gin.HandlerFunc {
+	im := &importer.Importer{Service: ps}
+
+	return func(c *gin.Context) {
+		format, err := validators.ValidateImportFormat(c)
+		if err != nil {
+			return
+		}
+
+		report, err := im.Import(c.Request.Context(), c.Request.Body, format)
+		if err != nil {
+			if errors.Is(err, custom_errors.ErrInvalidImportFile) {
+				c.Status(http.StatusBadRequest)
+			}
+			c.Set("errors", err)
+			return
+		}
+
+		// Set data in the context
+		c.Set("data", [1]*importer.Report{report})
+	}
+}
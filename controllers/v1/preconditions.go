@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag returns the entity tag of a product, its quoted version
+func etag(product *models.Product) string {
+	return strconv.Quote(strconv.FormatInt(product.Version, 10))
+}
+
+// matchesETag tells whether an If-Match or If-None-Match header is "*" or lists tag. Weak
+// tags, e.g. W/"3", only match when weak comparison is allowed, as for If-None-Match.
+func matchesETag(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ifMatch tells whether the If-Match header of a write, if any, matches the current product,
+// responding 412 Precondition Failed when it does not
+func ifMatch(c *gin.Context, current *models.Product) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || matchesETag(header, etag(current), false) {
+		return true
+	}
+
+	c.Status(http.StatusPreconditionFailed)
+	c.Set("errors", custom_errors.ErrVersionMismatch)
+	return false
+}
+
+// ifMatchVersion checks the If-Match header of a write against the stored product and returns
+// the version the write must apply to, zero when there is no If-Match. Matching the version
+// again when writing catches products modified in between.
+func ifMatchVersion(c *gin.Context, ps services.ProductsServiceInterface, id string) (int64, bool) {
+	if c.GetHeader("If-Match") == "" {
+		return 0, true
+	}
+
+	current, err := ps.GetProductById(c.Request.Context(), id)
+	if err != nil {
+		productError(c, err)
+		return 0, false
+	}
+
+	if !ifMatch(c, current) {
+		return 0, false
+	}
+
+	return current.Version, true
+}
+
+// productError sets the error of a request on products, with its status when the product or
+// the category filtered on is missing, or the product was modified
+func productError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, custom_errors.ErrProductNotFound), errors.Is(err, custom_errors.ErrCategoryNotFound):
+		c.Status(http.StatusNotFound)
+	case errors.Is(err, custom_errors.ErrVersionMismatch):
+		c.Status(http.StatusPreconditionFailed)
+	}
+	c.Set("errors", err)
+}
@@ -1,10 +1,10 @@
 package controllers
 
 import (
-	"errors"
 	"net/http"
 	custom_errors "simpler-products/errors"
 	"simpler-products/models"
+	"simpler-products/pagination"
 	"simpler-products/services"
 	"simpler-products/validators"
 	"strconv"
@@ -13,39 +13,103 @@ import (
 )
 
 func GetAllProducts(ps services.ProductsServiceInterface) gin.HandlerFunc {
+	cursors := pagination.DefaultCodec()
+
 	return func(c *gin.Context) {
 		// Get pagination parameters from query string
-		limitStr := c.DefaultQuery("limit", "10")  // Default limit is 10
-		offsetStr := c.DefaultQuery("offset", "0") // Default offset is 0
+		limit, offset, ok := paginationParams(c)
+		if !ok {
+			return
+		}
 
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil || limit <= 0 || limit > 100 {
-			c.Status(http.StatusBadRequest)
-			c.Set("errors", custom_errors.ErrInvalidLimitParameter)
+		// Get filtering and sorting parameters from query string
+		query, err := validators.ValidateProductQuery(c)
+		if err != nil {
 			return
 		}
+		query.Offset = offset
 
-		offset, err := strconv.Atoi(offsetStr)
-		if err != nil || offset < 0 {
-			c.Status(http.StatusBadRequest)
-			c.Set("errors", custom_errors.ErrInvalidOffsetParameter)
+		// Get the requested facets, if any
+		facetQuery, err := validators.ValidateFacetQuery(c)
+		if err != nil {
 			return
 		}
 
-		products, total, err := ps.GetAllProducts(limit, offset)
+		// A cursor from a previous page switches to keyset pagination
+		cursor, cursorMode := c.GetQuery("cursor")
+		if cursorMode {
+			if _, ok := c.GetQuery("offset"); ok {
+				c.Status(http.StatusBadRequest)
+				c.Set("errors", custom_errors.ErrCursorWithOffset)
+				return
+			}
+
+			query.Keyset, err = cursors.Decode(cursor, *query)
+			if err != nil {
+				c.Status(http.StatusBadRequest)
+				c.Set("errors", err)
+				return
+			}
+		}
+		backward := cursorMode && query.Keyset.Backward
+
+		// Fetch one more product than requested to know whether there is a page beyond this one
+		query.Limit = limit + 1
+
+		products, total, err := ps.GetAllProducts(c.Request.Context(), *query)
 		if err != nil {
-			c.Set("errors", err)
+			// An unknown category filter is reported as not found
+			productError(c, err)
 			return
 		}
 
+		// Facets cover every product matching the filters, not only this page
+		if facetQuery != nil {
+			facets, err := ps.GetProductFacets(c.Request.Context(), query.Filter, *facetQuery)
+			if err != nil {
+				productError(c, err)
+				return
+			}
+			c.Set("facets", facets)
+		}
+
+		more := len(products) > limit
+		if more {
+			// Going backward the extra product precedes the page
+			if backward {
+				products = products[len(products)-limit:]
+			} else {
+				products = products[:limit]
+			}
+		}
+
 		// Set data and pagination in the context
 		c.Set("data", products)
-		c.Set("pagination", gin.H{
-			"limit":  limit,
-			"offset": offset,
-			"total":  total,
-			"count":  len(products),
-		})
+
+		page := gin.H{
+			"limit": limit,
+			"total": total,
+			"count": len(products),
+		}
+		if !cursorMode {
+			page["offset"] = offset
+		}
+
+		if len(products) > 0 {
+			hasNext := more
+			hasPrev := cursorMode || offset > 0
+			if backward {
+				hasNext, hasPrev = true, more
+			}
+
+			if hasNext {
+				page["next_cursor"] = cursors.Encode(*query, models.KeysetOf(products[len(products)-1], false))
+			}
+			if hasPrev {
+				page["prev_cursor"] = cursors.Encode(*query, models.KeysetOf(products[0], true))
+			}
+		}
+		c.Set("pagination", page)
 	}
 }
 
@@ -56,12 +120,18 @@ func GetProductById(ps services.ProductsServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		product, err := ps.GetProductById(id)
+		product, err := ps.GetProductById(c.Request.Context(), id)
 		if err != nil {
-			if errors.Is(err, custom_errors.ErrProductNotFound) {
-				c.Status(http.StatusNotFound)
-			}
-			c.Set("errors", err)
+			productError(c, err)
+			return
+		}
+
+		// Caches revalidating an unchanged product get no body back
+		tag := etag(product)
+		c.Header("ETag", tag)
+		if header := c.GetHeader("If-None-Match"); header != "" && matchesETag(header, tag, true) {
+			c.Status(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
 			return
 		}
 
@@ -77,12 +147,13 @@ func AddProduct(ps services.ProductsServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		if err := ps.AddProduct(product); err != nil {
+		if err := ps.AddProduct(c.Request.Context(), product); err != nil {
 			c.Set("errors", err)
 			return
 		}
 
 		// Set data in the context
+		c.Header("ETag", etag(product))
 		c.Status(http.StatusCreated)
 		c.Set("data", [1]*models.Product{product})
 	}
@@ -100,20 +171,65 @@ func UpdateProduct(ps services.ProductsServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		updatedProduct, err := ps.UpdateProduct(id, product)
+		// The update only applies to the version matching If-Match
+		version, ok := ifMatchVersion(c, ps, id)
+		if !ok {
+			return
+		}
+		product.Version = version
+
+		updatedProduct, err := ps.UpdateProduct(c.Request.Context(), id, product)
 		if err != nil {
-			if errors.Is(err, custom_errors.ErrProductNotFound) {
-				c.Status(http.StatusNotFound)
-			}
-			c.Set("errors", err)
+			productError(c, err)
 			return
 		}
 
 		// Set data in the context
+		c.Header("ETag", etag(updatedProduct))
 		c.Set("data", [1]*models.Product{updatedProduct})
 	}
 }
 
+func PatchProduct(ps services.ProductsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		// The patch applies to the stored product
+		current, err := ps.GetProductById(c.Request.Context(), id)
+		if err != nil {
+			productError(c, err)
+			return
+		}
+
+		if !ifMatch(c, current) {
+			return
+		}
+
+		patch, err := validators.ValidateProductPatch(c, *current)
+		if err != nil {
+			return
+		}
+
+		// With If-Match, the patch must not overwrite changes made since current was read
+		if c.GetHeader("If-Match") != "" {
+			patch.Version = current.Version
+		}
+
+		patchedProduct, err := ps.PatchProduct(c.Request.Context(), id, *patch)
+		if err != nil {
+			productError(c, err)
+			return
+		}
+
+		// Set data in the context
+		c.Header("ETag", etag(patchedProduct))
+		c.Set("data", [1]*models.Product{patchedProduct})
+	}
+}
+
 func DeleteProduct(ps services.ProductsServiceInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, err := validators.ValidateProductID(c)
@@ -121,14 +237,38 @@ func DeleteProduct(ps services.ProductsServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		if err := ps.DeleteProduct(id); err != nil {
-			if errors.Is(err, custom_errors.ErrProductNotFound) {
-				c.Status(http.StatusNotFound)
-			}
-			c.Set("errors", err)
+		version, ok := ifMatchVersion(c, ps, id)
+		if !ok {
+			return
+		}
+
+		if err := ps.DeleteProduct(c.Request.Context(), id, version); err != nil {
+			productError(c, err)
 			return
 		}
 
 		c.Status(http.StatusNoContent)
 	}
 }
+
+// paginationParams reads the limit and offset query parameters, reporting invalid ones
+func paginationParams(c *gin.Context) (limit, offset int, ok bool) {
+	limitStr := c.DefaultQuery("limit", "10")  // Default limit is 10
+	offsetStr := c.DefaultQuery("offset", "0") // Default offset is 0
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 100 {
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", custom_errors.ErrInvalidLimitParameter)
+		return 0, 0, false
+	}
+
+	offset, err = strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", custom_errors.ErrInvalidOffsetParameter)
+		return 0, 0, false
+	}
+
+	return limit, offset, true
+}
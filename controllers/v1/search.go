@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/services"
+	"simpler-products/validators"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSearchQueryLength bounds the length of search queries, in characters
+const maxSearchQueryLength = 200
+
+func SearchProducts(ss services.SearchServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Leading spaces do not matter, a trailing one marks the last word as complete
+		q := strings.TrimLeft(c.Query("q"), " ")
+		if strings.TrimSpace(q) == "" || utf8.RuneCountInString(q) > maxSearchQueryLength {
+			c.Status(http.StatusBadRequest)
+			c.Set("errors", custom_errors.ErrInvalidSearchQuery)
+			return
+		}
+
+		// Get pagination parameters from query string
+		limit, offset, ok := paginationParams(c)
+		if !ok {
+			return
+		}
+
+		// Get the requested facets, if any
+		facetQuery, err := validators.ValidateFacetQuery(c)
+		if err != nil {
+			return
+		}
+
+		hits, total, err := ss.SearchProducts(c.Request.Context(), q, limit, offset)
+		if err != nil {
+			c.Set("errors", err)
+			return
+		}
+
+		// Facets cover every matching product, not only this page
+		if facetQuery != nil {
+			facets, err := ss.SearchFacets(c.Request.Context(), q, *facetQuery)
+			if err != nil {
+				c.Set("errors", err)
+				return
+			}
+			c.Set("facets", facets)
+		}
+
+		// Set data and pagination in the context
+		c.Set("data", hits)
+		c.Set("pagination", gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  total,
+			"count":  len(hits),
+		})
+	}
+}
+
+// maxSuggestPrefixLength bounds the length of suggestion prefixes, that of the longest product name
+const maxSuggestPrefixLength = 255
+
+func SuggestProducts(ss services.SearchServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// A trailing space marks the last word as complete
+		prefix := strings.TrimLeft(c.Query("prefix"), " ")
+		if strings.TrimSpace(prefix) == "" || utf8.RuneCountInString(prefix) > maxSuggestPrefixLength {
+			c.Status(http.StatusBadRequest)
+			c.Set("errors", custom_errors.ErrInvalidSuggestPrefix)
+			return
+		}
+
+		// Without a limit, the configured maximum number of suggestions is returned
+		limit := 0
+		if limitStr, ok := c.GetQuery("limit"); ok {
+			var err error
+			if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 {
+				c.Status(http.StatusBadRequest)
+				c.Set("errors", custom_errors.ErrInvalidSuggestLimit)
+				return
+			}
+		}
+
+		fuzzy, err := strconv.ParseBool(c.DefaultQuery("fuzzy", "false"))
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			c.Set("errors", custom_errors.ErrInvalidFuzzyParameter)
+			return
+		}
+
+		suggestions, err := ss.SuggestProducts(c.Request.Context(), prefix, limit, fuzzy)
+		if err != nil {
+			c.Set("errors", err)
+			return
+		}
+
+		c.Set("data", suggestions)
+	}
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/services"
+	"simpler-products/validators"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetStock(is services.InventoryServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		stock, err := is.GetStock(c.Request.Context(), id)
+		if err != nil {
+			stockError(c, err)
+			return
+		}
+
+		c.Set("data", [1]*models.Stock{stock})
+	}
+}
+
+// MoveStock applies a movement of the given type to the stock of the product, and responds
+// with the movement recorded in its ledger
+func MoveStock(is services.InventoryServiceInterface, movementType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movement, err := validators.ValidateStockMovement(c, movementType)
+		if err != nil {
+			return
+		}
+
+		applied, err := is.MoveStock(c.Request.Context(), *movement)
+		if err != nil {
+			stockError(c, err)
+			return
+		}
+
+		c.Status(http.StatusCreated)
+		c.Set("data", [1]*models.StockMovement{applied})
+	}
+}
+
+// TransferStock moves stock of the product between two warehouses, and responds with the
+// paired movements recorded in its ledger
+func TransferStock(is services.InventoryServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transfer, err := validators.ValidateStockTransfer(c)
+		if err != nil {
+			return
+		}
+
+		movements, err := is.TransferStock(c.Request.Context(), *transfer)
+		if err != nil {
+			stockError(c, err)
+			return
+		}
+
+		c.Status(http.StatusCreated)
+		c.Set("data", movements)
+	}
+}
+
+// AllocateStock picks the warehouses to take a quantity of the product from, and reserves the
+// stock there when requested
+func AllocateStock(is services.InventoryServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, request, err := validators.ValidateAllocationRequest(c)
+		if err != nil {
+			return
+		}
+
+		plan, err := is.AllocateStock(c.Request.Context(), id, *request)
+		if err != nil {
+			stockError(c, err)
+			return
+		}
+
+		if plan.Reserved {
+			c.Status(http.StatusCreated)
+		}
+		c.Set("data", [1]*models.AllocationPlan{plan})
+	}
+}
+
+func GetVariantStock(is services.InventoryServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		variantID, err := validators.ValidateVariantID(c)
+		if err != nil {
+			return
+		}
+
+		stock, err := is.GetVariantStock(c.Request.Context(), id, variantID)
+		if err != nil {
+			// The variant is part of the path, so missing when not found
+			variantError(c, err)
+			return
+		}
+
+		c.Set("data", [1]*models.Stock{stock})
+	}
+}
+
+func GetStockMovements(is services.InventoryServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		limit, offset, ok := paginationParams(c)
+		if !ok {
+			return
+		}
+
+		movements, total, err := is.GetStockMovements(c.Request.Context(), id, limit, offset)
+		if err != nil {
+			stockError(c, err)
+			return
+		}
+
+		c.Set("data", movements)
+		c.Set("pagination", gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  total,
+			"count":  len(movements),
+		})
+	}
+}
+
+// stockError sets the error of a request on stock, with its status when the product, a variant
+// or a warehouse is missing, or there is not enough stock for a movement or an allocation
+func stockError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, custom_errors.ErrProductNotFound):
+		c.Status(http.StatusNotFound)
+	case errors.Is(err, custom_errors.ErrWarehouseNotFound), errors.Is(err, custom_errors.ErrVariantNotFound):
+		c.Status(http.StatusBadRequest)
+	case errors.Is(err, custom_errors.ErrInsufficientStock),
+		errors.Is(err, custom_errors.ErrInsufficientReservedStock),
+		errors.Is(err, custom_errors.ErrNoSingleWarehouse):
+		c.Status(http.StatusConflict)
+	}
+	c.Set("errors", err)
+}
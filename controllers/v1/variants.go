@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/services"
+	"simpler-products/validators"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetProductOptions(vs services.VariantsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		options, err := vs.GetProductOptions(c.Request.Context(), id)
+		if err != nil {
+			variantError(c, err)
+			return
+		}
+
+		c.Set("data", options)
+	}
+}
+
+func SetProductOptions(vs services.VariantsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		options, err := validators.ValidateProductOptions(c)
+		if err != nil {
+			return
+		}
+
+		options, err = vs.SetProductOptions(c.Request.Context(), id, options)
+		if err != nil {
+			variantError(c, err)
+			return
+		}
+
+		c.Set("data", options)
+	}
+}
+
+func GetAllVariants(vs services.VariantsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		variants, err := vs.ListVariants(c.Request.Context(), id)
+		if err != nil {
+			variantError(c, err)
+			return
+		}
+
+		c.Set("data", variants)
+	}
+}
+
+func GetVariantById(vs services.VariantsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		variantID, err := validators.ValidateVariantID(c)
+		if err != nil {
+			return
+		}
+
+		variant, err := vs.GetVariant(c.Request.Context(), id, variantID)
+		if err != nil {
+			variantError(c, err)
+			return
+		}
+
+		c.Set("data", [1]*models.Variant{variant})
+	}
+}
+
+func AddVariant(vs services.VariantsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		variant, err := validators.ValidateVariant(c)
+		if err != nil {
+			return
+		}
+
+		if err := vs.AddVariant(c.Request.Context(), id, variant); err != nil {
+			variantError(c, err)
+			return
+		}
+
+		c.Status(http.StatusCreated)
+		c.Set("data", [1]*models.Variant{variant})
+	}
+}
+
+// GenerateVariants creates the variants missing for the combinations of the option values of
+// the product, and responds with them
+func GenerateVariants(vs services.VariantsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		skuPrefix, price, err := validators.ValidateVariantGeneration(c)
+		if err != nil {
+			return
+		}
+
+		variants, err := vs.GenerateVariants(c.Request.Context(), id, skuPrefix, price)
+		if err != nil {
+			variantError(c, err)
+			return
+		}
+
+		c.Status(http.StatusCreated)
+		c.Set("data", variants)
+	}
+}
+
+func UpdateVariant(vs services.VariantsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		variantID, err := validators.ValidateVariantID(c)
+		if err != nil {
+			return
+		}
+
+		variant, err := validators.ValidateVariant(c)
+		if err != nil {
+			return
+		}
+
+		updatedVariant, err := vs.UpdateVariant(c.Request.Context(), id, variantID, variant)
+		if err != nil {
+			variantError(c, err)
+			return
+		}
+
+		c.Set("data", [1]*models.Variant{updatedVariant})
+	}
+}
+
+func DeleteVariant(vs services.VariantsServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateProductID(c)
+		if err != nil {
+			return
+		}
+
+		variantID, err := validators.ValidateVariantID(c)
+		if err != nil {
+			return
+		}
+
+		if err := vs.DeleteVariant(c.Request.Context(), id, variantID); err != nil {
+			variantError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// variantError sets the error of a request on variants, with its status when the product or
+// the variant is missing, the options of a variant do not fit those of the product or have too
+// many combinations to generate, or a change clashes with other variants or with stock
+func variantError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, custom_errors.ErrProductNotFound), errors.Is(err, custom_errors.ErrVariantNotFound):
+		c.Status(http.StatusNotFound)
+	case errors.Is(err, custom_errors.ErrInvalidVariantOptions), errors.Is(err, custom_errors.ErrTooManyVariants):
+		c.Status(http.StatusBadRequest)
+	case errors.Is(err, custom_errors.ErrDuplicateSKU),
+		errors.Is(err, custom_errors.ErrDuplicateVariant),
+		errors.Is(err, custom_errors.ErrOptionsInUse),
+		errors.Is(err, custom_errors.ErrVariantNotEmpty):
+		c.Status(http.StatusConflict)
+	}
+	c.Set("errors", err)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/services"
+	"simpler-products/validators"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetAllWarehouses(ws services.WarehousesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		warehouses, err := ws.ListWarehouses(c.Request.Context())
+		if err != nil {
+			warehouseError(c, err)
+			return
+		}
+
+		c.Set("data", warehouses)
+	}
+}
+
+func GetWarehouseById(ws services.WarehousesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateWarehouseID(c)
+		if err != nil {
+			return
+		}
+
+		warehouse, err := ws.GetWarehouse(c.Request.Context(), id)
+		if err != nil {
+			warehouseError(c, err)
+			return
+		}
+
+		c.Set("data", [1]*models.Warehouse{warehouse})
+	}
+}
+
+func AddWarehouse(ws services.WarehousesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		warehouse, err := validators.ValidateWarehouse(c)
+		if err != nil {
+			return
+		}
+
+		if err := ws.AddWarehouse(c.Request.Context(), warehouse); err != nil {
+			warehouseError(c, err)
+			return
+		}
+
+		c.Status(http.StatusCreated)
+		c.Set("data", [1]*models.Warehouse{warehouse})
+	}
+}
+
+func UpdateWarehouse(ws services.WarehousesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateWarehouseID(c)
+		if err != nil {
+			return
+		}
+
+		warehouse, err := validators.ValidateWarehouse(c)
+		if err != nil {
+			return
+		}
+
+		updatedWarehouse, err := ws.UpdateWarehouse(c.Request.Context(), id, warehouse)
+		if err != nil {
+			warehouseError(c, err)
+			return
+		}
+
+		c.Set("data", [1]*models.Warehouse{updatedWarehouse})
+	}
+}
+
+func DeleteWarehouse(ws services.WarehousesServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := validators.ValidateWarehouseID(c)
+		if err != nil {
+			return
+		}
+
+		if err := ws.DeleteWarehouse(c.Request.Context(), id); err != nil {
+			warehouseError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// warehouseError sets the error of a request on warehouses, with its status when the
+// warehouse is missing or still holds stock
+func warehouseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, custom_errors.ErrWarehouseNotFound):
+		c.Status(http.StatusNotFound)
+	case errors.Is(err, custom_errors.ErrWarehouseNotEmpty):
+		c.Status(http.StatusConflict)
+	}
+	c.Set("errors", err)
+}
@@ -1,65 +1,104 @@
 package database
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	_ "modernc.org/sqlite"
 )
 
-func Init(log *logrus.Logger, user, password, host, port, dbName string) (*sql.DB, error) {
-	dbConnectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, password, host, port, dbName)
-	db, err := sql.Open("mysql", dbConnectionString)
+// Options configures the connection pool and the health watchdog
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// HealthCheckInterval is the delay between pings while the database is healthy
+	HealthCheckInterval time.Duration
+	// PingTimeout bounds every health check ping
+	PingTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the exponential delay between pings while it is not
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxOpenConns:        25,
+		MaxIdleConns:        25,
+		ConnMaxLifetime:     5 * time.Minute,
+		ConnMaxIdleTime:     5 * time.Minute,
+		HealthCheckInterval: 5 * time.Second,
+		PingTimeout:         2 * time.Second,
+		MinBackoff:          500 * time.Millisecond,
+		MaxBackoff:          30 * time.Second,
+	}
+}
+
+func MySQLDSN(user, password, host, port, dbName string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, password, host, port, dbName)
+}
+
+// Init opens a connection pool, checks that the database is reachable and starts
+// a watchdog tracking its health. Close the returned Manager on shutdown.
+func Init(log *logrus.Logger, driver, dbConnectionString string, opts Options) (*Manager, error) {
+	// Every statement is traced as a child span of the context it runs with
+	db, err := otelsql.Open(driver, dbConnectionString,
+		otelsql.WithAttributes(dbSystem(driver)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+		}),
+	)
 	if err != nil {
 		log.Errorf("Error connecting to database: %v", err)
 		return nil, err
 	}
 
+	if driver == "sqlite" {
+		initSQLite(db)
+	} else {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+
 	// check if Database connection is established
-	err = db.Ping()
+	ctx, cancel := context.WithTimeout(context.Background(), opts.PingTimeout)
+	defer cancel()
+
+	err = db.PingContext(ctx)
 	if err != nil {
 		log.Errorf("Error checking database connection: %v", err)
+		db.Close()
 		return nil, err
 	}
 
-	// Start a goroutine to periodically check the connection
-	go checkDBConnection(db, dbConnectionString, log)
+	manager := NewManager(db, log, opts)
+	manager.Start()
 
-	return db, nil
+	return manager, nil
 }
 
-func checkDBConnection(db *sql.DB, dbConnectionString string, log *logrus.Logger) {
-	for {
-		// Sleep for 5s
-		time.Sleep(5 * time.Second)
-
-		// Check the connection
-		err := db.Ping()
-		if err != nil {
-			log.Errorf("Database connection lost: %v", err)
-
-			// Attempt to reconnect
-			for {
-				log.Info("Attempting to reconnect to the database...")
-				db, err = sql.Open("mysql", dbConnectionString)
-				if err != nil {
-					log.Errorf("Reconnection failed: %v", err)
-					time.Sleep(5 * time.Second)
-					continue
-				}
-
-				err = db.Ping()
-				if err != nil {
-					log.Errorf("Reconnection ping failed: %v", err)
-					time.Sleep(5 * time.Second)
-					continue
-				}
-
-				log.Info("Successfully reconnected to the database!")
-				break
-			}
-		}
+// dbSystem identifies the database in the spans of its statements
+func dbSystem(driver string) attribute.KeyValue {
+	switch driver {
+	case "mysql":
+		return semconv.DBSystemMySQL
+	case PostgresDriver:
+		return semconv.DBSystemPostgreSQL
+	case "sqlite":
+		return semconv.DBSystemSqlite
+	default:
+		return semconv.DBSystemOtherSQL
 	}
 }
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Manager owns a connection pool and keeps track of whether the database is reachable.
+//
+// database/sql re-dials broken connections on its own, so the pool is never reopened:
+// the watchdog only pings it, backing off exponentially while the database is down,
+// and exposes the result to the rest of the application.
+type Manager struct {
+	DB   *sql.DB
+	Log  *logrus.Logger
+	opts Options
+
+	mu        sync.RWMutex
+	healthy   bool
+	lastErr   error
+	lastCheck time.Time
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewManager(db *sql.DB, log *logrus.Logger, opts Options) *Manager {
+	return &Manager{
+		DB:        db,
+		Log:       log,
+		opts:      opts,
+		healthy:   true,
+		lastCheck: time.Now(),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start launches the health watchdog
+func (m *Manager) Start() {
+	m.startOnce.Do(func() {
+		go m.watch()
+	})
+}
+
+// Healthy reports whether the last health check succeeded
+func (m *Manager) Healthy() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.healthy
+}
+
+// LastError returns the error of the last failed health check, or nil when healthy
+func (m *Manager) LastError() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.lastErr
+}
+
+// LastCheck returns when the database was last pinged
+func (m *Manager) LastCheck() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.lastCheck
+}
+
+// Close stops the watchdog and closes the connection pool
+func (m *Manager) Close() error {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+
+	// Wait for the watchdog only if it was started
+	started := true
+	m.startOnce.Do(func() {
+		started = false
+	})
+	if started {
+		<-m.done
+	}
+
+	return m.DB.Close()
+}
+
+func (m *Manager) watch() {
+	defer close(m.done)
+
+	timer := time.NewTimer(m.opts.HealthCheckInterval)
+	defer timer.Stop()
+
+	attempt := 0
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-timer.C:
+		}
+
+		err := m.ping()
+		m.setState(err)
+
+		if err != nil {
+			if attempt == 0 {
+				m.Log.Errorf("Database connection lost: %v", err)
+			} else {
+				m.Log.Errorf("Reconnection ping failed: %v", err)
+			}
+
+			delay := m.backoff(attempt)
+			attempt++
+			m.Log.Infof("Attempting to reconnect to the database in %s...", delay)
+			timer.Reset(delay)
+			continue
+		}
+
+		if attempt > 0 {
+			m.Log.Info("Successfully reconnected to the database!")
+		}
+		attempt = 0
+		timer.Reset(m.opts.HealthCheckInterval)
+	}
+}
+
+func (m *Manager) ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.PingTimeout)
+	defer cancel()
+
+	return m.DB.PingContext(ctx)
+}
+
+func (m *Manager) setState(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.healthy = err == nil
+	m.lastErr = err
+	m.lastCheck = time.Now()
+}
+
+// backoff returns an exponentially growing delay capped at MaxBackoff, with jitter
+// spreading it over [delay/2, delay] so that replicas do not retry in lockstep
+func (m *Manager) backoff(attempt int) time.Duration {
+	delay := m.opts.MinBackoff
+	for i := 0; i < attempt && delay < m.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > m.opts.MaxBackoff {
+		delay = m.opts.MaxBackoff
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+
+	return half + rand.N(half+1)
+}
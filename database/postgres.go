@@ -0,0 +1,26 @@
+package database
+
+import (
+	"net"
+	"net/url"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// PostgresDriver is the database/sql driver name registered by pgx
+const PostgresDriver = "pgx"
+
+func PostgresDSN(user, password, host, port, dbName, sslMode string) string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(user, password),
+		Host:   net.JoinHostPort(host, port),
+		Path:   "/" + dbName,
+	}
+
+	if sslMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {sslMode}}.Encode()
+	}
+
+	return dsn.String()
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SQLiteDSN builds a DSN for the given database file, enabling foreign keys,
+// WAL journaling and a busy timeout so concurrent writers wait instead of failing
+func SQLiteDSN(path string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return fmt.Sprintf("%s%s_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path, separator)
+}
+
+func initSQLite(db *sql.DB) {
+	// SQLite allows a single writer, and every connection to ':memory:' opens a new database,
+	// so keep exactly one connection open for the lifetime of the pool
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+}
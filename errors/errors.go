@@ -5,11 +5,53 @@ import "errors"
 var (
 	ErrProductNotFound            = errors.New("product not found")
 	ErrInvalidProductID           = errors.New("invalid product id")
+	ErrCategoryNotFound           = errors.New("category not found")
+	ErrInvalidCategoryID          = errors.New("invalid category id")
+	ErrParentCategoryNotFound     = errors.New("parent category not found")
+	ErrCategoryCycle              = errors.New("a category cannot be nested in itself or in one of its descendants")
+	ErrCategoryNotEmpty           = errors.New("category has subcategories or products, move or delete them first")
+	ErrUnknownCategories          = errors.New("category_ids holds categories that do not exist")
+	ErrWarehouseNotFound          = errors.New("warehouse not found")
+	ErrInvalidWarehouseID         = errors.New("invalid warehouse id")
+	ErrWarehouseNotEmpty          = errors.New("warehouse holds stock, move or ship it first")
+	ErrVariantNotFound            = errors.New("variant not found")
+	ErrInvalidVariantID           = errors.New("invalid variant id")
+	ErrInvalidVariantOptions      = errors.New("options must hold a value of each option of the product, and nothing else")
+	ErrDuplicateSKU               = errors.New("sku is already used by another variant")
+	ErrDuplicateVariant           = errors.New("another variant of the product has the same options")
+	ErrOptionsInUse               = errors.New("variants use options or values left out, update or delete them first")
+	ErrVariantNotEmpty            = errors.New("variant holds stock, move or ship it first")
+	ErrTooManyVariants            = errors.New("the options of the product have too many combinations to generate variants from")
+	ErrNoSingleWarehouse          = errors.New("no single warehouse has the quantity available, allow split allocations to take it from several")
+	ErrInsufficientStock          = errors.New("insufficient stock, the quantity exceeds the available stock")
+	ErrInsufficientReservedStock  = errors.New("the quantity exceeds the reserved stock")
 	ErrInvalidLimitParameter      = errors.New("invalid limit parameter, limit must be in the range of [1, 100]")
 	ErrInvalidOffsetParameter     = errors.New("invalid offset parameter, offest must be a positive number")
+	ErrUnsupportedPatchType       = errors.New("unsupported Content-Type, patches must be sent as application/merge-patch+json or application/json-patch+json")
+	ErrInvalidPatch               = errors.New("invalid patch document")
+	ErrPatchTestFailed            = errors.New("patch test operation failed")
+	ErrInvalidIdempotencyKey      = errors.New("invalid Idempotency-Key header, the key must be between 1 and 255 printable ASCII characters long")
+	ErrIdempotencyKeyReused       = errors.New("Idempotency-Key was already used for a different request")
+	ErrIdempotencyKeyInProgress   = errors.New("a request with the same Idempotency-Key is still being processed")
+	ErrBulkRolledBack             = errors.New("not applied, another operation of the all-or-nothing request failed")
+	ErrUnsupportedImportType      = errors.New("unsupported Content-Type, imports must be sent as text/csv or application/x-ndjson")
+	ErrInvalidImportFile          = errors.New("invalid import file")
+	ErrInvalidExportFormat        = errors.New("invalid format parameter, format must be one of: csv, tsv, ndjson")
+	ErrVersionMismatch            = errors.New("precondition failed, the product was modified and its ETag does not match If-Match")
+	ErrInvalidSearchQuery         = errors.New("invalid q parameter, the search query must be between 1 and 200 characters long")
+	ErrInvalidSuggestPrefix       = errors.New("invalid prefix parameter, the prefix must be between 1 and 255 characters long")
+	ErrInvalidSuggestLimit        = errors.New("invalid limit parameter, limit must be a positive number")
+	ErrInvalidFuzzyParameter      = errors.New("invalid fuzzy parameter, fuzzy must be true or false")
+	ErrInvalidCursor              = errors.New("invalid cursor parameter")
+	ErrCursorQueryMismatch        = errors.New("cursor was issued for different filters or sort, repeat them unchanged when following a cursor")
+	ErrCursorWithOffset           = errors.New("cursor and offset parameters cannot be combined")
 	ErrAuthorizationHeaderMissing = errors.New("authorization header is missing")
 	ErrAuthorizationHeaderFormat  = errors.New("invalid Authorization header format")
 	ErrDecodingPublicKey          = errors.New("error decoding public key")
 	ErrParsingPublicKey           = errors.New("error parsing public key")
 	ErrInvalidToken               = errors.New("invalid token")
+	ErrRequestCanceled            = errors.New("request canceled by the client")
+	ErrRequestTimeout             = errors.New("request timed out")
+	ErrServerShuttingDown         = errors.New("server is shutting down")
+	ErrMigrationsLocked           = errors.New("another process is running migrations")
 )
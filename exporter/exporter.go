@@ -0,0 +1,139 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"simpler-products/models"
+	"simpler-products/services"
+	"strconv"
+)
+
+// Formats are the supported export formats
+var Formats = []string{models.FormatCSV, models.FormatTSV, models.FormatNDJSON}
+
+// ContentTypes are the media types of the export formats
+var ContentTypes = map[string]string{
+	models.FormatCSV:    "text/csv; charset=utf-8",
+	models.FormatTSV:    "text/tab-separated-values; charset=utf-8",
+	models.FormatNDJSON: "application/x-ndjson",
+}
+
+// columns are the columns of CSV and TSV exports, the ones imports read
+var columns = []string{"id", "name", "description", "price"}
+
+// rowWriter writes products in an export format
+type rowWriter interface {
+	write(product models.Product) error
+	// flush writes the buffered products, and reports the errors met writing them
+	flush() error
+}
+
+// Exporter writes the products matching a listing's filters as CSV, TSV or NDJSON
+type Exporter struct {
+	Service services.ProductsServiceInterface
+}
+
+// Export streams the products matching filter, in the order of sort, to w in the given format
+// and returns how many were written. Output is buffered, so nothing is written to w when the
+// export fails before the first products are read.
+func (ex *Exporter) Export(ctx context.Context, w io.Writer, format string, filter models.ProductFilter, sort []models.SortField) (int, error) {
+	buffered := bufio.NewWriter(w)
+
+	var rows rowWriter
+	switch format {
+	case models.FormatCSV:
+		rows = newDelimitedRows(buffered, ',')
+	case models.FormatTSV:
+		rows = newDelimitedRows(buffered, '\t')
+	case models.FormatNDJSON:
+		rows = newNDJSONRows(buffered)
+	default:
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	exported := 0
+	err := ex.Service.ExportProducts(ctx, filter, sort, func(product models.Product) error {
+		exported++
+		return rows.write(product)
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	if err := rows.flush(); err != nil {
+		return exported, err
+	}
+
+	return exported, buffered.Flush()
+}
+
+// delimitedRows writes products as CSV, or TSV, starting with a header row. Text that
+// spreadsheets would read as a formula is prefixed with a quote.
+type delimitedRows struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newDelimitedRows(w io.Writer, comma rune) *delimitedRows {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+
+	return &delimitedRows{w: writer}
+}
+
+func (rows *delimitedRows) writeHeader() error {
+	if rows.header {
+		return nil
+	}
+	rows.header = true
+
+	return rows.w.Write(columns)
+}
+
+func (rows *delimitedRows) write(product models.Product) error {
+	if err := rows.writeHeader(); err != nil {
+		return err
+	}
+
+	// Files are opened in spreadsheets, where text must not run as a formula
+	return rows.w.Write([]string{
+		product.ID,
+		models.EscapeFormula(product.Name),
+		models.EscapeFormula(product.Description),
+		strconv.FormatFloat(product.Price, 'f', -1, 64),
+	})
+}
+
+func (rows *delimitedRows) flush() error {
+	// Exports without products still hold the header
+	if err := rows.writeHeader(); err != nil {
+		return err
+	}
+
+	rows.w.Flush()
+	return rows.w.Error()
+}
+
+// ndjsonRows writes a product per line as JSON, as the API returns them
+type ndjsonRows struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONRows(w io.Writer) *ndjsonRows {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return &ndjsonRows{encoder: encoder}
+}
+
+func (rows *ndjsonRows) write(product models.Product) error {
+	return rows.encoder.Encode(product)
+}
+
+func (rows *ndjsonRows) flush() error {
+	return nil
+}
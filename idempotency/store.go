@@ -0,0 +1,118 @@
+package idempotency
+
+import (
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long responses are kept for replay when no TTL is configured
+const DefaultTTL = 24 * time.Hour
+
+// Response is a response recorded for replay
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// StoreInterface is what the router needs to make requests idempotent
+type StoreInterface interface {
+	// Begin reserves key for a request identified by fingerprint. It returns the recorded
+	// response when the request was already completed, nil when it must be processed, and
+	// an error when key is in use by another request.
+	Begin(key, fingerprint string) (*Response, error)
+	// Complete records the response to replay for key
+	Complete(key string, response Response)
+	// Release frees key, so that the request can be retried
+	Release(key string)
+}
+
+type entry struct {
+	fingerprint string
+	// response is nil while the request is in progress
+	response *Response
+	expires  time.Time
+}
+
+// Store keeps the responses of idempotent requests in memory for TTL.
+// It is safe for concurrent use.
+type Store struct {
+	TTL time.Duration
+	// Now returns the current time, it can be replaced in tests
+	Now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+func NewStore(ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return &Store{
+		TTL:     ttl,
+		Now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+func (s *Store) Begin(key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, custom_errors.ErrIdempotencyKeyReused
+		case e.response == nil:
+			return nil, custom_errors.ErrIdempotencyKeyInProgress
+		default:
+			return e.response, nil
+		}
+	}
+
+	s.entries[key] = &entry{
+		fingerprint: fingerprint,
+		expires:     now.Add(s.TTL),
+	}
+
+	return nil, nil
+}
+
+func (s *Store) Complete(key string, response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.response = &response
+		e.expires = s.Now().Add(s.TTL)
+	}
+}
+
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// sweep drops the expired entries, at most once per TTL
+func (s *Store) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.TTL {
+		return
+	}
+
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
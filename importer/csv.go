@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"simpler-products/models"
+	"slices"
+	"strconv"
+	"strings"
+
+	custom_errors "simpler-products/errors"
+)
+
+// csvColumns are the columns a CSV file can hold, id being optional
+var csvColumns = []string{"id", "name", "description", "price"}
+
+// csvRows reads products from a CSV file whose header names the column of each field
+type csvRows struct {
+	r       *csv.Reader
+	columns map[string]int
+	fields  int
+}
+
+func newCSVRows(r io.Reader) (*csvRows, error) {
+	reader := csv.NewReader(r)
+	// Rows with a wrong number of fields are reported, not returned as errors
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the CSV file is empty", custom_errors.ErrInvalidImportFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", custom_errors.ErrInvalidImportFile, err)
+	}
+
+	rows := &csvRows{r: reader, columns: make(map[string]int), fields: len(header)}
+	for i, column := range header {
+		// Spreadsheets may start the file with a byte order mark
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		column = strings.ToLower(strings.TrimSpace(column))
+
+		if !slices.Contains(csvColumns, column) {
+			return nil, fmt.Errorf("%w: unknown CSV column %q, columns must be %s", custom_errors.ErrInvalidImportFile, column, strings.Join(csvColumns, ", "))
+		}
+		if _, ok := rows.columns[column]; ok {
+			return nil, fmt.Errorf("%w: duplicate CSV column %q", custom_errors.ErrInvalidImportFile, column)
+		}
+		rows.columns[column] = i
+	}
+	for _, column := range csvColumns[1:] {
+		if _, ok := rows.columns[column]; !ok {
+			return nil, fmt.Errorf("%w: missing CSV column %q", custom_errors.ErrInvalidImportFile, column)
+		}
+	}
+
+	return rows, nil
+}
+
+func (rows *csvRows) next() (row, error) {
+	record, err := rows.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return row{
+			line:    parseErr.StartLine,
+			product: &models.Product{},
+			errors:  []map[string]string{{"message": fmt.Sprintf("malformed CSV row: %v", parseErr.Err)}},
+		}, nil
+	}
+	if err != nil {
+		return row{}, err
+	}
+
+	line, _ := rows.r.FieldPos(0)
+	result := row{line: line, product: &models.Product{}}
+	if len(record) != rows.fields {
+		result.errors = []map[string]string{{"message": fmt.Sprintf("row has %d fields, the header has %d", len(record), rows.fields)}}
+		return result, nil
+	}
+
+	field := func(column string) string {
+		if i, ok := rows.columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	result.product.ID = field("id")
+	// Text is read back as exports wrote it before escaping formulas
+	result.product.Name = models.UnescapeFormula(field("name"))
+	result.product.Description = models.UnescapeFormula(field("description"))
+	if price := field("price"); price != "" {
+		if result.product.Price, err = strconv.ParseFloat(price, 64); err != nil {
+			result.errors = []map[string]string{{"message": "price is invalid"}}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"simpler-products/models"
+	"simpler-products/services"
+	"simpler-products/validators"
+)
+
+// Formats are the supported import formats
+var Formats = []string{models.FormatCSV, models.FormatNDJSON}
+
+// DefaultBatchSize is the number of rows upserted per call to the products service
+const DefaultBatchSize = 500
+
+// MaxReportedErrors bounds the row errors held in a report, the failed rows are still all counted
+const MaxReportedErrors = 1000
+
+// RowError holds the errors of a row that was not imported
+type RowError struct {
+	// Line is the line of the file the row starts at, the CSV header being line 1
+	Line   int                 `json:"line"`
+	ID     string              `json:"id,omitempty"`
+	Errors []map[string]string `json:"errors"`
+}
+
+// Report sums up an import
+type Report struct {
+	Rows    int        `json:"rows"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+}
+
+// fail records that row was not imported because of errs
+func (report *Report) fail(row row, errs []map[string]string) {
+	report.Failed++
+	if len(report.Errors) < MaxReportedErrors {
+		report.Errors = append(report.Errors, RowError{Line: row.line, ID: row.product.ID, Errors: errs})
+	}
+}
+
+// row is a product read from a file, along with the errors found reading it
+type row struct {
+	line    int
+	product *models.Product
+	errors  []map[string]string
+}
+
+// rowReader reads the rows of a file one at a time, and returns io.EOF after the last one
+type rowReader interface {
+	next() (row, error)
+}
+
+// Importer upserts the products of CSV or NDJSON files: rows with an id create or replace
+// that product, the others create a new one
+type Importer struct {
+	Service services.ProductsServiceInterface
+	// BatchSize is the number of rows upserted per call to the service, DefaultBatchSize if zero
+	BatchSize int
+}
+
+// Import streams the products of r, in the given format, validating each row like the products
+// sent to the API and upserting the valid ones in batches. Invalid or failing rows are reported
+// without stopping the import. The error is only set when the file cannot be read as a whole,
+// e.g. when its CSV header is invalid, in which case the rows imported so far are reported.
+func (im *Importer) Import(ctx context.Context, r io.Reader, format string) (*Report, error) {
+	var rows rowReader
+	switch format {
+	case models.FormatCSV:
+		csvRows, err := newCSVRows(r)
+		if err != nil {
+			return nil, err
+		}
+		rows = csvRows
+	case models.FormatNDJSON:
+		rows = newNDJSONRows(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+
+	batchSize := im.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	report := &Report{Errors: make([]RowError, 0)}
+	batch := make([]row, 0, batchSize)
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		report.Rows++
+		if len(row.errors) == 0 {
+			// Files are not sanitized by the XSS middleware, rows are
+			validators.SanitizeProduct(row.product)
+			row.errors = validators.ValidateProductFields(row.product)
+		}
+		if len(row.errors) > 0 {
+			report.fail(row, row.errors)
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == batchSize {
+			if err := im.upsert(ctx, batch, report); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := im.upsert(ctx, batch, report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// upsert applies a batch of valid rows and records the outcome of each in report
+func (im *Importer) upsert(ctx context.Context, batch []row, report *Report) error {
+	operations := make([]models.BulkOperation, len(batch))
+	for i, row := range batch {
+		operations[i] = models.BulkOperation{Op: models.BulkUpsert, ID: row.product.ID, Product: row.product}
+		if row.product.ID == "" {
+			operations[i].Op = models.BulkCreate
+		}
+	}
+
+	results, err := im.Service.BulkProducts(ctx, operations, false)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		switch {
+		case result.Err != nil:
+			report.fail(batch[i], []map[string]string{{"message": result.Err.Error()}})
+		case result.Product.Version == 1:
+			report.Created++
+		default:
+			report.Updated++
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"simpler-products/models"
+)
+
+// maxLineLength bounds the length of an NDJSON line, longer lines are reported and skipped
+const maxLineLength = 64 * 1024
+
+// ndjsonRows reads products from an NDJSON file, one JSON object per line
+type ndjsonRows struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newNDJSONRows(r io.Reader) *ndjsonRows {
+	return &ndjsonRows{r: bufio.NewReaderSize(r, maxLineLength)}
+}
+
+func (rows *ndjsonRows) next() (row, error) {
+	for {
+		text, tooLong, err := rows.readLine()
+		if err != nil {
+			return row{}, err
+		}
+		rows.line++
+
+		result := row{line: rows.line, product: &models.Product{}}
+		if tooLong {
+			result.errors = []map[string]string{{"message": fmt.Sprintf("line is longer than %d bytes", maxLineLength)}}
+			return result, nil
+		}
+
+		// Blank lines, e.g. the one ending the file, are not rows
+		text = bytes.TrimSpace(text)
+		if len(text) == 0 {
+			continue
+		}
+
+		if err := json.Unmarshal(text, result.product); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				result.errors = []map[string]string{{"message": fmt.Sprintf("%s is invalid", typeErr.Field)}}
+			} else {
+				result.errors = []map[string]string{{"message": "line is not a JSON object"}}
+			}
+		}
+
+		return result, nil
+	}
+}
+
+// readLine returns the next line, or reports that it is too long after skipping it
+func (rows *ndjsonRows) readLine() (line []byte, tooLong bool, err error) {
+	line, err = rows.r.ReadSlice('\n')
+	for errors.Is(err, bufio.ErrBufferFull) {
+		tooLong = true
+		_, err = rows.r.ReadSlice('\n')
+	}
+	if err == io.EOF && (len(line) > 0 || tooLong) {
+		// The last line is not terminated
+		err = nil
+	}
+
+	return line, tooLong, err
+}
@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	entryKey
+)
+
+// WithRequestID returns a copy of ctx carrying the request ID and a log entry tagged with it
+func WithRequestID(ctx context.Context, log *logrus.Logger, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, requestID)
+	return context.WithValue(ctx, entryKey, log.WithField("request_id", requestID))
+}
+
+// RequestID returns the ID of the request ctx belongs to, or an empty string outside of a request
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// FromContext returns the request-scoped log entry of ctx, falling back to log outside of
+// a request. The entry carries ctx so that hooks can add its trace IDs.
+func FromContext(ctx context.Context, log *logrus.Logger) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey).(*logrus.Entry); ok {
+		return entry.WithContext(ctx)
+	}
+
+	return log.WithContext(ctx)
+}
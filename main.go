@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"simpler-products/commands"
 	"simpler-products/config"
+	custom_errors "simpler-products/errors"
 	"simpler-products/routers"
+	"simpler-products/services"
 	"syscall"
 	"time"
 
@@ -19,6 +23,24 @@ func main() {
 	log.SetFormatter(&logrus.JSONFormatter{})
 	log.SetOutput(os.Stdout)
 
+	// Run a subcommand instead of the server when one is given, e.g. 'migrate up'
+	if len(os.Args) > 1 {
+		cfg, err := config.InitCommand(log)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = commands.Run(cfg, os.Args[1:], os.Stdout)
+		if cfg.DBManager != nil {
+			cfg.DBManager.Close()
+		}
+		cfg.ShutdownTracing(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	cfg, err := config.Init(log)
 	if err != nil {
 		log.Fatal(err)
@@ -26,10 +48,16 @@ func main() {
 
 	router := routers.NewRouter(cfg.Services, log)
 
+	// Requests derive their context from baseCtx, which is cancelled if shutdown times out
+	baseCtx, cancelBase := context.WithCancelCause(context.Background())
+
 	// Create a server instance
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: router,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 
 	// Start the server in a goroutine
@@ -45,18 +73,37 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
-	// close Database connection when app terminates
-	defer cfg.DB.Close()
-	defer log.Debug("Closing Database connection")
+	// report not ready right away so that no new traffic is routed here
+	if healthService, ok := cfg.Services.(services.HealthServiceInterface); ok {
+		healthService.MarkShuttingDown()
+	}
+
+	// stop the health watchdog and close Database connections when app terminates
+	if cfg.DBManager != nil {
+		defer cfg.DBManager.Close()
+		defer log.Debug("Closing Database connection")
+	}
 
 	// Create a context with a timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Abort in-flight requests and their queries once the timeout expires
+	context.AfterFunc(ctx, func() {
+		cancelBase(custom_errors.ErrServerShuttingDown)
+	})
+
 	// Attempt graceful shutdown
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server forced to shutdown: ", err)
 	}
 
+	// Export the spans of the last requests
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := cfg.ShutdownTracing(flushCtx); err != nil {
+		log.Error("Error flushing traces: ", err)
+	}
+
 	log.Println("Server exiting")
 }
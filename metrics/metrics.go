@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "simpler_products"
+
+// RecorderInterface is what the router needs to expose and record HTTP metrics
+type RecorderInterface interface {
+	MetricsHandler() http.Handler
+	ObserveRequest(method, route string, status int, duration time.Duration)
+}
+
+// Recorder holds the application's Prometheus collectors on a dedicated registry
+type Recorder struct {
+	registry        *prometheus.Registry
+	requestsTotal   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	methodDuration  *prometheus.HistogramVec
+}
+
+func NewRecorder() *Recorder {
+	r := &Recorder{
+		registry: prometheus.NewRegistry(),
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests handled, by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests, by route template, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		methodDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "service_method_duration_seconds",
+			Help:      "Duration of service method calls including their database queries, by service, method and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"service", "method", "outcome"}),
+	}
+
+	r.registry.MustRegister(
+		r.requestsTotal,
+		r.requestDuration,
+		r.methodDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return r
+}
+
+// RegisterDB exposes the connection pool statistics of db (sql.DBStats) as gauges
+func (r *Recorder) RegisterDB(db *sql.DB, dbName string) error {
+	return r.registry.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// MetricsHandler serves the collected metrics in the Prometheus text exposition format
+func (r *Recorder) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{Registry: r.registry})
+}
+
+func (r *Recorder) ObserveRequest(method, route string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	r.requestsTotal.WithLabelValues(route, method, statusLabel).Inc()
+	r.requestDuration.WithLabelValues(route, method, statusLabel).Observe(duration.Seconds())
+}
+
+func (r *Recorder) ObserveMethod(service, method string, duration time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+
+	r.methodDuration.WithLabelValues(service, method, outcome).Observe(duration.Seconds())
+}
@@ -13,6 +13,9 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// CallerKey is the context key of the subject of the token a request is authenticated with
+const CallerKey = "caller"
+
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the Authorization header
@@ -71,7 +74,12 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Token is valid, continue to the next handler
+		// Token is valid, remember who the caller is and continue to the next handler
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if subject, ok := claims["sub"].(string); ok {
+				c.Set(CallerKey, subject)
+			}
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/idempotency"
+	"simpler-products/logging"
+	"simpler-products/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks the responses replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength bounds the keys accepted from clients, as they are kept in memory
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes the requests sent with an Idempotency-Key safe to retry. The first
+// response for a key and caller is recorded and replayed to the retries, and reusing the
+// key for a different request, or while the first one is in progress, is rejected with
+// 409 Conflict. Server errors are not recorded, so those requests can be retried.
+// Requests without the header are processed as usual.
+func Idempotency(store idempotency.StoreInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength || !printableASCII(key) {
+			c.Status(http.StatusBadRequest)
+			c.Set("errors", custom_errors.ErrInvalidIdempotencyKey)
+			c.Abort()
+			return
+		}
+
+		// Read the body to fingerprint the request, and put it back for the handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			c.Set("errors", err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Keys are scoped to the caller, so that callers cannot replay each other's responses
+		key = callerOf(c) + "\x00" + key
+
+		recorded, err := store.Begin(key, fingerprint(c.Request, body))
+		if err != nil {
+			c.Status(http.StatusConflict)
+			c.Set("errors", err)
+			c.Abort()
+			return
+		}
+		if recorded != nil {
+			replay(c, recorded)
+			c.Abort()
+			return
+		}
+
+		completed := false
+		defer func() {
+			// The request failed or panicked, let it be retried
+			if !completed {
+				store.Release(key)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		// Write the response now, rather than in ResponseFormatter, to record it
+		if !c.Writer.Written() {
+			writeResponse(c)
+		}
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError || status == StatusClientClosedRequest {
+			return
+		}
+
+		store.Complete(key, idempotency.Response{
+			Status: status,
+			Header: recorder.Header().Clone(),
+			Body:   recorder.body.Bytes(),
+		})
+		completed = true
+	}
+}
+
+// callerOf identifies the caller of a request, by the subject of its token when it is
+// authenticated and by its IP address otherwise
+func callerOf(c *gin.Context) string {
+	if subject := c.GetString(CallerKey); subject != "" {
+		return "sub:" + subject
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+// fingerprint identifies a request by its method, URL and body
+func fingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replay sends a recorded response, keeping the headers already set for this request
+// such as its X-Request-ID, and the request and trace IDs of this request in the envelope
+func replay(c *gin.Context, recorded *idempotency.Response) {
+	body := replayedBody(c, recorded.Body)
+
+	header := c.Writer.Header()
+	for name, values := range recorded.Header {
+		if _, ok := header[name]; !ok {
+			header[name] = values
+		}
+	}
+	header.Del("Content-Length")
+	header.Set(IdempotentReplayedHeader, "true")
+
+	c.Writer.WriteHeader(recorded.Status)
+	c.Writer.Write(body)
+}
+
+// replayedEnvelope is the response envelope written by writeResponse, its payload left encoded
+type replayedEnvelope struct {
+	Status     json.RawMessage `json:"status"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	Pagination json.RawMessage `json:"pagination,omitempty"`
+	Facets     json.RawMessage `json:"facets,omitempty"`
+	Errors     json.RawMessage `json:"errors,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	TraceID    string          `json:"trace_id,omitempty"`
+}
+
+// replayedBody returns the recorded envelope with the request and trace IDs of the retry.
+// Bodies written by the handlers themselves are replayed as they are.
+func replayedBody(c *gin.Context, recorded []byte) []byte {
+	var envelope replayedEnvelope
+	if err := json.Unmarshal(recorded, &envelope); err != nil || envelope.Status == nil {
+		return recorded
+	}
+
+	envelope.RequestID = logging.RequestID(c.Request.Context())
+	envelope.TraceID = tracing.TraceID(c.Request.Context())
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return recorded
+	}
+
+	return body
+}
+
+// responseRecorder keeps a copy of the response body written through it
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -2,6 +2,8 @@ package middlewares
 
 import (
 	"encoding/json"
+	"simpler-products/logging"
+	"simpler-products/tracing"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +20,12 @@ func JSONLoggerMiddleware() gin.HandlerFunc {
 			log["remote_addr"] = params.ClientIP
 			log["response_time"] = params.Latency.String()
 			log["user_agent"] = params.Request.UserAgent()
+			if requestID := logging.RequestID(params.Request.Context()); requestID != "" {
+				log["request_id"] = requestID
+			}
+			if traceID := tracing.TraceID(params.Request.Context()); traceID != "" {
+				log["trace_id"] = traceID
+			}
 
 			s, _ := json.Marshal(log)
 			return string(s) + "\n"
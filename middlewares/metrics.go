@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"simpler-products/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records every request labelled by its route template (e.g. /api/v1/products/:id),
+// so that metric cardinality does not grow with the ids in the paths
+func Metrics(recorder metrics.RecorderInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		recorder.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
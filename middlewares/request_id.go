@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"simpler-products/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds the IDs accepted from clients, as they end up in every log line
+	maxRequestIDLength = 128
+)
+
+// RequestID reuses the caller's X-Request-ID, or generates one, and makes it available to
+// the rest of the request: in its context, its logs, the response headers and the envelope
+func RequestID(log *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx := logging.WithRequestID(c.Request.Context(), log, requestID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(RequestIDHeader, requestID)
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request_id", requestID))
+
+		c.Next()
+	}
+}
+
+// validRequestID accepts non-empty IDs made of printable ASCII characters
+func validRequestID(requestID string) bool {
+	return requestID != "" && len(requestID) <= maxRequestIDLength && printableASCII(requestID)
+}
+
+// printableASCII tells whether s is made of printable ASCII characters, spaces excluded
+func printableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,81 +1,134 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
+	custom_errors "simpler-products/errors"
+	"simpler-products/logging"
+	"simpler-products/tracing"
 	"simpler-products/validators"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// StatusClientClosedRequest is the non-standard status used when the client goes away mid-request
+const StatusClientClosedRequest = 499
+
+// contextErrorStatus maps the errors of aborted requests to their status codes
+func contextErrorStatus(err any) (int, bool) {
+	e, ok := err.(error)
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case errors.Is(e, custom_errors.ErrRequestCanceled):
+		return StatusClientClosedRequest, true
+	case errors.Is(e, custom_errors.ErrServerShuttingDown):
+		return http.StatusServiceUnavailable, true
+	case errors.Is(e, custom_errors.ErrRequestTimeout):
+		return http.StatusGatewayTimeout, true
+	default:
+		return 0, false
+	}
+}
+
 func ResponseFormatter(log *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Process the request and get the data to be sent in the response
 		c.Next()
 
-		// Get the data and errors from the context
-		data, dataExists := c.Get("data")
-		pagination, paginationExists := c.Get("pagination")
-		errors, errorsExist := c.Get("errors")
-
-		// Construct the response
-		var response struct {
-			Status     int         `json:"status"`
-			Data       interface{} `json:"data,omitempty"`       // Include data only if present
-			Pagination interface{} `json:"pagination,omitempty"` // Include pagination only if present
-			Errors     interface{} `json:"errors,omitempty"`     // Include errors only if present
+		// Leave responses the handler has written itself untouched
+		if c.Writer.Written() {
+			return
 		}
 
-		// Set the status code based on the presence of errors
-		if errorsExist {
-			if c.Writer.Status() != 0 && c.Writer.Status() != 200 {
-				response.Status = c.Writer.Status()
-			} else {
-				response.Status = http.StatusInternalServerError // Default to 500 if no status is set
-			}
+		writeResponse(c)
+	}
+}
 
-			// Format the errors consistently
-			formattedErrors := make([]map[string]any, 0)
-			switch err := errors.(type) {
-			case *validators.ValidationError:
-				for _, validationError := range err.Errors {
-					for _, errorMsg := range validationError {
-						formattedErrors = append(formattedErrors, map[string]any{
-							"message": errorMsg,
-						})
-					}
-				}
-			case []string:
-				for _, errorMsg := range err {
+// writeResponse sends the data, pagination, facets and errors set in the context in the
+// response envelope
+func writeResponse(c *gin.Context) {
+	// Get the data and errors from the context
+	data, dataExists := c.Get("data")
+	pagination, paginationExists := c.Get("pagination")
+	facets, facetsExist := c.Get("facets")
+	errors, errorsExist := c.Get("errors")
+
+	// Construct the response
+	var response struct {
+		Status     int         `json:"status"`
+		Data       interface{} `json:"data,omitempty"`       // Include data only if present
+		Pagination interface{} `json:"pagination,omitempty"` // Include pagination only if present
+		Facets     interface{} `json:"facets,omitempty"`     // Include facets only if present
+		Errors     interface{} `json:"errors,omitempty"`     // Include errors only if present
+		RequestID  string      `json:"request_id,omitempty"` // Include the request ID only if set
+		TraceID    string      `json:"trace_id,omitempty"`   // Include the trace ID only within a trace
+	}
+
+	if c.Request != nil {
+		response.RequestID = logging.RequestID(c.Request.Context())
+		response.TraceID = tracing.TraceID(c.Request.Context())
+	}
+
+	// Set the status code based on the presence of errors
+	if errorsExist {
+		if status, ok := contextErrorStatus(errors); ok {
+			response.Status = status
+		} else if c.Writer.Status() != 0 && c.Writer.Status() != 200 {
+			response.Status = c.Writer.Status()
+		} else {
+			response.Status = http.StatusInternalServerError // Default to 500 if no status is set
+		}
+
+		// Format the errors consistently
+		formattedErrors := make([]map[string]any, 0)
+		switch err := errors.(type) {
+		case *validators.ValidationError:
+			for _, validationError := range err.Errors {
+				for _, errorMsg := range validationError {
 					formattedErrors = append(formattedErrors, map[string]any{
 						"message": errorMsg,
 					})
 				}
-			default:
+			}
+		case []string:
+			for _, errorMsg := range err {
 				formattedErrors = append(formattedErrors, map[string]any{
-					"message": errors.(error).Error(),
+					"message": errorMsg,
 				})
 			}
-			response.Errors = formattedErrors
+		default:
+			formattedErrors = append(formattedErrors, map[string]any{
+				"message": errors.(error).Error(),
+			})
+		}
+		response.Errors = formattedErrors
+	} else {
+		if c.Writer.Status() != 0 {
+			response.Status = c.Writer.Status()
 		} else {
-			if c.Writer.Status() != 0 {
-				response.Status = c.Writer.Status()
-			} else {
-				response.Status = http.StatusOK // Default to 200 OK if no status is set
-			}
+			response.Status = http.StatusOK // Default to 200 OK if no status is set
+		}
 
-			// Include data only if present
-			if dataExists {
-				response.Data = data
-			}
+		// Include data only if present
+		if dataExists {
+			response.Data = data
+		}
 
-			// Include pagination only if present
-			if paginationExists {
-				response.Pagination = pagination
-			}
+		// Include pagination only if present
+		if paginationExists {
+			response.Pagination = pagination
 		}
 
-		// Send the formatted response
-		c.JSON(response.Status, response)
+		// Include facets only if present
+		if facetsExist {
+			response.Facets = facets
+		}
 	}
+
+	// Send the formatted response
+	c.JSON(response.Status, response)
 }
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "simpler-products/middlewares"
+
+// Tracing continues the trace of the W3C traceparent header, or starts a new one, with a
+// server span per request. Handlers find the span in the request context.
+func Tracing() gin.HandlerFunc {
+	tracer := otel.Tracer(tracerName)
+
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		// Name the span after the route template, like the metrics, to keep its cardinality low
+		route := c.FullPath()
+		spanName := c.Request.Method
+		if route != "" {
+			spanName += " " + route
+		}
+
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if errors, ok := c.Get("errors"); ok {
+			if err, ok := errors.(error); ok {
+				span.RecordError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	xss "github.com/dvwright/xss-mw"
+	"github.com/gin-gonic/gin"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// xssSkippedField is left as sent, like xss-mw does for passwords
+const xssSkippedField = "password"
+
+// RemoveXss strips HTML from the string values of POST and PUT JSON bodies.
+// Other requests (query strings, forms) are handed to xss-mw, whose JSON rewriting turns
+// empty arrays and objects into invalid documents and drops numbers and booleans from arrays
+func RemoveXss() gin.HandlerFunc {
+	var mw xss.XssMw
+	policy := mw.GetBlueMondayPolicy()
+	fallback := mw.RemoveXss()
+
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if (method != http.MethodPost && method != http.MethodPut) || c.ContentType() != gin.MIMEJSON {
+			fallback(c)
+			return
+		}
+
+		if err := sanitizeJSONBody(c.Request, policy); err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// sanitizeJSONBody rewrites the request body with its string values sanitized.
+// Bodies that are not valid JSON are left untouched for the handlers to reject
+func sanitizeJSONBody(req *http.Request, policy *bluemonday.Policy) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	raw, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	var document any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if decoder.Decode(&document) != nil || decoder.More() {
+		req.Body = io.NopCloser(bytes.NewReader(raw))
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(sanitizeValue(document, policy)); err != nil {
+		return err
+	}
+
+	req.Body = io.NopCloser(&body)
+	req.ContentLength = int64(body.Len())
+	req.Header.Set("Content-Length", strconv.Itoa(body.Len()))
+	return nil
+}
+
+// sanitizeValue walks a decoded JSON document, sanitizing strings and keeping every other value as is
+func sanitizeValue(value any, policy *bluemonday.Policy) any {
+	switch v := value.(type) {
+	case string:
+		return policy.Sanitize(v)
+	case []any:
+		for i := range v {
+			v[i] = sanitizeValue(v[i], policy)
+		}
+		return v
+	case map[string]any:
+		for key, item := range v {
+			if key == xssSkippedField {
+				continue
+			}
+			v[key] = sanitizeValue(item, policy)
+		}
+		return v
+	default:
+		return v
+	}
+}
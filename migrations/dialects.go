@@ -0,0 +1,100 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	custom_errors "simpler-products/errors"
+)
+
+const (
+	lockName = "simpler_products_schema_migrations"
+	// lockKey is an arbitrary constant identifying the PostgreSQL advisory lock
+	lockKey = 7346104429481532
+	// lockTimeoutSeconds is how long a runner waits for another one to finish
+	lockTimeoutSeconds = 60
+)
+
+type dialect struct {
+	createTable string
+	// tableExists counts the schema_migrations tables visible to the connection, 0 or 1
+	tableExists   string
+	insertVersion string
+	deleteVersion string
+	// transactional reports whether each migration runs in its own transaction
+	transactional bool
+	lock          func(ctx context.Context, conn *sql.Conn) error
+	unlock        func(ctx context.Context, conn *sql.Conn, runErr error) error
+}
+
+var dialects = map[string]dialect{
+	"mysql": {
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		tableExists:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'schema_migrations'",
+		insertVersion: "INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
+		deleteVersion: "DELETE FROM schema_migrations WHERE version = ?",
+		// DDL statements cause an implicit commit in MySQL
+		transactional: false,
+		lock: func(ctx context.Context, conn *sql.Conn) error {
+			var acquired sql.NullInt64
+			if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeoutSeconds).Scan(&acquired); err != nil {
+				return err
+			}
+			if !acquired.Valid || acquired.Int64 != 1 {
+				return custom_errors.ErrMigrationsLocked
+			}
+			return nil
+		},
+		unlock: func(ctx context.Context, conn *sql.Conn, _ error) error {
+			_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+			return err
+		},
+	},
+	"postgres": {
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+		tableExists:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'",
+		insertVersion: "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+		deleteVersion: "DELETE FROM schema_migrations WHERE version = $1",
+		transactional: true,
+		lock: func(ctx context.Context, conn *sql.Conn) error {
+			// Blocks until the lock is free or ctx is done
+			_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey)
+			return err
+		},
+		unlock: func(ctx context.Context, conn *sql.Conn, _ error) error {
+			_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockKey)
+			return err
+		},
+	},
+	"sqlite": {
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		tableExists:   "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'",
+		insertVersion: "INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
+		deleteVersion: "DELETE FROM schema_migrations WHERE version = ?",
+		// The whole run happens inside the write transaction taken by lock
+		transactional: false,
+		lock: func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+			return err
+		},
+		unlock: func(ctx context.Context, conn *sql.Conn, runErr error) error {
+			if runErr != nil {
+				_, err := conn.ExecContext(ctx, "ROLLBACK")
+				return err
+			}
+			_, err := conn.ExecContext(ctx, "COMMIT")
+			return err
+		},
+	},
+}
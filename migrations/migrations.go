@@ -0,0 +1,354 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+// Migrator applies the embedded migrations of a dialect and records them in schema_migrations
+type Migrator struct {
+	DB         *sql.DB
+	Log        *logrus.Logger
+	dialect    dialect
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, dialectName string, log *logrus.Logger) (*Migrator, error) {
+	d, ok := dialects[dialectName]
+	if !ok {
+		return nil, fmt.Errorf("migrations are not supported for dialect %q", dialectName)
+	}
+
+	migrations, err := load(dialectName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		DB:         db,
+		Log:        log,
+		dialect:    d,
+		migrations: migrations,
+	}, nil
+}
+
+// load reads '<version>_<name>.up.sql' and '<version>_<name>.down.sql' files ordered by version
+func load(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", fileName, err)
+		}
+
+		contents, err := fs.ReadFile(files, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+
+		if direction == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			m.Log.Infof("Applying migration %d_%s", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration.Up, m.dialect.insertVersion, migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last n applied migrations and returns how many were reverted
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	reverted := 0
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < n; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			m.Log.Infof("Reverting migration %d_%s", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration.Down, m.dialect.deleteVersion, migration.Version); err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return nil, err
+	}
+
+	versions, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, applied := versions[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   applied,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// Pending returns how many known migrations have not been applied. Unlike Status it only
+// reads, so that it can be polled, e.g. by health checks, on read-only users or replicas
+// without taking schema locks. A missing schema_migrations table leaves every migration pending.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var tables int
+	if err := conn.QueryRowContext(ctx, m.dialect.tableExists).Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return len(m.migrations), nil
+	}
+
+	versions, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, migration := range m.migrations {
+		if _, ok := versions[migration.Version]; !ok {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+// withLock runs fn on a dedicated connection while holding the dialect's migration lock,
+// so that concurrent runners (e.g. several replicas auto-migrating) apply each migration once
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := m.dialect.lock(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := m.dialect.unlock(context.WithoutCancel(ctx), conn, err); unlockErr != nil && err == nil {
+			err = unlockErr
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]string, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]string)
+	for rows.Next() {
+		var version int64
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+// apply executes a migration script followed by its bookkeeping statement,
+// inside a transaction when the dialect supports transactional DDL
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...any) error {
+	type execer interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	}
+
+	var exec execer = conn
+	var tx *sql.Tx
+	if m.dialect.transactional {
+		var err error
+		tx, err = conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		exec = tx
+	}
+
+	for _, statement := range splitStatements(script) {
+		if _, err := exec.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := exec.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	if tx != nil {
+		return tx.Commit()
+	}
+
+	return nil
+}
+
+// splitStatements splits a script on semicolons that end a line
+func splitStatements(script string) []string {
+	statements := make([]string, 0)
+
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			if statement := strings.TrimSpace(current.String()); statement != ";" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+		}
+	}
+
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+
+	return statements
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS Products (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL
+);
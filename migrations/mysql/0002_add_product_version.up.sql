@@ -0,0 +1 @@
+ALTER TABLE Products ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
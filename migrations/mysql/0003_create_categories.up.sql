@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS Categories (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    parent_id VARCHAR(255) NULL,
+    FOREIGN KEY (parent_id) REFERENCES Categories (id)
+);
+
+CREATE TABLE IF NOT EXISTS ProductCategories (
+    product_id VARCHAR(255) NOT NULL,
+    category_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (product_id, category_id),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES Categories (id)
+);
+
+CREATE INDEX idx_product_categories_category_id ON ProductCategories (category_id);
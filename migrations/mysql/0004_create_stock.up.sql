@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS Stock (
+    product_id VARCHAR(255) PRIMARY KEY,
+    on_hand BIGINT NOT NULL DEFAULT 0,
+    reserved BIGINT NOT NULL DEFAULT 0,
+    movement_count BIGINT NOT NULL DEFAULT 0,
+    CHECK (reserved >= 0 AND reserved <= on_hand),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS StockMovements (
+    product_id VARCHAR(255) NOT NULL,
+    sequence_number BIGINT NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    quantity BIGINT NOT NULL,
+    on_hand BIGINT NOT NULL,
+    reserved BIGINT NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    created_at VARCHAR(32) NOT NULL,
+    PRIMARY KEY (product_id, sequence_number),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE
+);
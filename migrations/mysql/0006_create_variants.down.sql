@@ -0,0 +1,15 @@
+ALTER TABLE StockMovements DROP COLUMN variant_id;
+
+DELETE FROM WarehouseStock WHERE variant_id <> '';
+
+UPDATE Stock SET
+    on_hand = (SELECT COALESCE(SUM(w.on_hand), 0) FROM WarehouseStock w WHERE w.product_id = Stock.product_id),
+    reserved = (SELECT COALESCE(SUM(w.reserved), 0) FROM WarehouseStock w WHERE w.product_id = Stock.product_id);
+
+ALTER TABLE WarehouseStock DROP PRIMARY KEY, ADD PRIMARY KEY (product_id, warehouse_id);
+
+ALTER TABLE WarehouseStock DROP COLUMN variant_id;
+
+DROP TABLE IF EXISTS Variants;
+
+DROP TABLE IF EXISTS ProductOptions;
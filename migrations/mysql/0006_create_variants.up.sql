@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS ProductOptions (
+    product_id VARCHAR(255) PRIMARY KEY,
+    options TEXT NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Variants (
+    id VARCHAR(255) PRIMARY KEY,
+    product_id VARCHAR(255) NOT NULL,
+    sku VARCHAR(255) NOT NULL UNIQUE,
+    options TEXT NOT NULL,
+    options_key CHAR(64) NOT NULL,
+    price DECIMAL(10, 2),
+    UNIQUE (product_id, options_key),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE
+);
+
+ALTER TABLE WarehouseStock ADD COLUMN variant_id VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE WarehouseStock DROP PRIMARY KEY, ADD PRIMARY KEY (product_id, variant_id, warehouse_id);
+
+ALTER TABLE StockMovements ADD COLUMN variant_id VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ProductCategories;
+
+DROP TABLE IF EXISTS Categories;
@@ -0,0 +1,7 @@
+ALTER TABLE StockMovements DROP COLUMN transfer_id;
+
+ALTER TABLE StockMovements DROP COLUMN warehouse_id;
+
+DROP TABLE IF EXISTS WarehouseStock;
+
+DROP TABLE IF EXISTS Warehouses;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS Products (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    price REAL NOT NULL
+);
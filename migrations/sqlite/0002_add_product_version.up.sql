@@ -0,0 +1 @@
+ALTER TABLE Products ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS Categories (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    parent_id TEXT,
+    FOREIGN KEY (parent_id) REFERENCES Categories (id)
+);
+
+CREATE TABLE IF NOT EXISTS ProductCategories (
+    product_id TEXT NOT NULL,
+    category_id TEXT NOT NULL,
+    PRIMARY KEY (product_id, category_id),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES Categories (id)
+);
+
+CREATE INDEX idx_product_categories_category_id ON ProductCategories (category_id);
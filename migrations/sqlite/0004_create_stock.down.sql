@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS StockMovements;
+
+DROP TABLE IF EXISTS Stock;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS Stock (
+    product_id TEXT PRIMARY KEY,
+    on_hand INTEGER NOT NULL DEFAULT 0,
+    reserved INTEGER NOT NULL DEFAULT 0,
+    movement_count INTEGER NOT NULL DEFAULT 0,
+    CHECK (reserved >= 0 AND reserved <= on_hand),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS StockMovements (
+    product_id TEXT NOT NULL,
+    sequence_number INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    on_hand INTEGER NOT NULL,
+    reserved INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (product_id, sequence_number),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE
+);
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS Warehouses (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    region TEXT NOT NULL,
+    priority INTEGER NOT NULL
+);
+
+INSERT INTO Warehouses (id, name, region, priority) VALUES ('default', 'Default', '', 0);
+
+CREATE TABLE IF NOT EXISTS WarehouseStock (
+    product_id TEXT NOT NULL,
+    warehouse_id TEXT NOT NULL,
+    on_hand INTEGER NOT NULL DEFAULT 0,
+    reserved INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, warehouse_id),
+    CHECK (reserved >= 0 AND reserved <= on_hand),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE,
+    FOREIGN KEY (warehouse_id) REFERENCES Warehouses (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_warehouse_stock_warehouse_id ON WarehouseStock (warehouse_id);
+
+INSERT INTO WarehouseStock (product_id, warehouse_id, on_hand, reserved)
+SELECT product_id, 'default', on_hand, reserved FROM Stock;
+
+ALTER TABLE StockMovements ADD COLUMN warehouse_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE StockMovements ADD COLUMN transfer_id TEXT NOT NULL DEFAULT '';
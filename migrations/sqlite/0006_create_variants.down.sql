@@ -0,0 +1,29 @@
+ALTER TABLE StockMovements DROP COLUMN variant_id;
+
+CREATE TABLE WarehouseStockByProduct (
+    product_id TEXT NOT NULL,
+    warehouse_id TEXT NOT NULL,
+    on_hand INTEGER NOT NULL DEFAULT 0,
+    reserved INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, warehouse_id),
+    CHECK (reserved >= 0 AND reserved <= on_hand),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE,
+    FOREIGN KEY (warehouse_id) REFERENCES Warehouses (id) ON DELETE CASCADE
+);
+
+INSERT INTO WarehouseStockByProduct (product_id, warehouse_id, on_hand, reserved)
+SELECT product_id, warehouse_id, on_hand, reserved FROM WarehouseStock WHERE variant_id = '';
+
+DROP TABLE WarehouseStock;
+
+ALTER TABLE WarehouseStockByProduct RENAME TO WarehouseStock;
+
+CREATE INDEX idx_warehouse_stock_warehouse_id ON WarehouseStock (warehouse_id);
+
+UPDATE Stock SET
+    on_hand = (SELECT COALESCE(SUM(w.on_hand), 0) FROM WarehouseStock w WHERE w.product_id = Stock.product_id),
+    reserved = (SELECT COALESCE(SUM(w.reserved), 0) FROM WarehouseStock w WHERE w.product_id = Stock.product_id);
+
+DROP TABLE IF EXISTS Variants;
+
+DROP TABLE IF EXISTS ProductOptions;
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS ProductOptions (
+    product_id TEXT PRIMARY KEY,
+    options TEXT NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Variants (
+    id TEXT PRIMARY KEY,
+    product_id TEXT NOT NULL,
+    sku TEXT NOT NULL UNIQUE,
+    options TEXT NOT NULL,
+    options_key TEXT NOT NULL,
+    price REAL,
+    UNIQUE (product_id, options_key),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE
+);
+
+CREATE TABLE WarehouseStockByVariant (
+    product_id TEXT NOT NULL,
+    variant_id TEXT NOT NULL DEFAULT '',
+    warehouse_id TEXT NOT NULL,
+    on_hand INTEGER NOT NULL DEFAULT 0,
+    reserved INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, variant_id, warehouse_id),
+    CHECK (reserved >= 0 AND reserved <= on_hand),
+    FOREIGN KEY (product_id) REFERENCES Products (id) ON DELETE CASCADE,
+    FOREIGN KEY (warehouse_id) REFERENCES Warehouses (id) ON DELETE CASCADE
+);
+
+INSERT INTO WarehouseStockByVariant (product_id, warehouse_id, on_hand, reserved)
+SELECT product_id, warehouse_id, on_hand, reserved FROM WarehouseStock;
+
+DROP TABLE WarehouseStock;
+
+ALTER TABLE WarehouseStockByVariant RENAME TO WarehouseStock;
+
+CREATE INDEX idx_warehouse_stock_warehouse_id ON WarehouseStock (warehouse_id);
+
+ALTER TABLE StockMovements ADD COLUMN variant_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,62 @@
+package models
+
+import "strings"
+
+// Allocation strategies, ordering the warehouses stock is allocated from
+const (
+	// AllocatePriority allocates from the warehouses with the lowest priority first
+	AllocatePriority = "priority"
+	// AllocateNearest allocates from the warehouses nearest to a region first, then by priority
+	AllocateNearest = "nearest"
+)
+
+// AllocationRequest asks for the warehouses to take a quantity of a product from
+type AllocationRequest struct {
+	// VariantID is the variant of the product to allocate, if any
+	VariantID string
+	Quantity  int64
+	Strategy  string
+	// Region is the region to allocate near to with AllocateNearest, e.g. the shipping address'
+	Region string
+	// Split allows taking the quantity from several warehouses, otherwise a single one is
+	// picked. When nil, the default of the service applies.
+	Split *bool
+	// Reserve reserves the allocated stock, along with the allocation
+	Reserve bool
+	// Reason is recorded along with the reservations
+	Reason string
+}
+
+// Allocation is the quantity to take from a warehouse
+type Allocation struct {
+	WarehouseID string `json:"warehouse_id"`
+	Quantity    int64  `json:"quantity"`
+}
+
+// AllocationPlan is the outcome of an allocation request
+type AllocationPlan struct {
+	ProductID   string       `json:"product_id"`
+	VariantID   string       `json:"variant_id,omitempty"`
+	Quantity    int64        `json:"quantity"`
+	Strategy    string       `json:"strategy"`
+	Allocations []Allocation `json:"allocations"`
+	// Reserved tells whether the allocated stock was reserved
+	Reserved bool `json:"reserved"`
+}
+
+// RegionAffinity is the number of leading segments region codes a and b have in common,
+// ignoring case. The higher, the nearer.
+func RegionAffinity(a, b string) int {
+	if a == "" || b == "" {
+		return 0
+	}
+
+	as := strings.Split(strings.ToUpper(a), "-")
+	bs := strings.Split(strings.ToUpper(b), "-")
+	n := 0
+	for n < len(as) && n < len(bs) && as[n] == bs[n] {
+		n++
+	}
+
+	return n
+}
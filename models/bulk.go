@@ -0,0 +1,33 @@
+package models
+
+// Kinds of bulk operations
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+	// BulkUpsert creates the product with the given ID, or updates it when it exists
+	BulkUpsert = "upsert"
+)
+
+// Bulk modes, either all the operations are applied or none, or each is applied on its own
+const (
+	BulkAllOrNothing = "all_or_nothing"
+	BulkBestEffort   = "best_effort"
+)
+
+// BulkOperation creates, updates or deletes a single product as part of a bulk request
+type BulkOperation struct {
+	Op string `json:"op"`
+	// ID is the product to update or delete
+	ID      string   `json:"id,omitempty"`
+	Product *Product `json:"product,omitempty"`
+	// Version, when non-zero, is the only version of the product an update or delete applies to
+	Version int64 `json:"version,omitempty"`
+}
+
+// BulkResult is the outcome of a bulk operation: the created or updated product, or the error
+// the operation failed with
+type BulkResult struct {
+	Product *Product
+	Err     error
+}
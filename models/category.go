@@ -0,0 +1,61 @@
+package models
+
+// Category organises products in a tree. Products can be assigned to several categories.
+type Category struct {
+	ID   string `json:"id" binding:"-"`
+	Name string `json:"name" binding:"required"`
+	// ParentID is the category this one is nested in, nil for top-level categories
+	ParentID *string `json:"parent_id"`
+}
+
+// CategoryNode is a category along with the categories nested in it
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// CategoryTree arranges categories in trees and returns their roots. Children keep the
+// order they have in categories.
+func CategoryTree(categories []Category) []*CategoryNode {
+	nodes := make(map[string]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category, Children: make([]*CategoryNode, 0)}
+	}
+
+	roots := make([]*CategoryNode, 0)
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	return roots
+}
+
+// Descendants returns id followed by the IDs of the categories nested in it, at any depth
+func Descendants(categories []Category, id string) []string {
+	children := make(map[string][]string, len(categories))
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	descendants := []string{id}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(descendants); i++ {
+		for _, child := range children[descendants[i]] {
+			if !seen[child] {
+				seen[child] = true
+				descendants = append(descendants, child)
+			}
+		}
+	}
+
+	return descendants
+}
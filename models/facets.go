@@ -0,0 +1,140 @@
+package models
+
+import (
+	"math"
+	"slices"
+)
+
+// FacetQuery selects the facets computed alongside a listing or a search, nil ones are left out
+type FacetQuery struct {
+	Price *PriceBuckets
+}
+
+// PriceBuckets splits prices either at fixed Edges, or into Quantiles buckets holding
+// about as many products each
+type PriceBuckets struct {
+	// Edges are strictly increasing
+	Edges     []float64
+	Quantiles int
+}
+
+// QuantilePositions returns the positions, among count prices in ascending order, of the
+// prices the quantile buckets start at
+func (pb PriceBuckets) QuantilePositions(count int) []int {
+	positions := make([]int, 0, pb.Quantiles)
+	if count == 0 {
+		return positions
+	}
+
+	for k := 1; k < pb.Quantiles; k++ {
+		positions = append(positions, k*count/pb.Quantiles)
+	}
+
+	return positions
+}
+
+// Facets summarizes the products matching a listing or a search
+type Facets struct {
+	Price *PriceFacet `json:"price,omitempty"`
+}
+
+// PriceFacet holds price statistics and the number of products in each price bucket.
+// The statistics are null when no product matches.
+type PriceFacet struct {
+	Count   int           `json:"count"`
+	Min     *float64      `json:"min"`
+	Max     *float64      `json:"max"`
+	Avg     *float64      `json:"avg"`
+	Buckets []PriceBucket `json:"buckets"`
+}
+
+// PriceBucket counts the products priced from From, inclusive, to To, exclusive.
+// A nil bound leaves the bucket open on that side.
+type PriceBucket struct {
+	From  *float64 `json:"from"`
+	To    *float64 `json:"to"`
+	Count int      `json:"count"`
+}
+
+// NewPriceBuckets returns the buckets delimited by edges, with their counts, which hold
+// one more entry than edges
+func NewPriceBuckets(edges []float64, counts []int) []PriceBucket {
+	buckets := make([]PriceBucket, 0, len(counts))
+
+	var from *float64
+	for i, count := range counts {
+		bucket := PriceBucket{From: from, Count: count}
+		if i < len(edges) {
+			to := edges[i]
+			bucket.To = &to
+			from = &to
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets
+}
+
+// QuantileEdges returns the edges of quantile buckets, given the lowest price and the
+// prices at the QuantilePositions. Skewed prices repeat, and would leave some buckets
+// empty, so repeated edges and edges at the lowest price are dropped.
+func QuantileEdges(min float64, prices []float64) []float64 {
+	edges := make([]float64, 0, len(prices))
+	for _, price := range prices {
+		if price > min && (len(edges) == 0 || price > edges[len(edges)-1]) {
+			edges = append(edges, price)
+		}
+	}
+
+	return edges
+}
+
+// PriceFacetOf computes the price facet of the given prices
+func PriceFacetOf(prices []float64, buckets PriceBuckets) *PriceFacet {
+	sorted := slices.Clone(prices)
+	slices.Sort(sorted)
+
+	facet := &PriceFacet{Count: len(sorted)}
+	if len(sorted) > 0 {
+		sum := 0.0
+		for _, price := range sorted {
+			sum += price
+		}
+		facet.SetStats(sorted[0], sorted[len(sorted)-1], sum/float64(len(sorted)))
+	}
+
+	edges := buckets.Edges
+	if buckets.Quantiles > 0 {
+		if len(sorted) == 0 {
+			facet.Buckets = make([]PriceBucket, 0)
+			return facet
+		}
+
+		quantiles := make([]float64, 0, buckets.Quantiles)
+		for _, position := range buckets.QuantilePositions(len(sorted)) {
+			quantiles = append(quantiles, sorted[position])
+		}
+		edges = QuantileEdges(sorted[0], quantiles)
+	}
+
+	counts := make([]int, len(edges)+1)
+	for _, price := range sorted {
+		// The bucket of a price is the number of edges it is not below
+		bucket, _ := slices.BinarySearchFunc(edges, price, func(edge, price float64) int {
+			if edge <= price {
+				return -1
+			}
+			return 1
+		})
+		counts[bucket]++
+	}
+	facet.Buckets = NewPriceBuckets(edges, counts)
+
+	return facet
+}
+
+// SetStats sets the price statistics, rounding the average to cents
+func (f *PriceFacet) SetStats(min, max, avg float64) {
+	avg = math.Round(avg*100) / 100
+	f.Min, f.Max, f.Avg = &min, &max, &avg
+}
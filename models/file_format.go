@@ -0,0 +1,33 @@
+package models
+
+import "strings"
+
+// Formats of the files products are imported from and exported to
+const (
+	FormatCSV    = "csv"
+	FormatTSV    = "tsv"
+	FormatNDJSON = "ndjson"
+)
+
+// formulaPrefixes are the leading characters making spreadsheets read a cell as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// EscapeFormula prefixes text with a quote when spreadsheets would read it as a formula, so
+// that exported files are safe to open in them
+func EscapeFormula(text string) string {
+	if text != "" && strings.ContainsRune(formulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+
+	return text
+}
+
+// UnescapeFormula removes the quote EscapeFormula adds, so that exports are imported back as
+// they were
+func UnescapeFormula(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(text[1])) {
+		return text[1:]
+	}
+
+	return text
+}
@@ -5,4 +5,7 @@ type Product struct {
 	Name        string  `json:"name" binding:"required"`
 	Description string  `json:"description" binding:"required"`
 	Price       float64 `json:"price" binding:"required,gt=0"`
+	// Version is incremented by every write and sent as the ETag. Writes given a product,
+	// or a patch, with a non-zero Version only apply to that version.
+	Version int64 `json:"-" binding:"-"`
 }
@@ -0,0 +1,62 @@
+package models
+
+// ProductPatch holds the product fields to change, nil ones are left as they are
+type ProductPatch struct {
+	Name        *string
+	Description *string
+	Price       *float64
+	// Version, when non-zero, is the only version of the product the patch applies to
+	Version int64
+}
+
+// DiffProducts returns the patch turning from into to
+func DiffProducts(from, to Product) ProductPatch {
+	var patch ProductPatch
+	if to.Name != from.Name {
+		patch.Name = &to.Name
+	}
+	if to.Description != from.Description {
+		patch.Description = &to.Description
+	}
+	if to.Price != from.Price {
+		patch.Price = &to.Price
+	}
+
+	return patch
+}
+
+// Fields returns the JSON names of the fields the patch changes
+func (p ProductPatch) Fields() []string {
+	fields := make([]string, 0, 3)
+	if p.Name != nil {
+		fields = append(fields, "name")
+	}
+	if p.Description != nil {
+		fields = append(fields, "description")
+	}
+	if p.Price != nil {
+		fields = append(fields, "price")
+	}
+
+	return fields
+}
+
+// IsEmpty tells whether the patch leaves products unchanged
+func (p ProductPatch) IsEmpty() bool {
+	return p.Name == nil && p.Description == nil && p.Price == nil
+}
+
+// Apply returns product with the patch applied
+func (p ProductPatch) Apply(product Product) Product {
+	if p.Name != nil {
+		product.Name = *p.Name
+	}
+	if p.Description != nil {
+		product.Description = *p.Description
+	}
+	if p.Price != nil {
+		product.Price = *p.Price
+	}
+
+	return product
+}
@@ -0,0 +1,55 @@
+package models
+
+// ProductSortFields are the fields product listings can be sorted by
+var ProductSortFields = []string{"id", "name", "price"}
+
+// ProductFilter narrows down a product listing, zero values leave a criterion out
+type ProductFilter struct {
+	// Name matches the products whose name contains it, ignoring case
+	Name     string
+	PriceMin *float64
+	PriceMax *float64
+	// Category matches the products assigned to it or to one of its descendants, which the
+	// products service resolves into CategoryIDs
+	Category string
+	// CategoryIDs matches the products assigned to any of these categories
+	CategoryIDs []string
+}
+
+// SortField orders a listing by one of ProductSortFields
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// Keyset positions a listing relative to a product by the values of its sortable fields,
+// so that pages stay consistent while products are inserted or deleted
+type Keyset struct {
+	ID    string
+	Name  string
+	Price float64
+	// Backward selects the products preceding the position instead of the ones following it
+	Backward bool
+}
+
+// KeysetOf returns the position of product in a listing
+func KeysetOf(product Product, backward bool) Keyset {
+	return Keyset{
+		ID:       product.ID,
+		Name:     product.Name,
+		Price:    product.Price,
+		Backward: backward,
+	}
+}
+
+// ProductQuery describes a page of a filtered and sorted product listing.
+// Products are always ordered by id last, so that pages are stable.
+type ProductQuery struct {
+	Filter ProductFilter
+	Sort   []SortField
+	Limit  int
+	Offset int
+	// Keyset, when set, replaces Offset: the page holds the Limit products closest to it,
+	// still in the requested order
+	Keyset *Keyset
+}
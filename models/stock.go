@@ -0,0 +1,126 @@
+package models
+
+import "time"
+
+// Kinds of stock movements
+const (
+	// StockAdjust adds to, or with a negative quantity removes from, the stock on hand,
+	// e.g. on deliveries and stocktakes
+	StockAdjust = "adjust"
+	// StockReserve sets aside available stock, e.g. for an order being placed
+	StockReserve = "reserve"
+	// StockRelease returns reserved stock to the available stock, e.g. on cancellations
+	StockRelease = "release"
+	// StockCommit removes reserved stock from the stock on hand, e.g. once an order ships
+	StockCommit = "commit"
+	// StockTransferOut and StockTransferIn are the paired movements of a transfer of stock on
+	// hand from a warehouse to another
+	StockTransferOut = "transfer_out"
+	StockTransferIn  = "transfer_in"
+)
+
+// Stock is the inventory of a product, or of one of its variants, in total and per warehouse.
+// Reserved stock is on hand but not available.
+type Stock struct {
+	ProductID  string           `json:"product_id"`
+	VariantID  string           `json:"variant_id,omitempty"`
+	OnHand     int64            `json:"on_hand"`
+	Reserved   int64            `json:"reserved"`
+	Available  int64            `json:"available"`
+	Warehouses []WarehouseStock `json:"warehouses"`
+}
+
+// WarehouseStock is the inventory of a product in a warehouse, of one of its variants when
+// VariantID is set
+type WarehouseStock struct {
+	VariantID   string `json:"variant_id,omitempty"`
+	WarehouseID string `json:"warehouse_id"`
+	OnHand      int64  `json:"on_hand"`
+	Reserved    int64  `json:"reserved"`
+	Available   int64  `json:"available"`
+}
+
+// NewWarehouseStock returns the stock of a product in a warehouse with the given quantities
+func NewWarehouseStock(warehouseID string, onHand, reserved int64) WarehouseStock {
+	return WarehouseStock{WarehouseID: warehouseID, OnHand: onHand, Reserved: reserved, Available: onHand - reserved}
+}
+
+// NewStock returns the stock of a product held in the given warehouses
+func NewStock(productID string, warehouses []WarehouseStock) Stock {
+	stock := Stock{ProductID: productID, Warehouses: warehouses}
+	if stock.Warehouses == nil {
+		stock.Warehouses = make([]WarehouseStock, 0)
+	}
+	for _, warehouse := range warehouses {
+		stock.OnHand += warehouse.OnHand
+		stock.Reserved += warehouse.Reserved
+	}
+	stock.Available = stock.OnHand - stock.Reserved
+
+	return stock
+}
+
+// OfVariant returns the stock of the variant of the product, the stock of the product itself
+// when variantID is empty
+func (s Stock) OfVariant(variantID string) Stock {
+	warehouses := make([]WarehouseStock, 0, len(s.Warehouses))
+	for _, warehouse := range s.Warehouses {
+		if warehouse.VariantID == variantID {
+			warehouses = append(warehouses, warehouse)
+		}
+	}
+
+	stock := NewStock(s.ProductID, warehouses)
+	stock.VariantID = variantID
+	return stock
+}
+
+// StockTransfer moves stock on hand of a product, or of one of its variants, from a warehouse
+// to another
+type StockTransfer struct {
+	ProductID string
+	VariantID string
+	From      string
+	To        string
+	Quantity  int64
+	Reason    string
+}
+
+// StockMovement is an entry of the stock ledger of a product
+type StockMovement struct {
+	ProductID string `json:"product_id"`
+	// VariantID is the variant of the product moved, if any
+	VariantID   string `json:"variant_id,omitempty"`
+	WarehouseID string `json:"warehouse_id"`
+	// Sequence numbers the movements of a product from 1, in the order they were applied
+	Sequence int64  `json:"sequence"`
+	Type     string `json:"type"`
+	Quantity int64  `json:"quantity"`
+	// TransferID pairs the movements of a transfer
+	TransferID string `json:"transfer_id,omitempty"`
+	// OnHand and Reserved are the quantities left by the movement in its warehouse
+	OnHand    int64     `json:"on_hand"`
+	Reserved  int64     `json:"reserved"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Deltas returns the changes the movement makes to the stock on hand and reserved
+func (m StockMovement) Deltas() (onHand, reserved int64) {
+	switch m.Type {
+	case StockAdjust:
+		return m.Quantity, 0
+	case StockReserve:
+		return 0, m.Quantity
+	case StockRelease:
+		return 0, -m.Quantity
+	case StockCommit:
+		return -m.Quantity, -m.Quantity
+	case StockTransferOut:
+		return -m.Quantity, 0
+	case StockTransferIn:
+		return m.Quantity, 0
+	default:
+		return 0, 0
+	}
+}
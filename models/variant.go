@@ -0,0 +1,154 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Limits of the options of a product, which keep the variants generated from them, all built
+// in memory and stored together, to a manageable number
+const (
+	MaxProductOptions      = 5
+	MaxOptionValues        = 50
+	MaxVariantCombinations = 1000
+)
+
+// Lengths, in characters, of SKUs and of the names and values of options. SKUs are stored
+// in VARCHAR(255) columns, options within every variant of the product.
+const (
+	MaxSKULength         = 255
+	MaxOptionNameLength  = 100
+	MaxOptionValueLength = 100
+)
+
+// ProductOption is an axis a product varies along, e.g. size, with the values it takes
+type ProductOption struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Variant is a combination of the option values of a product, e.g. a size and a colour,
+// sold under its own SKU and stocked on its own
+type Variant struct {
+	ID        string `json:"id" binding:"-"`
+	ProductID string `json:"product_id" binding:"-"`
+	SKU       string `json:"sku" binding:"required,max=255"`
+	// Options holds the value of every option of the product, by option name
+	Options map[string]string `json:"options" binding:"dive,keys,max=100,endkeys,max=100"`
+	// Price overrides the price of the product when set
+	Price *float64 `json:"price" binding:"omitempty,gt=0"`
+}
+
+// Matches reports whether the variant holds a value of each of options, and nothing else
+func (v Variant) Matches(options []ProductOption) bool {
+	if len(v.Options) != len(options) {
+		return false
+	}
+
+	for _, option := range options {
+		value, ok := v.Options[option.Name]
+		if !ok || !slices.Contains(option.Values, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OptionsKey identifies the combination of option values of the variant, whatever the
+// order of its options
+func (v Variant) OptionsKey() string {
+	options := v.Options
+	if options == nil {
+		options = make(map[string]string)
+	}
+
+	// Maps are encoded with their keys sorted
+	encoded, _ := json.Marshal(options)
+	sum := sha256.Sum256(encoded)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Combinations returns every combination of the values of options, varying the last option
+// first. Without options, the only combination is the empty one.
+func Combinations(options []ProductOption) []map[string]string {
+	combinations := []map[string]string{{}}
+	for _, option := range options {
+		next := make([]map[string]string, 0, len(combinations)*len(option.Values))
+		for _, combination := range combinations {
+			for _, value := range option.Values {
+				extended := make(map[string]string, len(combination)+1)
+				for name, v := range combination {
+					extended[name] = v
+				}
+				extended[option.Name] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+
+	return combinations
+}
+
+// CountCombinations returns the number of combinations of the values of options, without
+// building them. Counting stops once it exceeds MaxVariantCombinations.
+func CountCombinations(options []ProductOption) int {
+	count := 1
+	for _, option := range options {
+		count *= len(option.Values)
+		if count > MaxVariantCombinations {
+			break
+		}
+	}
+
+	return count
+}
+
+// SKUSegment turns text into a segment of a generated SKU: upper case letters and digits,
+// runs of other characters replaced by a dash
+func SKUSegment(text string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToUpper(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	return sb.String()
+}
+
+// GenerateSKU joins segments into the SKU of a generated variant, skipping the empty ones.
+// SKUs longer than MaxSKULength are cut short and end with a hash of the whole SKU instead,
+// which keeps SKUs sharing a long beginning apart.
+func GenerateSKU(segments ...string) string {
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment != "" {
+			parts = append(parts, segment)
+		}
+	}
+	sku := strings.Join(parts, "-")
+
+	runes := []rune(sku)
+	if len(runes) <= MaxSKULength {
+		return sku
+	}
+
+	sum := sha256.Sum256([]byte(sku))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:4]))
+
+	return strings.TrimRight(string(runes[:MaxSKULength-len(hash)-1]), "-") + "-" + hash
+}
@@ -0,0 +1,16 @@
+package models
+
+// DefaultWarehouseID is the warehouse stock moves in when no warehouse is given. It holds the
+// stock recorded before products were stocked per warehouse.
+const DefaultWarehouseID = "default"
+
+// Warehouse is a location products are stocked in and shipped from
+type Warehouse struct {
+	ID   string `json:"id" binding:"-"`
+	Name string `json:"name" binding:"required"`
+	// Region is a code made of dash-separated segments going from the largest area to the
+	// smallest, e.g. EU-DE-BER. Warehouses sharing leading segments are near each other.
+	Region string `json:"region"`
+	// Priority orders warehouses for allocation, the lowest first
+	Priority int `json:"priority"`
+}
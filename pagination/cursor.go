@@ -0,0 +1,133 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"simpler-products/models"
+	"strconv"
+	"strings"
+	"sync"
+
+	custom_errors "simpler-products/errors"
+)
+
+// Codec turns listing positions into opaque cursors and back. Cursors are signed, so
+// clients cannot forge positions, and bound to the filters and sort of their listing.
+type Codec struct {
+	key []byte
+}
+
+// payload is the content of a cursor, with short keys to keep cursors short in URLs
+type payload struct {
+	ID       string  `json:"i"`
+	Name     string  `json:"n"`
+	Price    float64 `json:"p"`
+	Backward bool    `json:"b,omitempty"`
+	// Query is the fingerprint of the filters and sort the cursor belongs to
+	Query string `json:"q"`
+}
+
+func NewCodec(key []byte) *Codec {
+	return &Codec{key: key}
+}
+
+var (
+	defaultCodec     *Codec
+	defaultCodecOnce sync.Once
+)
+
+// DefaultCodec returns the codec keyed by CURSOR_SECRET_KEY. When it is unset a random key
+// is used, and cursors are then only valid within the process that issued them.
+func DefaultCodec() *Codec {
+	defaultCodecOnce.Do(func() {
+		key := []byte(os.Getenv("CURSOR_SECRET_KEY"))
+		if len(key) == 0 {
+			key = make([]byte, 32)
+			rand.Read(key)
+		}
+		defaultCodec = NewCodec(key)
+	})
+
+	return defaultCodec
+}
+
+// Encode returns the cursor of keyset in the listing described by query
+func (c *Codec) Encode(query models.ProductQuery, keyset models.Keyset) string {
+	content, _ := json.Marshal(payload{
+		ID:       keyset.ID,
+		Name:     keyset.Name,
+		Price:    keyset.Price,
+		Backward: keyset.Backward,
+		Query:    fingerprint(query),
+	})
+
+	return base64.RawURLEncoding.EncodeToString(content) + "." + base64.RawURLEncoding.EncodeToString(c.sign(content))
+}
+
+// Decode returns the keyset of cursor, which must have been issued for the filters and sort of query
+func (c *Codec) Decode(cursor string, query models.ProductQuery) (*models.Keyset, error) {
+	encodedContent, encodedSignature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, custom_errors.ErrInvalidCursor
+	}
+
+	content, err := base64.RawURLEncoding.DecodeString(encodedContent)
+	if err != nil {
+		return nil, custom_errors.ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, c.sign(content)) {
+		return nil, custom_errors.ErrInvalidCursor
+	}
+
+	var p payload
+	if err := json.Unmarshal(content, &p); err != nil {
+		return nil, custom_errors.ErrInvalidCursor
+	}
+	if p.Query != fingerprint(query) {
+		return nil, custom_errors.ErrCursorQueryMismatch
+	}
+
+	return &models.Keyset{
+		ID:       p.ID,
+		Name:     p.Name,
+		Price:    p.Price,
+		Backward: p.Backward,
+	}, nil
+}
+
+func (c *Codec) sign(content []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+// fingerprint identifies the filters and sort of query, which a keyset is only meaningful for
+func fingerprint(query models.ProductQuery) string {
+	var b strings.Builder
+	b.WriteString(strconv.Quote(query.Filter.Name))
+	for _, price := range []*float64{query.Filter.PriceMin, query.Filter.PriceMax} {
+		b.WriteByte('|')
+		if price != nil {
+			b.WriteString(strconv.FormatFloat(*price, 'g', -1, 64))
+		}
+	}
+	// Only written when set, so that the cursors issued before categories existed stay valid
+	if query.Filter.Category != "" {
+		b.WriteString("|category=" + strconv.Quote(query.Filter.Category))
+	}
+	for _, field := range query.Sort {
+		b.WriteByte('|')
+		if field.Descending {
+			b.WriteByte('-')
+		}
+		b.WriteString(field.Field)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+	"simpler-products/models"
+)
+
+// CategoryRepository abstracts the storage of the category tree. Products are assigned to
+// categories through ProductRepository.
+type CategoryRepository interface {
+	Get(ctx context.Context, id string) (*models.Category, error)
+	// List returns every category, ordered by name
+	List(ctx context.Context) ([]models.Category, error)
+	Insert(ctx context.Context, id string, category *models.Category) (*models.Category, error)
+	// Update replaces the name and parent of the category
+	Update(ctx context.Context, id string, category *models.Category) (*models.Category, error)
+	// Delete deletes the category, failing with ErrCategoryNotEmpty when the schema still
+	// ties subcategories or products to it
+	Delete(ctx context.Context, id string) error
+	// InTransaction runs fn with a repository whose writes are all applied when fn succeeds,
+	// and none otherwise
+	InTransaction(ctx context.Context, fn func(repo CategoryRepository) error) error
+}
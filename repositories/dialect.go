@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect describes the SQL flavour spoken by a storage backend
+type Dialect struct {
+	Name string
+	// Queries are written with '?' placeholders and rebound per dialect
+	Rebind func(query string) string
+	// Returning reports whether INSERT/UPDATE ... RETURNING is supported
+	Returning bool
+}
+
+var (
+	MySQL = Dialect{
+		Name:   "mysql",
+		Rebind: func(query string) string { return query },
+	}
+	SQLite = Dialect{
+		Name:      "sqlite",
+		Rebind:    func(query string) string { return query },
+		Returning: true,
+	}
+	Postgres = Dialect{
+		Name:      "postgres",
+		Rebind:    rebindDollar,
+		Returning: true,
+	}
+)
+
+// rebindDollar converts '?' placeholders to PostgreSQL's positional $1, $2, ... syntax
+func rebindDollar(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// insertIgnore turns an INSERT statement into one skipping the rows whose key already exists
+func (d Dialect) insertIgnore(query string) string {
+	if d.Name == MySQL.Name {
+		return strings.Replace(query, "INSERT INTO", "INSERT IGNORE INTO", 1)
+	}
+
+	return query + " ON CONFLICT DO NOTHING"
+}
+
+// isUniqueViolation reports whether err was raised by a unique or primary key constraint,
+// whichever the backend
+func isUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	var pgErr *pgconn.PgError
+	var sqliteErr *sqlite.Error
+
+	switch {
+	case errors.As(err, &mysqlErr):
+		return mysqlErr.Number == 1062
+	case errors.As(err, &pgErr):
+		return pgErr.Code == "23505"
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+
+	return false
+}
+
+// isForeignKeyViolation reports whether err was raised by a foreign key constraint, whichever
+// the backend
+func isForeignKeyViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	var pgErr *pgconn.PgError
+	var sqliteErr *sqlite.Error
+
+	switch {
+	case errors.As(err, &mysqlErr):
+		return mysqlErr.Number == 1451 || mysqlErr.Number == 1452
+	case errors.As(err, &pgErr):
+		return pgErr.Code == "23503"
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	}
+
+	return false
+}
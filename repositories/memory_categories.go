@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"slices"
+	"sync"
+)
+
+// InMemoryCategoryRepository keeps categories in process memory, like InMemoryProductRepository
+type InMemoryCategoryRepository struct {
+	mu         sync.RWMutex
+	categories map[string]models.Category
+}
+
+func NewInMemoryCategoryRepository() *InMemoryCategoryRepository {
+	return &InMemoryCategoryRepository{
+		categories: make(map[string]models.Category),
+	}
+}
+
+func (r *InMemoryCategoryRepository) Get(ctx context.Context, id string) (*models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	category, ok := r.categories[id]
+	if !ok {
+		return nil, custom_errors.ErrCategoryNotFound
+	}
+
+	return &category, nil
+}
+
+func (r *InMemoryCategoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categories := make([]models.Category, 0, len(r.categories))
+	for _, category := range r.categories {
+		categories = append(categories, category)
+	}
+	slices.SortFunc(categories, func(a, b models.Category) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+
+	return categories, nil
+}
+
+func (r *InMemoryCategoryRepository) Insert(ctx context.Context, id string, category *models.Category) (*models.Category, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *category
+	stored.ID = id
+	r.categories[id] = stored
+
+	return &stored, nil
+}
+
+func (r *InMemoryCategoryRepository) Update(ctx context.Context, id string, category *models.Category) (*models.Category, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.categories[id]; !ok {
+		return nil, custom_errors.ErrCategoryNotFound
+	}
+
+	stored := *category
+	stored.ID = id
+	r.categories[id] = stored
+
+	return &stored, nil
+}
+
+func (r *InMemoryCategoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.categories[id]; !ok {
+		return custom_errors.ErrCategoryNotFound
+	}
+	delete(r.categories, id)
+
+	return nil
+}
+
+// InTransaction runs fn on a copy of the categories, which replaces them when fn succeeds.
+// Other operations wait for the transaction to end.
+func (r *InMemoryCategoryRepository) InTransaction(ctx context.Context, fn func(repo CategoryRepository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &InMemoryCategoryRepository{categories: maps.Clone(r.categories)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.categories = tx.categories
+
+	return nil
+}
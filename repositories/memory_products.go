@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"context"
+	"maps"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"slices"
+	"sync"
+)
+
+// InMemoryProductRepository keeps products in process memory.
+// It is meant for tests and local development without a database. Operations never block,
+// so the context arguments are not consulted.
+type InMemoryProductRepository struct {
+	mu       sync.RWMutex
+	products map[string]models.Product
+	// categories holds the categories each product is assigned to
+	categories map[string][]string
+}
+
+func NewInMemoryProductRepository() *InMemoryProductRepository {
+	return &InMemoryProductRepository{
+		products:   make(map[string]models.Product),
+		categories: make(map[string][]string),
+	}
+}
+
+func (r *InMemoryProductRepository) Get(ctx context.Context, id string) (*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, ok := r.products[id]
+	if !ok {
+		return nil, custom_errors.ErrProductNotFound
+	}
+
+	return &product, nil
+}
+
+func (r *InMemoryProductRepository) List(ctx context.Context, query models.ProductQuery) ([]models.Product, error) {
+	if err := checkSort(query.Sort); err != nil {
+		return nil, err
+	}
+
+	matching := r.matching(query.Filter)
+	slices.SortFunc(matching, func(a, b models.Product) int {
+		return compareProducts(a, b, query.Sort)
+	})
+
+	if query.Keyset == nil {
+		if query.Offset >= len(matching) {
+			return make([]models.Product, 0), nil
+		}
+
+		return matching[query.Offset:min(query.Offset+query.Limit, len(matching))], nil
+	}
+
+	// Position of the first product following the keyset
+	keyset := models.Product{ID: query.Keyset.ID, Name: query.Keyset.Name, Price: query.Keyset.Price}
+	position, found := slices.BinarySearchFunc(matching, keyset, func(a, b models.Product) int {
+		return compareProducts(a, b, query.Sort)
+	})
+
+	if query.Keyset.Backward {
+		return matching[max(0, position-query.Limit):position], nil
+	}
+
+	if found {
+		position++
+	}
+
+	return matching[position:min(position+query.Limit, len(matching))], nil
+}
+
+func (r *InMemoryProductRepository) Count(ctx context.Context, filter models.ProductFilter) (int, error) {
+	return len(r.matching(filter)), nil
+}
+
+func (r *InMemoryProductRepository) PriceFacet(ctx context.Context, filter models.ProductFilter, buckets models.PriceBuckets) (*models.PriceFacet, error) {
+	matching := r.matching(filter)
+	prices := make([]float64, 0, len(matching))
+	for _, product := range matching {
+		prices = append(prices, product.Price)
+	}
+
+	return models.PriceFacetOf(prices, buckets), nil
+}
+
+// matching returns a copy of the products matching filter, in no particular order
+func (r *InMemoryProductRepository) matching(filter models.ProductFilter) []models.Product {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]models.Product, 0, len(r.products))
+	for _, product := range r.products {
+		if matchesFilter(product, r.categories[product.ID], filter) {
+			products = append(products, product)
+		}
+	}
+
+	return products
+}
+
+func (r *InMemoryProductRepository) Insert(ctx context.Context, id string, product *models.Product) (*models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *product
+	stored.ID = id
+	stored.Version = 1
+	r.products[id] = stored
+
+	return &stored, nil
+}
+
+func (r *InMemoryProductRepository) Update(ctx context.Context, id string, product *models.Product) (*models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.current(id, product.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := *product
+	stored.ID = id
+	stored.Version = current.Version + 1
+	r.products[id] = stored
+
+	return &stored, nil
+}
+
+func (r *InMemoryProductRepository) Patch(ctx context.Context, id string, patch models.ProductPatch) (*models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, err := r.current(id, patch.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing to write, the product is left at its version
+	if patch.IsEmpty() {
+		return &product, nil
+	}
+
+	stored := patch.Apply(product)
+	stored.Version++
+	r.products[id] = stored
+
+	return &stored, nil
+}
+
+func (r *InMemoryProductRepository) Delete(ctx context.Context, id string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.current(id, version); err != nil {
+		return err
+	}
+
+	delete(r.products, id)
+	delete(r.categories, id)
+
+	return nil
+}
+
+func (r *InMemoryProductRepository) Categories(ctx context.Context, id string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categories := slices.Clone(r.categories[id])
+	slices.Sort(categories)
+
+	return categories, nil
+}
+
+func (r *InMemoryProductRepository) SetCategories(ctx context.Context, id string, categoryIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[id]; !ok {
+		return custom_errors.ErrProductNotFound
+	}
+
+	if len(categoryIDs) == 0 {
+		delete(r.categories, id)
+	} else {
+		r.categories[id] = slices.Clone(categoryIDs)
+	}
+
+	return nil
+}
+
+// current returns the stored product id, checking it is at the given version unless that is zero
+func (r *InMemoryProductRepository) current(id string, version int64) (models.Product, error) {
+	product, ok := r.products[id]
+	if !ok {
+		return models.Product{}, custom_errors.ErrProductNotFound
+	}
+	if version != 0 && product.Version != version {
+		return models.Product{}, custom_errors.ErrVersionMismatch
+	}
+
+	return product, nil
+}
+
+// InTransaction runs fn on a copy of the products, which replaces them when fn succeeds.
+// Other operations wait for the transaction to end.
+func (r *InMemoryProductRepository) InTransaction(ctx context.Context, fn func(repo ProductRepository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &InMemoryProductRepository{products: maps.Clone(r.products), categories: maps.Clone(r.categories)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.products = tx.products
+	r.categories = tx.categories
+
+	return nil
+}
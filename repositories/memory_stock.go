@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"slices"
+	"sync"
+)
+
+// InMemoryStockRepository keeps stock in process memory, for the products of Products and
+// their variants of Variants, in the warehouses of Warehouses
+type InMemoryStockRepository struct {
+	Products   ProductRepository
+	Warehouses WarehouseRepository
+	Variants   VariantRepository
+
+	mu sync.Mutex
+	// stock holds the stock of each product by variant and warehouse
+	stock  map[string]map[stockKey]models.WarehouseStock
+	ledger map[string][]models.StockMovement
+}
+
+// stockKey identifies the stock of a variant of a product, or of the product itself when
+// variantID is empty, in a warehouse
+type stockKey struct {
+	variantID   string
+	warehouseID string
+}
+
+func NewInMemoryStockRepository(products ProductRepository, warehouses WarehouseRepository, variants VariantRepository) *InMemoryStockRepository {
+	return &InMemoryStockRepository{
+		Products:   products,
+		Warehouses: warehouses,
+		Variants:   variants,
+		stock:      make(map[string]map[stockKey]models.WarehouseStock),
+		ledger:     make(map[string][]models.StockMovement),
+	}
+}
+
+func (r *InMemoryStockRepository) Get(ctx context.Context, productID string) (*models.Stock, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.current(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	warehouses := make([]models.WarehouseStock, 0, len(current))
+	for _, stock := range current {
+		warehouses = append(warehouses, stock)
+	}
+	slices.SortFunc(warehouses, func(a, b models.WarehouseStock) int {
+		return cmp.Or(cmp.Compare(a.VariantID, b.VariantID), cmp.Compare(a.WarehouseID, b.WarehouseID))
+	})
+
+	stock := models.NewStock(productID, warehouses)
+	return &stock, nil
+}
+
+func (r *InMemoryStockRepository) Move(ctx context.Context, productID string, movements []models.StockMovement) ([]models.StockMovement, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.current(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Movements are applied to a copy, kept only when they all succeed
+	next := make(map[stockKey]models.WarehouseStock, len(current)+len(movements))
+	for key, stock := range current {
+		next[key] = stock
+	}
+
+	applied := make([]models.StockMovement, 0, len(movements))
+	sequence := int64(len(r.ledger[productID]))
+	for _, movement := range movements {
+		if _, err := r.Warehouses.Get(ctx, movement.WarehouseID); err != nil {
+			return nil, err
+		}
+
+		key := stockKey{variantID: movement.VariantID, warehouseID: movement.WarehouseID}
+		onHand, reserved := movement.Deltas()
+		onHand += next[key].OnHand
+		reserved += next[key].Reserved
+		if reserved < 0 || onHand < reserved {
+			return nil, movementError(movement)
+		}
+		stock := models.NewWarehouseStock(movement.WarehouseID, onHand, reserved)
+		stock.VariantID = movement.VariantID
+		next[key] = stock
+
+		sequence++
+		movement.ProductID = productID
+		movement.Sequence = sequence
+		movement.OnHand = onHand
+		movement.Reserved = reserved
+		applied = append(applied, movement)
+	}
+
+	r.stock[productID] = next
+	r.ledger[productID] = append(r.ledger[productID], applied...)
+
+	return applied, nil
+}
+
+func (r *InMemoryStockRepository) Movements(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ledger := r.ledger[productID]
+	movements := make([]models.StockMovement, 0, limit)
+	for i := len(ledger) - 1 - offset; i >= 0 && len(movements) < limit; i-- {
+		movements = append(movements, ledger[i])
+	}
+
+	return movements, nil
+}
+
+func (r *InMemoryStockRepository) CountMovements(ctx context.Context, productID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.ledger[productID]), nil
+}
+
+func (r *InMemoryStockRepository) HoldsStock(ctx context.Context, warehouseID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for productID := range r.stock {
+		current, err := r.current(ctx, productID)
+		if errors.Is(err, custom_errors.ErrProductNotFound) {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		for key, stock := range current {
+			if key.warehouseID == warehouseID && (stock.OnHand > 0 || stock.Reserved > 0) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// current returns the stock of the product by variant and warehouse, dropping the stock left by
+// a deleted product or variant, or in a deleted warehouse
+func (r *InMemoryStockRepository) current(ctx context.Context, productID string) (map[stockKey]models.WarehouseStock, error) {
+	if _, err := r.Products.Get(ctx, productID); err != nil {
+		if errors.Is(err, custom_errors.ErrProductNotFound) {
+			delete(r.stock, productID)
+			delete(r.ledger, productID)
+		}
+		return nil, err
+	}
+
+	current := r.stock[productID]
+	for key := range current {
+		if _, err := r.Warehouses.Get(ctx, key.warehouseID); errors.Is(err, custom_errors.ErrWarehouseNotFound) {
+			delete(current, key)
+			continue
+		}
+		if key.variantID != "" {
+			if _, err := r.Variants.Get(ctx, productID, key.variantID); errors.Is(err, custom_errors.ErrVariantNotFound) {
+				delete(current, key)
+			}
+		}
+	}
+
+	return current, nil
+}
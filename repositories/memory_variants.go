@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"slices"
+	"sync"
+)
+
+// InMemoryVariantRepository keeps the options and variants of products in process memory
+type InMemoryVariantRepository struct {
+	mu       sync.RWMutex
+	options  map[string][]models.ProductOption
+	variants map[string]models.Variant
+}
+
+func NewInMemoryVariantRepository() *InMemoryVariantRepository {
+	return &InMemoryVariantRepository{
+		options:  make(map[string][]models.ProductOption),
+		variants: make(map[string]models.Variant),
+	}
+}
+
+func (r *InMemoryVariantRepository) Options(ctx context.Context, productID string) ([]models.ProductOption, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	options := make([]models.ProductOption, 0, len(r.options[productID]))
+	for _, option := range r.options[productID] {
+		options = append(options, models.ProductOption{Name: option.Name, Values: slices.Clone(option.Values)})
+	}
+
+	return options, nil
+}
+
+func (r *InMemoryVariantRepository) SetOptions(ctx context.Context, productID string, options []models.ProductOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, variant := range r.variants {
+		if variant.ProductID == productID && !variant.Matches(options) {
+			return custom_errors.ErrOptionsInUse
+		}
+	}
+
+	stored := make([]models.ProductOption, 0, len(options))
+	for _, option := range options {
+		stored = append(stored, models.ProductOption{Name: option.Name, Values: slices.Clone(option.Values)})
+	}
+	r.options[productID] = stored
+
+	return nil
+}
+
+func (r *InMemoryVariantRepository) List(ctx context.Context, productID string) ([]models.Variant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	variants := make([]models.Variant, 0)
+	for _, variant := range r.variants {
+		if variant.ProductID == productID {
+			variants = append(variants, cloneVariant(variant))
+		}
+	}
+	slices.SortFunc(variants, func(a, b models.Variant) int {
+		return cmp.Compare(a.SKU, b.SKU)
+	})
+
+	return variants, nil
+}
+
+func (r *InMemoryVariantRepository) Get(ctx context.Context, productID, id string) (*models.Variant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	variant, ok := r.variants[id]
+	if !ok || variant.ProductID != productID {
+		return nil, custom_errors.ErrVariantNotFound
+	}
+
+	variant = cloneVariant(variant)
+	return &variant, nil
+}
+
+func (r *InMemoryVariantRepository) Insert(ctx context.Context, variants []models.Variant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Variants are checked against the stored ones and each other before any is stored
+	inserted := make(map[string]models.Variant, len(r.variants)+len(variants))
+	for id, variant := range r.variants {
+		inserted[id] = variant
+	}
+	for _, variant := range variants {
+		if err := r.conflict(inserted, variant); err != nil {
+			return err
+		}
+		inserted[variant.ID] = cloneVariant(variant)
+	}
+	r.variants = inserted
+
+	return nil
+}
+
+func (r *InMemoryVariantRepository) Update(ctx context.Context, variant models.Variant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.variants[variant.ID]; !ok || stored.ProductID != variant.ProductID {
+		return custom_errors.ErrVariantNotFound
+	}
+	if err := r.conflict(r.variants, variant); err != nil {
+		return err
+	}
+	r.variants[variant.ID] = cloneVariant(variant)
+
+	return nil
+}
+
+func (r *InMemoryVariantRepository) Delete(ctx context.Context, productID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if variant, ok := r.variants[id]; !ok || variant.ProductID != productID {
+		return custom_errors.ErrVariantNotFound
+	}
+	delete(r.variants, id)
+
+	return nil
+}
+
+// conflict returns the error of storing variant along with variants, if they clash
+func (r *InMemoryVariantRepository) conflict(variants map[string]models.Variant, variant models.Variant) error {
+	key := variant.OptionsKey()
+	for id, other := range variants {
+		if id == variant.ID {
+			continue
+		}
+		if other.SKU == variant.SKU {
+			return custom_errors.ErrDuplicateSKU
+		}
+		if other.ProductID == variant.ProductID && other.OptionsKey() == key {
+			return custom_errors.ErrDuplicateVariant
+		}
+	}
+
+	return nil
+}
+
+func cloneVariant(variant models.Variant) models.Variant {
+	options := make(map[string]string, len(variant.Options))
+	for name, value := range variant.Options {
+		options[name] = value
+	}
+	variant.Options = options
+
+	if variant.Price != nil {
+		price := *variant.Price
+		variant.Price = &price
+	}
+
+	return variant
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"slices"
+	"sync"
+)
+
+// InMemoryWarehouseRepository keeps warehouses in process memory, starting with the default
+// warehouse like the SQL schema
+type InMemoryWarehouseRepository struct {
+	mu         sync.RWMutex
+	warehouses map[string]models.Warehouse
+}
+
+func NewInMemoryWarehouseRepository() *InMemoryWarehouseRepository {
+	return &InMemoryWarehouseRepository{
+		warehouses: map[string]models.Warehouse{
+			models.DefaultWarehouseID: {ID: models.DefaultWarehouseID, Name: "Default"},
+		},
+	}
+}
+
+func (r *InMemoryWarehouseRepository) Get(ctx context.Context, id string) (*models.Warehouse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	warehouse, ok := r.warehouses[id]
+	if !ok {
+		return nil, custom_errors.ErrWarehouseNotFound
+	}
+
+	return &warehouse, nil
+}
+
+func (r *InMemoryWarehouseRepository) List(ctx context.Context) ([]models.Warehouse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	warehouses := make([]models.Warehouse, 0, len(r.warehouses))
+	for _, warehouse := range r.warehouses {
+		warehouses = append(warehouses, warehouse)
+	}
+	slices.SortFunc(warehouses, func(a, b models.Warehouse) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+
+	return warehouses, nil
+}
+
+func (r *InMemoryWarehouseRepository) Insert(ctx context.Context, id string, warehouse *models.Warehouse) (*models.Warehouse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *warehouse
+	stored.ID = id
+	r.warehouses[id] = stored
+
+	return &stored, nil
+}
+
+func (r *InMemoryWarehouseRepository) Update(ctx context.Context, id string, warehouse *models.Warehouse) (*models.Warehouse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.warehouses[id]; !ok {
+		return nil, custom_errors.ErrWarehouseNotFound
+	}
+
+	stored := *warehouse
+	stored.ID = id
+	r.warehouses[id] = stored
+
+	return &stored, nil
+}
+
+func (r *InMemoryWarehouseRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.warehouses[id]; !ok {
+		return custom_errors.ErrWarehouseNotFound
+	}
+	delete(r.warehouses, id)
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"cmp"
+	"fmt"
+	"simpler-products/models"
+	"slices"
+	"strings"
+)
+
+// productSortColumns maps the sortable fields to their columns. Only these are ever
+// interpolated into ORDER BY clauses, every other value is passed as a parameter.
+// Names are ordered regardless of case, whatever the collation of the backend, and so by
+// compareProducts, so that pages and cursors match across backends.
+var productSortColumns = map[string]string{
+	"id":    "id",
+	"name":  "LOWER(name)",
+	"price": "price",
+}
+
+// likeEscape is accepted as LIKE escape character by MySQL, PostgreSQL and SQLite alike,
+// unlike the backslash whose quoting differs between them
+const likeEscape = "!"
+
+var likeEscaper = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+// whereClause returns the WHERE clause selecting the products matching filter and, when
+// set, lying beyond keyset in the given order, with its arguments
+func whereClause(filter models.ProductFilter, sort []models.SortField, keyset *models.Keyset) (string, []any) {
+	conditions := make([]string, 0, 4)
+	args := make([]any, 0, 3)
+
+	if filter.Name != "" {
+		conditions = append(conditions, "LOWER(name) LIKE ? ESCAPE '"+likeEscape+"'")
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(filter.Name))+"%")
+	}
+	if filter.PriceMin != nil {
+		conditions = append(conditions, "price >= ?")
+		args = append(args, *filter.PriceMin)
+	}
+	if filter.PriceMax != nil {
+		conditions = append(conditions, "price <= ?")
+		args = append(args, *filter.PriceMax)
+	}
+	if len(filter.CategoryIDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.CategoryIDs)), ", ")
+		conditions = append(conditions, "id IN (SELECT product_id FROM ProductCategories WHERE category_id IN ("+placeholders+"))")
+		for _, id := range filter.CategoryIDs {
+			args = append(args, id)
+		}
+	}
+	if keyset != nil {
+		condition, keysetArgs := keysetCondition(sort, *keyset)
+		conditions = append(conditions, condition)
+		args = append(args, keysetArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// keysetCondition selects the rows after the keyset in the order of sort, or before it
+// when going backward. Sort orders may be mixed, so instead of a row value comparison
+// it expands to (a > ?) OR (a = ? AND b < ?) OR (a = ? AND b = ? AND id > ?).
+func keysetCondition(sort []models.SortField, keyset models.Keyset) (string, []any) {
+	sort = totalOrder(sort)
+	alternatives := make([]string, 0, len(sort))
+	args := make([]any, 0)
+
+	for i, field := range sort {
+		terms := make([]string, 0, i+1)
+		for _, previous := range sort[:i] {
+			terms = append(terms, productSortColumns[previous.Field]+" = ?")
+			args = append(args, keysetValue(keyset, previous.Field))
+		}
+
+		operator := ">"
+		if field.Descending != keyset.Backward {
+			operator = "<"
+		}
+		terms = append(terms, productSortColumns[field.Field]+" "+operator+" ?")
+		args = append(args, keysetValue(keyset, field.Field))
+
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+func keysetValue(keyset models.Keyset, field string) any {
+	switch field {
+	case "name":
+		return strings.ToLower(keyset.Name)
+	case "price":
+		return keyset.Price
+	default:
+		return keyset.ID
+	}
+}
+
+// orderClause returns the ORDER BY clause for sort, reversed when going backward
+func orderClause(sort []models.SortField, backward bool) (string, error) {
+	if err := checkSort(sort); err != nil {
+		return "", err
+	}
+
+	sort = totalOrder(sort)
+	terms := make([]string, 0, len(sort))
+	for _, field := range sort {
+		direction := "ASC"
+		if field.Descending != backward {
+			direction = "DESC"
+		}
+		terms = append(terms, productSortColumns[field.Field]+" "+direction)
+	}
+
+	return " ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+// totalOrder appends id to sort unless it is already part of it, so that no two
+// products compare equal
+func totalOrder(sort []models.SortField) []models.SortField {
+	for _, field := range sort {
+		if field.Field == "id" {
+			return sort
+		}
+	}
+
+	return append(sort[:len(sort):len(sort)], models.SortField{Field: "id"})
+}
+
+// checkSort reports the first sort field that is not sortable
+func checkSort(sort []models.SortField) error {
+	for _, field := range sort {
+		if _, ok := productSortColumns[field.Field]; !ok {
+			return fmt.Errorf("unsupported sort field: %s", field.Field)
+		}
+	}
+
+	return nil
+}
+
+// matchesFilter is the in-memory counterpart of whereClause, without the keyset. categories
+// are the categories the product is assigned to.
+func matchesFilter(product models.Product, categories []string, filter models.ProductFilter) bool {
+	if filter.Name != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	if filter.PriceMin != nil && product.Price < *filter.PriceMin {
+		return false
+	}
+	if filter.PriceMax != nil && product.Price > *filter.PriceMax {
+		return false
+	}
+	if len(filter.CategoryIDs) > 0 && !slices.ContainsFunc(categories, func(id string) bool {
+		return slices.Contains(filter.CategoryIDs, id)
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// compareProducts is the in-memory counterpart of orderClause
+func compareProducts(a, b models.Product, sort []models.SortField) int {
+	for _, field := range totalOrder(sort) {
+		var c int
+		switch field.Field {
+		case "id":
+			c = cmp.Compare(a.ID, b.ID)
+		case "name":
+			c = cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case "price":
+			c = cmp.Compare(a.Price, b.Price)
+		}
+
+		if field.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"simpler-products/models"
+)
+
+// ProductRepository abstracts the storage backend used by the products service
+type ProductRepository interface {
+	Get(ctx context.Context, id string) (*models.Product, error)
+	// List returns a page of the products matching the query, in the order it requests
+	List(ctx context.Context, query models.ProductQuery) ([]models.Product, error)
+	// Count returns the number of products matching filter
+	Count(ctx context.Context, filter models.ProductFilter) (int, error)
+	// PriceFacet returns the price statistics and buckets of the products matching filter
+	PriceFacet(ctx context.Context, filter models.ProductFilter, buckets models.PriceBuckets) (*models.PriceFacet, error)
+	// Insert stores a new product at version 1
+	Insert(ctx context.Context, id string, product *models.Product) (*models.Product, error)
+	// Update replaces the fields of the product and increments its version. When product.Version
+	// is set and the stored product is at another version, it fails with ErrVersionMismatch.
+	Update(ctx context.Context, id string, product *models.Product) (*models.Product, error)
+	// Patch updates only the fields set in patch, with the same version check as Update
+	Patch(ctx context.Context, id string, patch models.ProductPatch) (*models.Product, error)
+	// Delete removes the product, with the same version check as Update unless version is zero
+	Delete(ctx context.Context, id string, version int64) error
+	// Categories returns the IDs of the categories the product is assigned to
+	Categories(ctx context.Context, id string) ([]string, error)
+	// SetCategories replaces the categories the product is assigned to
+	SetCategories(ctx context.Context, id string, categoryIDs []string) error
+	// InTransaction runs fn with a repository whose writes are all applied when fn succeeds,
+	// and none otherwise
+	InTransaction(ctx context.Context, fn func(repo ProductRepository) error) error
+}
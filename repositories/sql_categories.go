@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+)
+
+// SQLCategoryRepository stores categories as an adjacency list, each row referencing its parent
+type SQLCategoryRepository struct {
+	DB      *sql.DB
+	Dialect Dialect
+	// tx is the transaction the queries run in, when the repository is given to InTransaction callbacks
+	tx *sql.Tx
+}
+
+const categoryColumns = "id, name, parent_id"
+
+func NewSQLCategoryRepository(db *sql.DB, dialect Dialect) *SQLCategoryRepository {
+	return &SQLCategoryRepository{
+		DB:      db,
+		Dialect: dialect,
+	}
+}
+
+// conn returns where the queries of the repository run
+func (r *SQLCategoryRepository) conn() queryer {
+	if r.tx != nil {
+		return r.tx
+	}
+
+	return r.DB
+}
+
+func (r *SQLCategoryRepository) Get(ctx context.Context, id string) (*models.Category, error) {
+	var category models.Category
+	err := r.conn().QueryRowContext(ctx, r.Dialect.Rebind("SELECT "+categoryColumns+" FROM Categories WHERE id = ?"), id).Scan(&category.ID, &category.Name, &category.ParentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, custom_errors.ErrCategoryNotFound
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (r *SQLCategoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+categoryColumns+" FROM Categories ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]models.Category, 0)
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.ID, &category.Name, &category.ParentID); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *SQLCategoryRepository) Insert(ctx context.Context, id string, category *models.Category) (*models.Category, error) {
+	_, err := r.conn().ExecContext(ctx, r.Dialect.Rebind("INSERT INTO Categories (id, name, parent_id) VALUES (?, ?, ?)"), id, category.Name, category.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	created := *category
+	created.ID = id
+
+	return &created, nil
+}
+
+func (r *SQLCategoryRepository) Update(ctx context.Context, id string, category *models.Category) (*models.Category, error) {
+	result, err := r.conn().ExecContext(ctx, r.Dialect.Rebind("UPDATE Categories SET name = ?, parent_id = ? WHERE id = ?"), category.Name, category.ParentID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// MySQL does not count the rows left unchanged, tell them apart from missing ones
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return r.Get(ctx, id)
+	}
+
+	updated := *category
+	updated.ID = id
+
+	return &updated, nil
+}
+
+func (r *SQLCategoryRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.conn().ExecContext(ctx, r.Dialect.Rebind("DELETE FROM Categories WHERE id = ?"), id)
+	if isForeignKeyViolation(err) {
+		// A subcategory or product was added to the category meanwhile
+		return custom_errors.ErrCategoryNotEmpty
+	} else if err != nil {
+		return err
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return custom_errors.ErrCategoryNotFound
+	}
+
+	return nil
+}
+
+// InTransaction runs fn with a repository whose queries run in a transaction, committed
+// when fn succeeds and rolled back otherwise
+func (r *SQLCategoryRepository) InTransaction(ctx context.Context, fn func(repo CategoryRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&SQLCategoryRepository{DB: r.DB, Dialect: r.Dialect, tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
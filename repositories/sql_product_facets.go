@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"simpler-products/models"
+	"strings"
+)
+
+// PriceFacet aggregates prices in the database. Quantile edges are read one at a time,
+// at their position in the price order, which every supported database can do.
+func (r *SQLProductRepository) PriceFacet(ctx context.Context, filter models.ProductFilter, buckets models.PriceBuckets) (*models.PriceFacet, error) {
+	where, args := whereClause(filter, nil, nil)
+
+	facet := &models.PriceFacet{}
+	var min, max, avg sql.NullFloat64
+	err := r.conn().QueryRowContext(ctx, r.Dialect.Rebind("SELECT COUNT(*), MIN(price), MAX(price), AVG(price) FROM Products"+where), args...).
+		Scan(&facet.Count, &min, &max, &avg)
+	if err != nil {
+		return nil, err
+	}
+	if facet.Count > 0 {
+		facet.SetStats(min.Float64, max.Float64, avg.Float64)
+	}
+
+	edges := buckets.Edges
+	if buckets.Quantiles > 0 {
+		if facet.Count == 0 {
+			facet.Buckets = make([]models.PriceBucket, 0)
+			return facet, nil
+		}
+
+		quantiles := make([]float64, 0, buckets.Quantiles)
+		for _, position := range buckets.QuantilePositions(facet.Count) {
+			var price float64
+			query := "SELECT price FROM Products" + where + " ORDER BY price LIMIT 1 OFFSET ?"
+			if err := r.conn().QueryRowContext(ctx, r.Dialect.Rebind(query), append(args, position)...).Scan(&price); err != nil {
+				return nil, err
+			}
+			quantiles = append(quantiles, price)
+		}
+		edges = models.QuantileEdges(min.Float64, quantiles)
+	}
+
+	counts, err := r.bucketCounts(ctx, where, args, edges)
+	if err != nil {
+		return nil, err
+	}
+	facet.Buckets = models.NewPriceBuckets(edges, counts)
+
+	return facet, nil
+}
+
+// bucketCounts counts the products matching where in each bucket delimited by edges
+func (r *SQLProductRepository) bucketCounts(ctx context.Context, where string, args []any, edges []float64) ([]int, error) {
+	columns := make([]string, 0, len(edges)+1)
+	bucketArgs := make([]any, 0, 2*len(edges))
+	for i := 0; i <= len(edges); i++ {
+		switch {
+		case len(edges) == 0:
+			columns = append(columns, "COUNT(*)")
+		case i == 0:
+			columns = append(columns, "COUNT(CASE WHEN price < ? THEN 1 END)")
+			bucketArgs = append(bucketArgs, edges[i])
+		case i == len(edges):
+			columns = append(columns, "COUNT(CASE WHEN price >= ? THEN 1 END)")
+			bucketArgs = append(bucketArgs, edges[i-1])
+		default:
+			columns = append(columns, "COUNT(CASE WHEN price >= ? AND price < ? THEN 1 END)")
+			bucketArgs = append(bucketArgs, edges[i-1], edges[i])
+		}
+	}
+
+	counts := make([]int, len(columns))
+	dest := make([]any, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM Products" + where
+	if err := r.conn().QueryRowContext(ctx, r.Dialect.Rebind(query), append(bucketArgs, args...)...).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
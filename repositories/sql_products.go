@@ -0,0 +1,304 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"slices"
+	"strings"
+)
+
+// SQLProductRepository stores products in a relational database through database/sql
+type SQLProductRepository struct {
+	DB      *sql.DB
+	Dialect Dialect
+	// tx is the transaction the queries run in, when the repository is given to InTransaction callbacks
+	tx *sql.Tx
+}
+
+// queryer runs queries on the database, or within a transaction
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const productColumns = "id, name, description, price, version"
+
+func NewSQLProductRepository(db *sql.DB, dialect Dialect) *SQLProductRepository {
+	return &SQLProductRepository{
+		DB:      db,
+		Dialect: dialect,
+	}
+}
+
+// conn returns where the queries of the repository run
+func (r *SQLProductRepository) conn() queryer {
+	if r.tx != nil {
+		return r.tx
+	}
+
+	return r.DB
+}
+
+func (r *SQLProductRepository) Get(ctx context.Context, id string) (*models.Product, error) {
+	return r.queryProduct(ctx, "SELECT "+productColumns+" FROM Products WHERE id = ?", id)
+}
+
+// queryProduct runs a query expected to return a single product row
+func (r *SQLProductRepository) queryProduct(ctx context.Context, query string, args ...any) (*models.Product, error) {
+	var product models.Product
+	err := r.conn().QueryRowContext(ctx, r.Dialect.Rebind(query), args...).Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.Version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, custom_errors.ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+func (r *SQLProductRepository) List(ctx context.Context, query models.ProductQuery) ([]models.Product, error) {
+	backward := query.Keyset != nil && query.Keyset.Backward
+	where, args := whereClause(query.Filter, query.Sort, query.Keyset)
+	order, err := orderClause(query.Sort, backward)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := query.Offset
+	if query.Keyset != nil {
+		offset = 0
+	}
+
+	args = append(args, query.Limit, offset)
+	products := make([]models.Product, 0)
+	err = r.queryProducts(ctx, "SELECT "+productColumns+" FROM Products"+where+order+" LIMIT ? OFFSET ?", args, func(product models.Product) error {
+		products = append(products, product)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Going backward the rows closest to the keyset come first, restore the requested order
+	if backward {
+		slices.Reverse(products)
+	}
+
+	return products, nil
+}
+
+// queryProducts runs a query returning product rows and calls fn with each of them
+func (r *SQLProductRepository) queryProducts(ctx context.Context, query string, args []any, fn func(product models.Product) error) error {
+	rows, err := r.conn().QueryContext(ctx, r.Dialect.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product models.Product
+		if err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.Version); err != nil {
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *SQLProductRepository) Count(ctx context.Context, filter models.ProductFilter) (int, error) {
+	where, args := whereClause(filter, nil, nil)
+
+	var totalCount int
+	err := r.conn().QueryRowContext(ctx, r.Dialect.Rebind("SELECT COUNT(*) FROM Products"+where), args...).Scan(&totalCount)
+	if err != nil {
+		return 0, err
+	}
+
+	return totalCount, nil
+}
+
+func (r *SQLProductRepository) Insert(ctx context.Context, id string, product *models.Product) (*models.Product, error) {
+	query := "INSERT INTO Products (id, name, description, price) VALUES (?, ?, ?, ?)"
+	args := []any{id, product.Name, product.Description, product.Price}
+
+	if r.Dialect.Returning {
+		return r.queryProduct(ctx, query+" RETURNING "+productColumns, args...)
+	}
+
+	if _, err := r.conn().ExecContext(ctx, r.Dialect.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	created := *product
+	created.ID = id
+	created.Version = 1
+
+	return &created, nil
+}
+
+func (r *SQLProductRepository) Update(ctx context.Context, id string, product *models.Product) (*models.Product, error) {
+	return r.update(ctx, id, product.Version, []string{"name = ?", "description = ?", "price = ?"},
+		[]any{product.Name, product.Description, product.Price})
+}
+
+func (r *SQLProductRepository) Patch(ctx context.Context, id string, patch models.ProductPatch) (*models.Product, error) {
+	columns := make([]string, 0, 3)
+	args := make([]any, 0, 5)
+	if patch.Name != nil {
+		columns = append(columns, "name = ?")
+		args = append(args, *patch.Name)
+	}
+	if patch.Description != nil {
+		columns = append(columns, "description = ?")
+		args = append(args, *patch.Description)
+	}
+	if patch.Price != nil {
+		columns = append(columns, "price = ?")
+		args = append(args, *patch.Price)
+	}
+
+	// Nothing to write, the product is left at its version
+	if len(columns) == 0 {
+		product, err := r.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if patch.Version != 0 && product.Version != patch.Version {
+			return nil, custom_errors.ErrVersionMismatch
+		}
+		return product, nil
+	}
+
+	return r.update(ctx, id, patch.Version, columns, args)
+}
+
+// update sets columns of the product id and increments its version, only when it is at the
+// given version unless that is zero, and returns the updated row
+func (r *SQLProductRepository) update(ctx context.Context, id string, version int64, columns []string, args []any) (*models.Product, error) {
+	query := "UPDATE Products SET " + strings.Join(columns, ", ") + ", version = version + 1 WHERE id = ?"
+	args = append(args, id)
+	if version != 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+
+	if r.Dialect.Returning {
+		product, err := r.queryProduct(ctx, query+" RETURNING "+productColumns, args...)
+		if version != 0 && errors.Is(err, custom_errors.ErrProductNotFound) {
+			return nil, r.versionConflict(ctx, id)
+		}
+		return product, err
+	}
+
+	result, err := r.conn().ExecContext(ctx, r.Dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 {
+		if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+			return nil, r.versionConflict(ctx, id)
+		}
+	}
+
+	// Fetch the updated row
+	return r.Get(ctx, id)
+}
+
+// Delete removes the product id, only when it is at the given version unless that is zero
+func (r *SQLProductRepository) Delete(ctx context.Context, id string, version int64) error {
+	query := "DELETE FROM Products WHERE id = ?"
+	args := []any{id}
+	if version != 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+
+	result, err := r.conn().ExecContext(ctx, r.Dialect.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	if version != 0 {
+		if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+			return r.versionConflict(ctx, id)
+		}
+	}
+
+	return nil
+}
+
+// versionConflict tells why a write guarded by a version matched no row, either the
+// product is gone or it is at another version
+func (r *SQLProductRepository) versionConflict(ctx context.Context, id string) error {
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+
+	return custom_errors.ErrVersionMismatch
+}
+
+func (r *SQLProductRepository) Categories(ctx context.Context, id string) ([]string, error) {
+	rows, err := r.conn().QueryContext(ctx, r.Dialect.Rebind("SELECT category_id FROM ProductCategories WHERE product_id = ? ORDER BY category_id"), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]string, 0)
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *SQLProductRepository) SetCategories(ctx context.Context, id string, categoryIDs []string) error {
+	return r.InTransaction(ctx, func(repo ProductRepository) error {
+		tx := repo.(*SQLProductRepository)
+		if _, err := tx.Get(ctx, id); err != nil {
+			return err
+		}
+
+		if _, err := tx.conn().ExecContext(ctx, tx.Dialect.Rebind("DELETE FROM ProductCategories WHERE product_id = ?"), id); err != nil {
+			return err
+		}
+		for _, categoryID := range categoryIDs {
+			if _, err := tx.conn().ExecContext(ctx, tx.Dialect.Rebind("INSERT INTO ProductCategories (product_id, category_id) VALUES (?, ?)"), id, categoryID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// InTransaction runs fn with a repository whose queries run in a transaction, committed
+// when fn succeeds and rolled back otherwise
+func (r *SQLProductRepository) InTransaction(ctx context.Context, fn func(repo ProductRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&SQLProductRepository{DB: r.DB, Dialect: r.Dialect, tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"time"
+)
+
+// movementTimeLayout is the layout of the times movements are recorded at. Times are kept as
+// fixed-width UTC text, read back the same by every driver and ordered like the times.
+const movementTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+const movementColumns = "product_id, variant_id, warehouse_id, sequence_number, type, quantity, transfer_id, on_hand, reserved, reason, created_at"
+
+// SQLStockRepository stores stock in a relational database, in total in Stock and per variant
+// and warehouse in WarehouseStock. Moves lock the Stock row of the product first, which serialises them, and
+// apply each movement with a conditional update.
+type SQLStockRepository struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+func NewSQLStockRepository(db *sql.DB, dialect Dialect) *SQLStockRepository {
+	return &SQLStockRepository{
+		DB:      db,
+		Dialect: dialect,
+	}
+}
+
+func (r *SQLStockRepository) Get(ctx context.Context, productID string) (*models.Stock, error) {
+	rows, err := r.DB.QueryContext(ctx, r.Dialect.Rebind("SELECT variant_id, warehouse_id, on_hand, reserved FROM WarehouseStock WHERE product_id = ? ORDER BY variant_id, warehouse_id"), productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := make([]models.WarehouseStock, 0)
+	for rows.Next() {
+		var variantID, warehouseID string
+		var onHand, reserved int64
+		if err := rows.Scan(&variantID, &warehouseID, &onHand, &reserved); err != nil {
+			return nil, err
+		}
+		warehouse := models.NewWarehouseStock(warehouseID, onHand, reserved)
+		warehouse.VariantID = variantID
+		warehouses = append(warehouses, warehouse)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The product has not moved yet, if it exists
+	if len(warehouses) == 0 {
+		if err := productExists(ctx, r.DB, r.Dialect, productID); err != nil {
+			return nil, err
+		}
+	}
+
+	stock := models.NewStock(productID, warehouses)
+	return &stock, nil
+}
+
+func (r *SQLStockRepository) Move(ctx context.Context, productID string, movements []models.StockMovement) ([]models.StockMovement, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Every move locks the Stock row of the product, created by its first move
+	updated, err := r.updateCreating(ctx, tx,
+		"UPDATE Stock SET movement_count = movement_count + ? WHERE product_id = ?", []any{len(movements), productID},
+		"INSERT INTO Stock (product_id) SELECT id FROM Products WHERE id = ?", []any{productID})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, custom_errors.ErrProductNotFound
+	}
+
+	var sequence int64
+	if err := tx.QueryRowContext(ctx, r.Dialect.Rebind("SELECT movement_count FROM Stock WHERE product_id = ?"), productID).Scan(&sequence); err != nil {
+		return nil, err
+	}
+	sequence -= int64(len(movements))
+
+	applied := make([]models.StockMovement, 0, len(movements))
+	var totalOnHand, totalReserved int64
+	for _, movement := range movements {
+		movement.ProductID = productID
+		if err := r.apply(ctx, tx, &movement); err != nil {
+			return nil, err
+		}
+
+		onHand, reserved := movement.Deltas()
+		totalOnHand += onHand
+		totalReserved += reserved
+
+		sequence++
+		movement.Sequence = sequence
+		_, err = tx.ExecContext(ctx, r.Dialect.Rebind("INSERT INTO StockMovements ("+movementColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			movement.ProductID, movement.VariantID, movement.WarehouseID, movement.Sequence, movement.Type, movement.Quantity, movement.TransferID,
+			movement.OnHand, movement.Reserved, movement.Reason, movement.CreatedAt.UTC().Format(movementTimeLayout))
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, movement)
+	}
+
+	_, err = tx.ExecContext(ctx, r.Dialect.Rebind("UPDATE Stock SET on_hand = on_hand + ?, reserved = reserved + ? WHERE product_id = ?"),
+		totalOnHand, totalReserved, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// apply applies movement to the stock of its variant in its warehouse, and sets the quantities
+// it leaves
+func (r *SQLStockRepository) apply(ctx context.Context, tx *sql.Tx, movement *models.StockMovement) error {
+	// The WarehouseStock row is created by the first movement of the variant in the warehouse
+	onHand, reserved := movement.Deltas()
+	updated, err := r.updateCreating(ctx, tx,
+		"UPDATE WarehouseStock SET on_hand = on_hand + ?, reserved = reserved + ? "+
+			"WHERE product_id = ? AND variant_id = ? AND warehouse_id = ? AND on_hand + ? >= reserved + ? AND reserved + ? >= 0",
+		[]any{onHand, reserved, movement.ProductID, movement.VariantID, movement.WarehouseID, onHand, reserved, reserved},
+		"INSERT INTO WarehouseStock (product_id, variant_id, warehouse_id) SELECT p.id, ?, w.id FROM Products p, Warehouses w WHERE p.id = ? AND w.id = ?",
+		[]any{movement.VariantID, movement.ProductID, movement.WarehouseID})
+	if err != nil {
+		return err
+	}
+	if !updated {
+		var exists int
+		err := tx.QueryRowContext(ctx, r.Dialect.Rebind("SELECT 1 FROM Warehouses WHERE id = ?"), movement.WarehouseID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return custom_errors.ErrWarehouseNotFound
+		} else if err != nil {
+			return err
+		}
+		return movementError(*movement)
+	}
+
+	return tx.QueryRowContext(ctx, r.Dialect.Rebind("SELECT on_hand, reserved FROM WarehouseStock WHERE product_id = ? AND variant_id = ? AND warehouse_id = ?"),
+		movement.ProductID, movement.VariantID, movement.WarehouseID).Scan(&movement.OnHand, &movement.Reserved)
+}
+
+func (r *SQLStockRepository) Movements(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, error) {
+	rows, err := r.DB.QueryContext(ctx, r.Dialect.Rebind("SELECT "+movementColumns+" FROM StockMovements WHERE product_id = ? ORDER BY sequence_number DESC LIMIT ? OFFSET ?"), productID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := make([]models.StockMovement, 0)
+	for rows.Next() {
+		var movement models.StockMovement
+		var createdAt string
+		if err := rows.Scan(&movement.ProductID, &movement.VariantID, &movement.WarehouseID, &movement.Sequence, &movement.Type, &movement.Quantity, &movement.TransferID,
+			&movement.OnHand, &movement.Reserved, &movement.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		if movement.CreatedAt, err = time.Parse(movementTimeLayout, createdAt); err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+
+	return movements, rows.Err()
+}
+
+func (r *SQLStockRepository) CountMovements(ctx context.Context, productID string) (int, error) {
+	var count int
+	err := r.DB.QueryRowContext(ctx, r.Dialect.Rebind("SELECT COUNT(*) FROM StockMovements WHERE product_id = ?"), productID).Scan(&count)
+
+	return count, err
+}
+
+func (r *SQLStockRepository) HoldsStock(ctx context.Context, warehouseID string) (bool, error) {
+	var holds int
+	err := r.DB.QueryRowContext(ctx, r.Dialect.Rebind("SELECT 1 FROM WarehouseStock WHERE warehouse_id = ? AND (on_hand > 0 OR reserved > 0) LIMIT 1"), warehouseID).Scan(&holds)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// updateCreating runs update and reports whether it updated a row. When it does not, the row
+// may be missing, so it is created with insert, skipped when it exists, and update is retried.
+// Rows are created this way rather than beforehand, as inserting a row that exists would lock
+// it for reading on MySQL, and concurrent updates of the row would then deadlock.
+func (r *SQLStockRepository) updateCreating(ctx context.Context, tx *sql.Tx, update string, updateArgs []any, insert string, insertArgs []any) (bool, error) {
+	for created := false; ; created = true {
+		result, err := tx.ExecContext(ctx, r.Dialect.Rebind(update), updateArgs...)
+		if err != nil {
+			return false, err
+		}
+		if updated, err := result.RowsAffected(); err != nil || updated > 0 || created {
+			return updated > 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, r.Dialect.Rebind(r.Dialect.insertIgnore(insert)), insertArgs...); err != nil {
+			return false, err
+		}
+	}
+}
+
+// productExists returns ErrProductNotFound when there is no product id
+func productExists(ctx context.Context, conn queryer, dialect Dialect, id string) error {
+	var exists int
+	err := conn.QueryRowContext(ctx, dialect.Rebind("SELECT 1 FROM Products WHERE id = ?"), id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return custom_errors.ErrProductNotFound
+	}
+
+	return err
+}
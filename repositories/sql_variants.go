@@ -0,0 +1,257 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+)
+
+// SQLVariantRepository stores the options of products in ProductOptions and their variants in
+// Variants, both encoded as JSON. SKUs and the combinations of option values of the variants of
+// a product are kept unique by the schema.
+type SQLVariantRepository struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+const variantColumns = "id, product_id, sku, options, price"
+
+func NewSQLVariantRepository(db *sql.DB, dialect Dialect) *SQLVariantRepository {
+	return &SQLVariantRepository{
+		DB:      db,
+		Dialect: dialect,
+	}
+}
+
+func (r *SQLVariantRepository) Options(ctx context.Context, productID string) ([]models.ProductOption, error) {
+	var encoded string
+	err := r.DB.QueryRowContext(ctx, r.Dialect.Rebind("SELECT options FROM ProductOptions WHERE product_id = ?"), productID).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		return make([]models.ProductOption, 0), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	options := make([]models.ProductOption, 0)
+	if err := json.Unmarshal([]byte(encoded), &options); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+func (r *SQLVariantRepository) SetOptions(ctx context.Context, productID string, options []models.ProductOption) error {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The options row is replaced first, which holds off the other writers of the options
+	// while the variants are checked against the new ones
+	if _, err := tx.ExecContext(ctx, r.Dialect.Rebind("DELETE FROM ProductOptions WHERE product_id = ?"), productID); err != nil {
+		return err
+	}
+	variants, err := r.list(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants {
+		if !variant.Matches(options) {
+			return custom_errors.ErrOptionsInUse
+		}
+	}
+	if _, err := tx.ExecContext(ctx, r.Dialect.Rebind("INSERT INTO ProductOptions (product_id, options) VALUES (?, ?)"), productID, string(encoded)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLVariantRepository) List(ctx context.Context, productID string) ([]models.Variant, error) {
+	return r.list(ctx, r.DB, productID)
+}
+
+func (r *SQLVariantRepository) list(ctx context.Context, conn queryer, productID string) ([]models.Variant, error) {
+	rows, err := conn.QueryContext(ctx, r.Dialect.Rebind("SELECT "+variantColumns+" FROM Variants WHERE product_id = ? ORDER BY sku"), productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make([]models.Variant, 0)
+	for rows.Next() {
+		variant, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, *variant)
+	}
+
+	return variants, rows.Err()
+}
+
+func (r *SQLVariantRepository) Get(ctx context.Context, productID, id string) (*models.Variant, error) {
+	row := r.DB.QueryRowContext(ctx, r.Dialect.Rebind("SELECT "+variantColumns+" FROM Variants WHERE id = ? AND product_id = ?"), id, productID)
+	variant, err := scanVariant(row)
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrVariantNotFound
+	}
+
+	return variant, err
+}
+
+func (r *SQLVariantRepository) Insert(ctx context.Context, variants []models.Variant) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, variant := range variants {
+		encoded, err := json.Marshal(variant.Options)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, r.Dialect.Rebind("INSERT INTO Variants (id, product_id, sku, options, options_key, price) VALUES (?, ?, ?, ?, ?, ?)"),
+			variant.ID, variant.ProductID, variant.SKU, string(encoded), variant.OptionsKey(), variant.Price)
+		if isUniqueViolation(err) {
+			// PostgreSQL aborts the transaction on the failed statement, the clash is told apart
+			// after rolling it back: among the stored variants, then the ones inserted before it
+			_ = tx.Rollback()
+			if err := r.conflict(ctx, r.DB, variant); err != nil {
+				return err
+			}
+			return batchConflict(variants[:i], variant)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLVariantRepository) Update(ctx context.Context, variant models.Variant) error {
+	encoded, err := json.Marshal(variant.Options)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.conflict(ctx, tx, variant); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, r.Dialect.Rebind("UPDATE Variants SET sku = ?, options = ?, options_key = ?, price = ? WHERE id = ? AND product_id = ?"),
+		variant.SKU, string(encoded), variant.OptionsKey(), variant.Price, variant.ID, variant.ProductID)
+	if err != nil {
+		return err
+	}
+
+	// MySQL does not count the rows left unchanged, tell them apart from missing ones
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		var exists int
+		err := tx.QueryRowContext(ctx, r.Dialect.Rebind("SELECT 1 FROM Variants WHERE id = ? AND product_id = ?"), variant.ID, variant.ProductID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return custom_errors.ErrVariantNotFound
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLVariantRepository) Delete(ctx context.Context, productID, id string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, r.Dialect.Rebind("DELETE FROM Variants WHERE id = ? AND product_id = ?"), id, productID)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return custom_errors.ErrVariantNotFound
+	}
+
+	// The stock rows of a variant are not tied to it by the schema, stock of the product
+	// itself having no variant
+	if _, err := tx.ExecContext(ctx, r.Dialect.Rebind("DELETE FROM WarehouseStock WHERE product_id = ? AND variant_id = ?"), productID, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// conflict returns ErrDuplicateSKU or ErrDuplicateVariant when another variant has the SKU or
+// the options of variant
+func (r *SQLVariantRepository) conflict(ctx context.Context, conn queryer, variant models.Variant) error {
+	var exists int
+	err := conn.QueryRowContext(ctx, r.Dialect.Rebind("SELECT 1 FROM Variants WHERE sku = ? AND id <> ?"), variant.SKU, variant.ID).Scan(&exists)
+	if err == nil {
+		return custom_errors.ErrDuplicateSKU
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	err = conn.QueryRowContext(ctx, r.Dialect.Rebind("SELECT 1 FROM Variants WHERE product_id = ? AND options_key = ? AND id <> ?"),
+		variant.ProductID, variant.OptionsKey(), variant.ID).Scan(&exists)
+	if err == nil {
+		return custom_errors.ErrDuplicateVariant
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	return nil
+}
+
+// batchConflict returns the error of inserting variant along with the variants inserted before it
+func batchConflict(inserted []models.Variant, variant models.Variant) error {
+	key := variant.OptionsKey()
+	for _, other := range inserted {
+		if other.SKU == variant.SKU {
+			return custom_errors.ErrDuplicateSKU
+		}
+		if other.ProductID == variant.ProductID && other.OptionsKey() == key {
+			return custom_errors.ErrDuplicateVariant
+		}
+	}
+
+	return fmt.Errorf("variant %s already exists", variant.ID)
+}
+
+// scanVariant scans a row of variantColumns
+func scanVariant(row interface{ Scan(dest ...any) error }) (*models.Variant, error) {
+	var variant models.Variant
+	var options string
+	var price sql.NullFloat64
+	if err := row.Scan(&variant.ID, &variant.ProductID, &variant.SKU, &options, &price); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(options), &variant.Options); err != nil {
+		return nil, err
+	}
+	if price.Valid {
+		variant.Price = &price.Float64
+	}
+
+	return &variant, nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+)
+
+// SQLWarehouseRepository stores warehouses in a relational database
+type SQLWarehouseRepository struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+const warehouseColumns = "id, name, region, priority"
+
+func NewSQLWarehouseRepository(db *sql.DB, dialect Dialect) *SQLWarehouseRepository {
+	return &SQLWarehouseRepository{
+		DB:      db,
+		Dialect: dialect,
+	}
+}
+
+func (r *SQLWarehouseRepository) Get(ctx context.Context, id string) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	err := r.DB.QueryRowContext(ctx, r.Dialect.Rebind("SELECT "+warehouseColumns+" FROM Warehouses WHERE id = ?"), id).
+		Scan(&warehouse.ID, &warehouse.Name, &warehouse.Region, &warehouse.Priority)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, custom_errors.ErrWarehouseNotFound
+		}
+		return nil, err
+	}
+
+	return &warehouse, nil
+}
+
+func (r *SQLWarehouseRepository) List(ctx context.Context) ([]models.Warehouse, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+warehouseColumns+" FROM Warehouses ORDER BY priority, name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := make([]models.Warehouse, 0)
+	for rows.Next() {
+		var warehouse models.Warehouse
+		if err := rows.Scan(&warehouse.ID, &warehouse.Name, &warehouse.Region, &warehouse.Priority); err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	return warehouses, rows.Err()
+}
+
+func (r *SQLWarehouseRepository) Insert(ctx context.Context, id string, warehouse *models.Warehouse) (*models.Warehouse, error) {
+	_, err := r.DB.ExecContext(ctx, r.Dialect.Rebind("INSERT INTO Warehouses ("+warehouseColumns+") VALUES (?, ?, ?, ?)"),
+		id, warehouse.Name, warehouse.Region, warehouse.Priority)
+	if err != nil {
+		return nil, err
+	}
+
+	created := *warehouse
+	created.ID = id
+
+	return &created, nil
+}
+
+func (r *SQLWarehouseRepository) Update(ctx context.Context, id string, warehouse *models.Warehouse) (*models.Warehouse, error) {
+	result, err := r.DB.ExecContext(ctx, r.Dialect.Rebind("UPDATE Warehouses SET name = ?, region = ?, priority = ? WHERE id = ?"),
+		warehouse.Name, warehouse.Region, warehouse.Priority, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// MySQL does not count the rows left unchanged, tell them apart from missing ones
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return r.Get(ctx, id)
+	}
+
+	updated := *warehouse
+	updated.ID = id
+
+	return &updated, nil
+}
+
+func (r *SQLWarehouseRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.DB.ExecContext(ctx, r.Dialect.Rebind("DELETE FROM Warehouses WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return custom_errors.ErrWarehouseNotFound
+	}
+
+	return nil
+}
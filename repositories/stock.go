@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+)
+
+// StockRepository stores the stock of products per warehouse, along with the ledger of its
+// movements
+type StockRepository interface {
+	// Get returns the stock of the product, in total and in each warehouse it was stocked in,
+	// empty until its first movement
+	Get(ctx context.Context, productID string) (*models.Stock, error)
+	// Move applies the movements of the product to the stock of their warehouses and records
+	// them in the ledger, with their sequence numbers and the quantities they leave. Either all
+	// the movements are applied or, when one would leave less stock on hand than reserved or a
+	// negative reservation in its warehouse, none. Concurrent moves of a product are applied
+	// one after the other, so stock is never oversold.
+	Move(ctx context.Context, productID string, movements []models.StockMovement) ([]models.StockMovement, error)
+	// Movements returns the ledger of the product, latest first
+	Movements(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, error)
+	CountMovements(ctx context.Context, productID string) (int, error)
+	// HoldsStock reports whether the warehouse holds stock of any product, on hand or reserved
+	HoldsStock(ctx context.Context, warehouseID string) (bool, error)
+}
+
+// movementError is the error of a movement that would leave too little stock
+func movementError(movement models.StockMovement) error {
+	switch movement.Type {
+	case models.StockRelease, models.StockCommit:
+		return custom_errors.ErrInsufficientReservedStock
+	default:
+		return custom_errors.ErrInsufficientStock
+	}
+}
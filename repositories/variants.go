@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+	"simpler-products/models"
+)
+
+// VariantRepository abstracts the storage of the options of products and of their variants.
+// Their stock is stored through StockRepository.
+type VariantRepository interface {
+	// Options returns the options of the product, none when they were never set
+	Options(ctx context.Context, productID string) ([]models.ProductOption, error)
+	// SetOptions replaces the options of the product, failing with ErrOptionsInUse when a
+	// variant of the product does not match them
+	SetOptions(ctx context.Context, productID string, options []models.ProductOption) error
+	// List returns the variants of the product, ordered by SKU
+	List(ctx context.Context, productID string) ([]models.Variant, error)
+	Get(ctx context.Context, productID, id string) (*models.Variant, error)
+	// Insert stores every variant or none of them, failing with ErrDuplicateSKU or
+	// ErrDuplicateVariant when one clashes with another
+	Insert(ctx context.Context, variants []models.Variant) error
+	Update(ctx context.Context, variant models.Variant) error
+	// Delete deletes the variant, along with the stock it does not hold anymore
+	Delete(ctx context.Context, productID, id string) error
+}
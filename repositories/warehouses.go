@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+	"simpler-products/models"
+)
+
+// WarehouseRepository abstracts the storage of warehouses. Their stock is stored through
+// StockRepository.
+type WarehouseRepository interface {
+	Get(ctx context.Context, id string) (*models.Warehouse, error)
+	// List returns every warehouse, ordered by priority then name
+	List(ctx context.Context) ([]models.Warehouse, error)
+	Insert(ctx context.Context, id string, warehouse *models.Warehouse) (*models.Warehouse, error)
+	Update(ctx context.Context, id string, warehouse *models.Warehouse) (*models.Warehouse, error)
+	Delete(ctx context.Context, id string) error
+}
@@ -5,10 +5,12 @@ import (
 	"simpler-products/config"
 	"simpler-products/controllers"
 	v1Controllers "simpler-products/controllers/v1"
+	"simpler-products/idempotency"
+	"simpler-products/metrics"
 	"simpler-products/middlewares"
+	"simpler-products/models"
 	"simpler-products/services"
 
-	"github.com/dvwright/xss-mw"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -19,9 +21,23 @@ func NewRouter(servs config.ServiceContainer, log *logrus.Logger) *gin.Engine {
 	// add middlewares
 	router.Use(gin.Recovery())
 
+	recorder, ok := servs.(metrics.RecorderInterface)
+	if !ok {
+		log.Fatal("RecorderInterface not found in services")
+	}
+	router.Use(middlewares.Metrics(recorder))
+
+	// /metrics is registered before the remaining middlewares so its exposition format is served as is
+	router.GET("/metrics", gin.WrapH(recorder.MetricsHandler()))
+
+	// start a server span per request, continuing the caller's trace if any
+	router.Use(middlewares.Tracing())
+
+	// tag the request, its logs and its response with an X-Request-ID
+	router.Use(middlewares.RequestID(log))
+
 	// sanitize input for XSS protection
-	var xssMdlwr xss.XssMw
-	router.Use(xssMdlwr.RemoveXss())
+	router.Use(middlewares.RemoveXss())
 
 	router.Use(middlewares.SecurityHeaders())
 	router.Use(middlewares.CORSMiddleware())
@@ -37,6 +53,18 @@ func NewRouter(servs config.ServiceContainer, log *logrus.Logger) *gin.Engine {
 		ping.GET("", controllers.Ping())
 	}
 
+	// /health routes
+	{
+		healthService, ok := servs.(services.HealthServiceInterface)
+		if !ok {
+			log.Fatal("HealthServiceInterface not found in services")
+		}
+
+		health := api.Group("/health")
+		health.GET("/live", controllers.Liveness())
+		health.GET("/ready", controllers.Readiness(healthService))
+	}
+
 	{
 		// v1 routes
 		v1Routes := api.Group("/v1")
@@ -48,6 +76,16 @@ func NewRouter(servs config.ServiceContainer, log *logrus.Logger) *gin.Engine {
 				log.Fatal("ProductsServiceInterface not found in services")
 			}
 
+			searchService, ok := servs.(services.SearchServiceInterface)
+			if !ok {
+				log.Fatal("SearchServiceInterface not found in services")
+			}
+
+			idempotencyStore, ok := servs.(idempotency.StoreInterface)
+			if !ok {
+				log.Fatal("idempotency StoreInterface not found in services")
+			}
+
 			products := v1Routes.Group("/products")
 
 			authEnabled := os.Getenv("AUTH_ENABLED")
@@ -57,11 +95,84 @@ func NewRouter(servs config.ServiceContainer, log *logrus.Logger) *gin.Engine {
 			}
 
 			products.GET("", v1Controllers.GetAllProducts(productsService))
+			products.GET("/export", v1Controllers.ExportProducts(productsService))
+			products.GET("/search", v1Controllers.SearchProducts(searchService))
+			products.GET("/suggest", v1Controllers.SuggestProducts(searchService))
 			products.GET("/:id", v1Controllers.GetProductById(productsService))
-			products.POST("", v1Controllers.AddProduct(productsService))
+			products.POST("", middlewares.Idempotency(idempotencyStore), v1Controllers.AddProduct(productsService))
+			products.POST("/bulk", middlewares.Idempotency(idempotencyStore), v1Controllers.BulkProducts(productsService))
+			products.POST("/import", v1Controllers.ImportProducts(productsService))
 			products.PUT("/:id", v1Controllers.UpdateProduct(productsService))
+			products.PATCH("/:id", v1Controllers.PatchProduct(productsService))
 			products.DELETE("/:id", v1Controllers.DeleteProduct(productsService))
 
+			// /products/:id/stock routes
+			inventoryService, ok := servs.(services.InventoryServiceInterface)
+			if !ok {
+				log.Fatal("InventoryServiceInterface not found in services")
+			}
+
+			products.GET("/:id/stock", v1Controllers.GetStock(inventoryService))
+			products.GET("/:id/stock/movements", v1Controllers.GetStockMovements(inventoryService))
+			for _, movementType := range []string{models.StockAdjust, models.StockReserve, models.StockRelease, models.StockCommit} {
+				products.POST("/:id/stock/"+movementType, middlewares.Idempotency(idempotencyStore), v1Controllers.MoveStock(inventoryService, movementType))
+			}
+			products.POST("/:id/stock/transfer", middlewares.Idempotency(idempotencyStore), v1Controllers.TransferStock(inventoryService))
+			products.POST("/:id/stock/allocate", middlewares.Idempotency(idempotencyStore), v1Controllers.AllocateStock(inventoryService))
+
+			// /products/:id/options and /products/:id/variants routes
+			variantsService, ok := servs.(services.VariantsServiceInterface)
+			if !ok {
+				log.Fatal("VariantsServiceInterface not found in services")
+			}
+
+			products.GET("/:id/options", v1Controllers.GetProductOptions(variantsService))
+			products.PUT("/:id/options", v1Controllers.SetProductOptions(variantsService))
+			products.GET("/:id/variants", v1Controllers.GetAllVariants(variantsService))
+			products.GET("/:id/variants/:variant_id", v1Controllers.GetVariantById(variantsService))
+			products.GET("/:id/variants/:variant_id/stock", v1Controllers.GetVariantStock(inventoryService))
+			products.POST("/:id/variants", v1Controllers.AddVariant(variantsService))
+			products.POST("/:id/variants/generate", v1Controllers.GenerateVariants(variantsService))
+			products.PUT("/:id/variants/:variant_id", v1Controllers.UpdateVariant(variantsService))
+			products.DELETE("/:id/variants/:variant_id", v1Controllers.DeleteVariant(variantsService))
+
+			// /warehouses routes
+			warehousesService, ok := servs.(services.WarehousesServiceInterface)
+			if !ok {
+				log.Fatal("WarehousesServiceInterface not found in services")
+			}
+
+			warehouses := v1Routes.Group("/warehouses")
+			if authEnabled == "true" {
+				warehouses.Use(middlewares.JWTAuthMiddleware())
+			}
+
+			warehouses.GET("", v1Controllers.GetAllWarehouses(warehousesService))
+			warehouses.GET("/:id", v1Controllers.GetWarehouseById(warehousesService))
+			warehouses.POST("", v1Controllers.AddWarehouse(warehousesService))
+			warehouses.PUT("/:id", v1Controllers.UpdateWarehouse(warehousesService))
+			warehouses.DELETE("/:id", v1Controllers.DeleteWarehouse(warehousesService))
+
+			// /categories routes, along with the categories of a product
+			categoriesService, ok := servs.(services.CategoriesServiceInterface)
+			if !ok {
+				log.Fatal("CategoriesServiceInterface not found in services")
+			}
+
+			categories := v1Routes.Group("/categories")
+			if authEnabled == "true" {
+				categories.Use(middlewares.JWTAuthMiddleware())
+			}
+
+			categories.GET("", v1Controllers.GetAllCategories(categoriesService))
+			categories.GET("/tree", v1Controllers.GetCategoryTree(categoriesService))
+			categories.GET("/:id", v1Controllers.GetCategoryById(categoriesService))
+			categories.POST("", v1Controllers.AddCategory(categoriesService))
+			categories.PUT("/:id", v1Controllers.UpdateCategory(categoriesService))
+			categories.DELETE("/:id", v1Controllers.DeleteCategory(categoriesService))
+
+			products.GET("/:id/categories", v1Controllers.GetProductCategories(categoriesService))
+			products.PUT("/:id/categories", v1Controllers.SetProductCategories(categoriesService))
 		}
 	}
 
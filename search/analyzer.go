@@ -0,0 +1,55 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kljensen/snowball/english"
+)
+
+// token is a word of a text, along with the term it is indexed under
+type token struct {
+	// Word is the lowercased word, used for prefix matching
+	Word string
+	// Term is the stem of Word
+	Term string
+	// Start and End are the byte offsets of the word in the text, used for highlighting
+	Start, End int
+}
+
+// analyze splits text into words made of letters and digits, lowercases and stems them,
+// and drops English stop words
+func analyze(text string) []token {
+	tokens := make([]token, 0)
+
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+
+		word := strings.ToLower(text[start:end])
+		if !english.IsStopWord(word) {
+			tokens = append(tokens, token{
+				Word:  word,
+				Term:  english.Stem(word, false),
+				Start: start,
+				End:   end,
+			})
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+
+	return tokens
+}
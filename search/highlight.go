@@ -0,0 +1,95 @@
+package search
+
+import (
+	"html"
+	"simpler-products/models"
+	"strings"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+
+	// snippetLength is the approximate length in bytes of the description excerpts
+	snippetLength = 160
+	// snippetLead is how much text precedes the first match in an excerpt
+	snippetLead = 40
+	ellipsis    = "…"
+)
+
+// highlights returns, for every field of product containing one of terms, its text with
+// the matching words marked. Long texts are cut to an excerpt around the first match.
+// The text is HTML-escaped, so that excerpts can be rendered as is.
+func highlights(product models.Product, terms map[string]float64) map[string]string {
+	out := make(map[string]string)
+
+	for _, field := range fields {
+		text := field.Value(product)
+
+		matches := make([]token, 0)
+		for _, token := range analyze(text) {
+			if _, ok := terms[token.Term]; ok {
+				matches = append(matches, token)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		out[field.Name] = snippet(text, matches)
+	}
+
+	return out
+}
+
+// snippet cuts an excerpt of text around the first match and marks the matches it contains
+func snippet(text string, matches []token) string {
+	start, end := 0, len(text)
+	if len(text) > snippetLength {
+		start = wordStart(text, max(0, matches[0].Start-snippetLead))
+		end = wordEnd(text, min(len(text), start+snippetLength))
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(ellipsis)
+	}
+
+	position := start
+	for _, match := range matches {
+		if match.Start < start {
+			continue
+		}
+		if match.End > end {
+			break
+		}
+
+		b.WriteString(html.EscapeString(text[position:match.Start]))
+		b.WriteString(highlightStart)
+		b.WriteString(html.EscapeString(text[match.Start:match.End]))
+		b.WriteString(highlightEnd)
+		position = match.End
+	}
+	b.WriteString(html.EscapeString(text[position:end]))
+
+	if end < len(text) {
+		b.WriteString(ellipsis)
+	}
+
+	return b.String()
+}
+
+// wordStart moves i back to the beginning of the word it falls in
+func wordStart(text string, i int) int {
+	return strings.LastIndexByte(text[:i], ' ') + 1
+}
+
+// wordEnd moves i forward to the end of the word it falls in
+func wordEnd(text string, i int) int {
+	next := strings.IndexByte(text[i:], ' ')
+	if next < 0 {
+		return len(text)
+	}
+
+	return i + next
+}
@@ -0,0 +1,296 @@
+package search
+
+import (
+	"cmp"
+	"math"
+	"simpler-products/models"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kljensen/snowball/english"
+)
+
+// BM25 parameters, with their usual values
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+const (
+	// minPrefixLength is the shortest word prefix expanded to the words it starts
+	minPrefixLength = 2
+	// prefixWeight discounts the terms only matched by prefix, so that whole words rank first
+	prefixWeight = 0.5
+)
+
+// field is an indexed product field
+type field struct {
+	Name   string
+	Weight float64
+	Value  func(models.Product) string
+}
+
+// fields are the indexed fields, matches in the name weighing more than in the description
+var fields = [...]field{
+	{Name: "name", Weight: 2, Value: func(p models.Product) string { return p.Name }},
+	{Name: "description", Weight: 1, Value: func(p models.Product) string { return p.Description }},
+}
+
+// frequencies counts the occurrences of a term in each field of a document
+type frequencies [len(fields)]int
+
+type document struct {
+	product models.Product
+	lengths [len(fields)]int
+	// terms and words are the distinct terms and words of the document
+	terms []string
+	words []string
+}
+
+// Hit is a product matching a search
+type Hit struct {
+	Product models.Product `json:"product"`
+	Score   float64        `json:"score"`
+	// Highlights holds, for each matching field, an excerpt with the matches wrapped in <mark> tags
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Index is an in-process inverted index of products ranking matches with BM25.
+// It is safe for concurrent use.
+type Index struct {
+	mu        sync.RWMutex
+	documents map[string]*document
+	// postings maps every term to the documents containing it
+	postings map[string]map[string]*frequencies
+	// words counts the documents containing every word, sortedWords lists them for prefix lookups
+	words        map[string]int
+	sortedWords  []string
+	totalLengths [len(fields)]int
+	// names holds the product names, for suggestions
+	names *trie
+}
+
+func NewIndex() *Index {
+	return &Index{
+		documents:   make(map[string]*document),
+		postings:    make(map[string]map[string]*frequencies),
+		words:       make(map[string]int),
+		sortedWords: make([]string, 0),
+		names:       newTrie(),
+	}
+}
+
+// Len returns the number of indexed products
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.documents)
+}
+
+// Add indexes product, replacing the previous version of it if any
+func (idx *Index) Add(product models.Product) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(product.ID)
+
+	doc := &document{product: product}
+	termFrequencies := make(map[string]*frequencies)
+	words := make(map[string]bool)
+
+	for f, field := range fields {
+		tokens := analyze(field.Value(product))
+		doc.lengths[f] = len(tokens)
+		idx.totalLengths[f] += len(tokens)
+
+		for _, token := range tokens {
+			if termFrequencies[token.Term] == nil {
+				termFrequencies[token.Term] = &frequencies{}
+			}
+			termFrequencies[token.Term][f]++
+			words[token.Word] = true
+		}
+	}
+
+	for term, freqs := range termFrequencies {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]*frequencies)
+		}
+		idx.postings[term][product.ID] = freqs
+		doc.terms = append(doc.terms, term)
+	}
+
+	for word := range words {
+		if idx.words[word] == 0 {
+			position, _ := slices.BinarySearch(idx.sortedWords, word)
+			idx.sortedWords = slices.Insert(idx.sortedWords, position, word)
+		}
+		idx.words[word]++
+		doc.words = append(doc.words, word)
+	}
+
+	idx.names.add(product.Name)
+	idx.documents[product.ID] = doc
+}
+
+// Remove drops the product from the index
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id string) {
+	doc, ok := idx.documents[id]
+	if !ok {
+		return
+	}
+
+	for f := range fields {
+		idx.totalLengths[f] -= doc.lengths[f]
+	}
+
+	for _, term := range doc.terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+
+	for _, word := range doc.words {
+		idx.words[word]--
+		if idx.words[word] == 0 {
+			delete(idx.words, word)
+			if position, found := slices.BinarySearch(idx.sortedWords, word); found {
+				idx.sortedWords = slices.Delete(idx.sortedWords, position, position+1)
+			}
+		}
+	}
+
+	idx.names.remove(doc.product.Name)
+	delete(idx.documents, id)
+}
+
+// Search returns a page of the products matching any word of query, best matches first,
+// along with their total number. The last word also matches the words it is a prefix of,
+// unless the query ends with a separator, so that results show up while typing.
+func (idx *Index) Search(query string, limit, offset int) ([]Hit, int) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := idx.queryTerms(query)
+	scores := idx.scores(terms)
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{
+			Product: idx.documents[id].product,
+			Score:   score,
+		})
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Product.ID, b.Product.ID)
+	})
+
+	total := len(hits)
+	if offset >= total {
+		return make([]Hit, 0), total
+	}
+	hits = hits[offset:min(offset+limit, total)]
+
+	for i := range hits {
+		hits[i].Score = math.Round(hits[i].Score*1e4) / 1e4
+		hits[i].Highlights = highlights(hits[i].Product, terms)
+	}
+
+	return hits, total
+}
+
+// Facets summarizes the products matching query, as Search finds them
+func (idx *Index) Facets(query string, facetQuery models.FacetQuery) *models.Facets {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := idx.scores(idx.queryTerms(query))
+
+	facets := &models.Facets{}
+	if facetQuery.Price != nil {
+		prices := make([]float64, 0, len(scores))
+		for id := range scores {
+			prices = append(prices, idx.documents[id].product.Price)
+		}
+		facets.Price = models.PriceFacetOf(prices, *facetQuery.Price)
+	}
+
+	return facets
+}
+
+// scores returns the BM25 score of every product matching one of terms
+func (idx *Index) scores(terms map[string]float64) map[string]float64 {
+	scores := make(map[string]float64)
+	documentCount := float64(len(idx.documents))
+
+	for term, weight := range terms {
+		postings := idx.postings[term]
+		matching := float64(len(postings))
+		idf := math.Log(1 + (documentCount-matching+0.5)/(matching+0.5))
+
+		for id, freqs := range postings {
+			doc := idx.documents[id]
+			for f, field := range fields {
+				tf := float64(freqs[f])
+				if tf == 0 {
+					continue
+				}
+
+				averageLength := float64(idx.totalLengths[f]) / documentCount
+				norm := 1 - b + b*float64(doc.lengths[f])/averageLength
+				scores[id] += weight * field.Weight * idf * tf * (k1 + 1) / (tf + k1*norm)
+			}
+		}
+	}
+
+	return scores
+}
+
+// queryTerms returns the indexed terms matching query, with their weight
+func (idx *Index) queryTerms(query string) map[string]float64 {
+	tokens := analyze(query)
+	terms := make(map[string]float64, len(tokens))
+
+	for _, token := range tokens {
+		if _, ok := idx.postings[token.Term]; ok {
+			terms[token.Term] = 1
+		}
+	}
+
+	if len(tokens) == 0 {
+		return terms
+	}
+
+	// A separator after the last word means it is complete
+	last := tokens[len(tokens)-1]
+	if last.End < len(query) || len(last.Word) < minPrefixLength {
+		return terms
+	}
+
+	position, _ := slices.BinarySearch(idx.sortedWords, last.Word)
+	for _, word := range idx.sortedWords[position:] {
+		if !strings.HasPrefix(word, last.Word) {
+			break
+		}
+
+		term := english.Stem(word, false)
+		if _, ok := terms[term]; !ok {
+			terms[term] = prefixWeight
+		}
+	}
+
+	return terms
+}
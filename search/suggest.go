@@ -0,0 +1,83 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Typo tolerance of fuzzy suggestions, growing with the length of the prefix since short
+// prefixes are a few edits away from almost anything
+const (
+	oneTypoMinLength  = 4
+	twoTyposMinLength = 8
+)
+
+// Suggestion is a product name completing a prefix
+type Suggestion struct {
+	Name string `json:"name"`
+	// Fuzzy tells whether the name only completes the prefix once its typos are corrected
+	Fuzzy bool `json:"fuzzy"`
+}
+
+// Suggest returns up to limit distinct product names starting with prefix, or having a
+// word starting with it, ignoring case. Names starting with prefix come first, then the
+// shortest ones. When fuzzy is set, names completing the prefix with a few typos are also
+// returned, after the exact completions.
+func (idx *Index) Suggest(prefix string, limit int, fuzzy bool) []Suggestion {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	prefix = normalizePrefix(prefix)
+	if prefix == "" {
+		return make([]Suggestion, 0)
+	}
+
+	found := make(candidates)
+	if maxDistance := typoTolerance(prefix); fuzzy && maxDistance > 0 {
+		idx.names.completeFuzzy(prefix, maxDistance, found)
+	} else {
+		idx.names.complete(prefix, found)
+	}
+
+	matches := make([]candidate, 0, len(found))
+	for _, match := range found {
+		matches = append(matches, match)
+	}
+	slices.SortFunc(matches, func(a, b candidate) int {
+		if a.better(b) {
+			return -1
+		}
+		if b.better(a) {
+			return 1
+		}
+		if c := cmp.Compare(utf8.RuneCountInString(a.name), utf8.RuneCountInString(b.name)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+
+	suggestions := make([]Suggestion, 0, min(limit, len(matches)))
+	for _, match := range matches[:min(limit, len(matches))] {
+		suggestions = append(suggestions, Suggestion{
+			Name:  match.name,
+			Fuzzy: match.distance > 0,
+		})
+	}
+
+	return suggestions
+}
+
+// typoTolerance returns the number of typos allowed in prefix
+func typoTolerance(prefix string) int {
+	length := utf8.RuneCountInString(strings.TrimSpace(prefix))
+	switch {
+	case length >= twoTyposMinLength:
+		return 2
+	case length >= oneTypoMinLength:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// trie is a prefix tree of product names, used for suggestions. Names can be reached from
+// the start of each of their words, so that "lamp" also suggests "Desk Lamp".
+type trie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	// names holds the names whose key ends at the node
+	names map[string]*trieEntry
+}
+
+type trieEntry struct {
+	// products counts the products with the name
+	products int
+	// whole tells whether the key is the whole name rather than one of its word suffixes
+	whole bool
+}
+
+func newTrie() *trie {
+	return &trie{root: &trieNode{}}
+}
+
+// add inserts name under its keys, counting one more product with it
+func (t *trie) add(name string) {
+	for i, key := range nameKeys(name) {
+		node := t.root
+		for _, r := range key {
+			if node.children == nil {
+				node.children = make(map[rune]*trieNode)
+			}
+			child, ok := node.children[r]
+			if !ok {
+				child = &trieNode{}
+				node.children[r] = child
+			}
+			node = child
+		}
+
+		if node.names == nil {
+			node.names = make(map[string]*trieEntry)
+		}
+		entry, ok := node.names[name]
+		if !ok {
+			entry = &trieEntry{whole: i == 0}
+			node.names[name] = entry
+		}
+		entry.products++
+	}
+}
+
+// remove counts one product less with name, and prunes the nodes left empty
+func (t *trie) remove(name string) {
+	for _, key := range nameKeys(name) {
+		runes := []rune(key)
+		path := make([]*trieNode, 0, len(runes)+1)
+
+		node := t.root
+		for _, r := range runes {
+			path = append(path, node)
+			if node = node.children[r]; node == nil {
+				break
+			}
+		}
+		if node == nil || node.names[name] == nil {
+			continue
+		}
+
+		node.names[name].products--
+		if node.names[name].products > 0 {
+			continue
+		}
+		delete(node.names, name)
+
+		for i := len(runes) - 1; i >= 0 && len(node.names) == 0 && len(node.children) == 0; i-- {
+			delete(path[i].children, runes[i])
+			node = path[i]
+		}
+	}
+}
+
+// candidate is a name matching a prefix, with the number of typos it takes
+type candidate struct {
+	name     string
+	distance int
+	whole    bool
+}
+
+// better tells whether c ranks before other: fewer typos first, then names starting with
+// the prefix before names with a word starting with it
+func (c candidate) better(other candidate) bool {
+	if c.distance != other.distance {
+		return c.distance < other.distance
+	}
+
+	return c.whole && !other.whole
+}
+
+// candidates holds the best match of every name found
+type candidates map[string]candidate
+
+// addNames adds the names ending at node
+func (cs candidates) addNames(node *trieNode, distance int) {
+	for name, entry := range node.names {
+		match := candidate{name: name, distance: distance, whole: entry.whole}
+		if current, ok := cs[name]; !ok || match.better(current) {
+			cs[name] = match
+		}
+	}
+}
+
+// addAll adds the names ending at node or below it
+func (cs candidates) addAll(node *trieNode, distance int) {
+	cs.addNames(node, distance)
+	for _, child := range node.children {
+		cs.addAll(child, distance)
+	}
+}
+
+// complete adds to found the names with a key starting with prefix
+func (t *trie) complete(prefix string, found candidates) {
+	node := t.root
+	for _, r := range prefix {
+		if node = node.children[r]; node == nil {
+			return
+		}
+	}
+
+	found.addAll(node, 0)
+}
+
+// completeFuzzy adds to found the names with a key starting with a string at most
+// maxDistance edits away from prefix. Edits are insertions, deletions, substitutions and
+// transpositions of adjacent characters.
+func (t *trie) completeFuzzy(prefix string, maxDistance int, found candidates) {
+	walk := fuzzyWalk{
+		query:       []rune(prefix),
+		maxDistance: maxDistance,
+		found:       found,
+	}
+
+	// The first row is the distance from the empty key to every prefix of the query
+	row := make([]int, len(walk.query)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for r, child := range t.root.children {
+		walk.visit(child, []rune{r}, [][]int{row}, len(walk.query))
+	}
+}
+
+// fuzzyWalk walks the trie computing, one row per node, the edit distances between the
+// key of the node and every prefix of the query
+type fuzzyWalk struct {
+	query       []rune
+	maxDistance int
+	found       candidates
+}
+
+// visit computes the row of node from the rows of its ancestors. best is the smallest
+// distance between the whole query and the key of an ancestor.
+func (w *fuzzyWalk) visit(node *trieNode, key []rune, rows [][]int, best int) {
+	depth := len(key)
+	r := key[depth-1]
+	previous := rows[depth-1]
+
+	row := make([]int, len(w.query)+1)
+	row[0] = depth
+	smallest := row[0]
+	for j := 1; j <= len(w.query); j++ {
+		cost := 1
+		if w.query[j-1] == r {
+			cost = 0
+		}
+		row[j] = min(previous[j]+1, row[j-1]+1, previous[j-1]+cost)
+
+		if j > 1 && depth > 1 && w.query[j-1] == key[depth-2] && w.query[j-2] == r {
+			row[j] = min(row[j], rows[depth-2][j-2]+1)
+		}
+		smallest = min(smallest, row[j])
+	}
+	best = min(best, row[len(w.query)])
+
+	if smallest > w.maxDistance {
+		// Distances only grow deeper down, so best is final for the names below
+		if best <= w.maxDistance {
+			w.found.addAll(node, best)
+		}
+		return
+	}
+
+	if best <= w.maxDistance {
+		w.found.addNames(node, best)
+	}
+
+	rows = append(rows, row)
+	for r, child := range node.children {
+		w.visit(child, append(key, r), rows, best)
+	}
+}
+
+// nameKeys returns the keys of a name: its normalized form, then the suffixes of it
+// starting at each of its words
+func nameKeys(name string) []string {
+	words := strings.Fields(strings.ToLower(name))
+	keys := make([]string, 0, len(words))
+	for i := range words {
+		keys = append(keys, strings.Join(words[i:], " "))
+	}
+
+	return keys
+}
+
+// normalizePrefix lowercases prefix and collapses its whitespace like nameKeys does. A
+// trailing space is kept, since it means the last word is complete.
+func normalizePrefix(prefix string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(prefix)), " ")
+	if normalized != "" && strings.TrimRightFunc(prefix, unicode.IsSpace) != prefix {
+		normalized += " "
+	}
+
+	return normalized
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"errors"
+	custom_errors "simpler-products/errors"
+	"simpler-products/logging"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type CategoriesServiceInterface interface {
+	ListCategories(ctx context.Context) ([]models.Category, error)
+	GetCategoryTree(ctx context.Context) ([]*models.CategoryNode, error)
+	GetCategory(ctx context.Context, id string) (*models.Category, error)
+	AddCategory(ctx context.Context, category *models.Category) error
+	UpdateCategory(ctx context.Context, id string, category *models.Category) (*models.Category, error)
+	DeleteCategory(ctx context.Context, id string) error
+	GetProductCategories(ctx context.Context, productID string) ([]models.Category, error)
+	SetProductCategories(ctx context.Context, productID string, categoryIDs []string) ([]models.Category, error)
+}
+
+// CategoriesService manages the category tree and the categories products are assigned to
+type CategoriesService struct {
+	Repo     repositories.CategoryRepository
+	Products repositories.ProductRepository
+	Log      *logrus.Logger
+	// QueryTimeout bounds the storage calls made while serving a request, zero disables it
+	QueryTimeout time.Duration
+	// Observer, when set, is notified of the duration and outcome of every method call
+	Observer MethodObserver
+}
+
+func (cs *CategoriesService) ListCategories(ctx context.Context) (categories []models.Category, err error) {
+	ctx, end := cs.begin(ctx, "ListCategories")
+	defer func() { end(err) }()
+
+	log := cs.logger(ctx)
+	log.Debug("Fetching categories from database")
+
+	categories, err = cs.Repo.List(ctx)
+	if err != nil {
+		log.Errorf("Error fetching categories: %v", err)
+		return nil, contextError(ctx, err)
+	}
+
+	return categories, nil
+}
+
+func (cs *CategoriesService) GetCategoryTree(ctx context.Context) (tree []*models.CategoryNode, err error) {
+	ctx, end := cs.begin(ctx, "GetCategoryTree")
+	defer func() { end(err) }()
+
+	log := cs.logger(ctx)
+	log.Debug("Building the category tree")
+
+	categories, err := cs.Repo.List(ctx)
+	if err != nil {
+		log.Errorf("Error fetching categories: %v", err)
+		return nil, contextError(ctx, err)
+	}
+
+	return models.CategoryTree(categories), nil
+}
+
+func (cs *CategoriesService) GetCategory(ctx context.Context, id string) (category *models.Category, err error) {
+	ctx, end := cs.begin(ctx, "GetCategory")
+	defer func() { end(err) }()
+
+	log := cs.logger(ctx)
+	log.Debugf("Fetching category with ID: %v from database", id)
+
+	category, err = cs.Repo.Get(ctx, id)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrCategoryNotFound) {
+			log.Errorf("Error fetching category: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return category, nil
+}
+
+func (cs *CategoriesService) AddCategory(ctx context.Context, category *models.Category) (err error) {
+	ctx, end := cs.begin(ctx, "AddCategory")
+	defer func() { end(err) }()
+
+	log := cs.logger(ctx)
+	log.Debugf("Creating new category in database, data: %+v", category)
+
+	id := uuid.NewString()
+	if err := checkParent(ctx, cs.Repo, id, category); err != nil {
+		if !isCategoryError(err) {
+			log.Errorf("Error fetching categories: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	createdCategory, err := cs.Repo.Insert(ctx, id, category)
+	if err != nil {
+		log.Errorf("Error creating new category: %v", err)
+		return contextError(ctx, err)
+	}
+
+	*category = *createdCategory
+
+	return nil
+}
+
+func (cs *CategoriesService) UpdateCategory(ctx context.Context, id string, category *models.Category) (updatedCategory *models.Category, err error) {
+	ctx, end := cs.begin(ctx, "UpdateCategory")
+	defer func() { end(err) }()
+
+	log := cs.logger(ctx)
+	log.Debugf("Updating category with ID: %v in database, data: %+v", id, category)
+
+	// The tree is checked and changed in a single transaction, no cycle is made by concurrent moves
+	err = cs.Repo.InTransaction(ctx, func(repo repositories.CategoryRepository) error {
+		if _, err := repo.Get(ctx, id); err != nil {
+			return err
+		}
+
+		if err := checkParent(ctx, repo, id, category); err != nil {
+			return err
+		}
+
+		updatedCategory, err = repo.Update(ctx, id, category)
+		return err
+	})
+	if err != nil {
+		if !isCategoryError(err) {
+			log.Errorf("Error updating category: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return updatedCategory, nil
+}
+
+func (cs *CategoriesService) DeleteCategory(ctx context.Context, id string) (err error) {
+	ctx, end := cs.begin(ctx, "DeleteCategory")
+	defer func() { end(err) }()
+
+	log := cs.logger(ctx)
+	log.Debugf("Deleting category with ID: %v from database", id)
+
+	// Only empty categories are deleted, products and subcategories are never left dangling.
+	// Products are assigned through their own repository, the schema refuses to delete the
+	// categories given products after they were counted.
+	assigned, err := cs.Products.Count(ctx, models.ProductFilter{CategoryIDs: []string{id}})
+	if err != nil {
+		log.Errorf("Error counting the products of the category: %v", err)
+		return contextError(ctx, err)
+	}
+	if assigned > 0 {
+		return custom_errors.ErrCategoryNotEmpty
+	}
+
+	err = cs.Repo.InTransaction(ctx, func(repo repositories.CategoryRepository) error {
+		categories, err := repo.List(ctx)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(categories, func(category models.Category) bool { return category.ID == id }) {
+			return custom_errors.ErrCategoryNotFound
+		}
+		if len(models.Descendants(categories, id)) > 1 {
+			return custom_errors.ErrCategoryNotEmpty
+		}
+
+		return repo.Delete(ctx, id)
+	})
+	if err != nil {
+		if !isCategoryError(err) {
+			log.Errorf("Error deleting category: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+func (cs *CategoriesService) GetProductCategories(ctx context.Context, productID string) (categories []models.Category, err error) {
+	ctx, end := cs.begin(ctx, "GetProductCategories")
+	defer func() { end(err) }()
+
+	log := cs.logger(ctx)
+	log.Debugf("Fetching the categories of product with ID: %v", productID)
+
+	return cs.productCategories(ctx, log, productID)
+}
+
+func (cs *CategoriesService) SetProductCategories(ctx context.Context, productID string, categoryIDs []string) (categories []models.Category, err error) {
+	ctx, end := cs.begin(ctx, "SetProductCategories")
+	defer func() { end(err) }()
+
+	log := cs.logger(ctx)
+	log.Debugf("Assigning product with ID: %v to categories: %v", productID, categoryIDs)
+
+	all, err := cs.Repo.List(ctx)
+	if err != nil {
+		log.Errorf("Error fetching categories: %v", err)
+		return nil, contextError(ctx, err)
+	}
+
+	ids := slices.Clone(categoryIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	for _, id := range ids {
+		if !slices.ContainsFunc(all, func(category models.Category) bool { return category.ID == id }) {
+			return nil, custom_errors.ErrUnknownCategories
+		}
+	}
+
+	if err := cs.Products.SetCategories(ctx, productID, ids); err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error assigning product categories: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return cs.productCategories(ctx, log, productID)
+}
+
+// productCategories returns the categories the product is assigned to, ordered by name
+func (cs *CategoriesService) productCategories(ctx context.Context, log *logrus.Entry, productID string) ([]models.Category, error) {
+	if _, err := cs.Products.Get(ctx, productID); err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error fetching product: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	ids, err := cs.Products.Categories(ctx, productID)
+	if err != nil {
+		log.Errorf("Error fetching product categories: %v", err)
+		return nil, contextError(ctx, err)
+	}
+
+	all, err := cs.Repo.List(ctx)
+	if err != nil {
+		log.Errorf("Error fetching categories: %v", err)
+		return nil, contextError(ctx, err)
+	}
+
+	categories := make([]models.Category, 0, len(ids))
+	for _, category := range all {
+		if slices.Contains(ids, category.ID) {
+			categories = append(categories, category)
+		}
+	}
+
+	return categories, nil
+}
+
+// checkParent normalises the parent of the category with the given ID and checks that it
+// exists and is not the category itself or one of its descendants
+func checkParent(ctx context.Context, repo repositories.CategoryRepository, id string, category *models.Category) error {
+	if category.ParentID != nil && *category.ParentID == "" {
+		category.ParentID = nil
+	}
+	if category.ParentID == nil {
+		return nil
+	}
+
+	categories, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(models.Descendants(categories, id), *category.ParentID) {
+		return custom_errors.ErrCategoryCycle
+	}
+	if !slices.ContainsFunc(categories, func(parent models.Category) bool { return parent.ID == *category.ParentID }) {
+		return custom_errors.ErrParentCategoryNotFound
+	}
+
+	return nil
+}
+
+// isCategoryError reports whether err is the expected outcome of changing the category tree,
+// not a failure
+func isCategoryError(err error) bool {
+	return errors.Is(err, custom_errors.ErrCategoryNotFound) ||
+		errors.Is(err, custom_errors.ErrParentCategoryNotFound) ||
+		errors.Is(err, custom_errors.ErrCategoryCycle) ||
+		errors.Is(err, custom_errors.ErrCategoryNotEmpty)
+}
+
+// begin prepares the context of a service call, as ProductsService.begin does
+func (cs *CategoriesService) begin(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, end := observeCall(ctx, "CategoriesService", "categories", method, cs.Observer)
+	ctx, cancel := withQueryTimeout(ctx, cs.QueryTimeout)
+
+	return ctx, func(err error) {
+		cancel()
+		end(err)
+	}
+}
+
+func (cs *CategoriesService) logger(ctx context.Context) *logrus.Entry {
+	return logging.FromContext(ctx, cs.Log)
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	custom_errors "simpler-products/errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// MethodObserver is notified when a service method returns, e.g. to record its duration
+type MethodObserver interface {
+	ObserveMethod(service, method string, duration time.Duration, err error)
+}
+
+// contextError replaces a storage error caused by a cancelled or expired context
+// with an error describing why the request was aborted
+func contextError(ctx context.Context, err error) error {
+	ctxErr := ctx.Err()
+	if ctxErr == nil {
+		return err
+	}
+
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, custom_errors.ErrServerShuttingDown):
+		return custom_errors.ErrServerShuttingDown
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		return custom_errors.ErrRequestTimeout
+	default:
+		return custom_errors.ErrRequestCanceled
+	}
+}
+
+// observeCall starts the span of a service method call, named after the service type, e.g.
+// ProductsService.GetAllProducts, and returns the function to call with its result, which
+// ends the span and notifies observer, if any
+func observeCall(ctx context.Context, serviceType, service, method string, observer MethodObserver) (context.Context, func(err error)) {
+	start := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, serviceType+"."+method)
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		if observer != nil {
+			observer.ObserveMethod(service, method, time.Since(start), err)
+		}
+	}
+}
+
+// withQueryTimeout bounds the storage calls made with ctx by timeout, zero disabling it
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"simpler-products/logging"
+	"simpler-products/migrations"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	HealthStatusUp           = "up"
+	HealthStatusDown         = "down"
+	HealthStatusReady        = "ready"
+	HealthStatusNotReady     = "not_ready"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+// HealthCheck probes a dependency; a failing critical check makes the service not ready
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+type ComponentHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components"`
+}
+
+type HealthServiceInterface interface {
+	CheckReadiness(ctx context.Context) (*HealthReport, bool)
+	MarkShuttingDown()
+}
+
+type HealthService struct {
+	Log *logrus.Logger
+	// Timeout bounds every individual check
+	Timeout time.Duration
+
+	mu           sync.RWMutex
+	checks       []HealthCheck
+	shuttingDown atomic.Bool
+}
+
+func (hs *HealthService) RegisterCheck(check HealthCheck) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.checks = append(hs.checks, check)
+}
+
+// MarkShuttingDown makes every following readiness check fail, so that load balancers
+// stop routing traffic while in-flight requests drain
+func (hs *HealthService) MarkShuttingDown() {
+	hs.shuttingDown.Store(true)
+}
+
+// CheckReadiness runs the registered checks concurrently and reports whether the service is ready
+func (hs *HealthService) CheckReadiness(ctx context.Context) (*HealthReport, bool) {
+	if hs.shuttingDown.Load() {
+		return &HealthReport{
+			Status:     HealthStatusShuttingDown,
+			Components: make([]ComponentHealth, 0),
+		}, false
+	}
+
+	hs.mu.RLock()
+	checks := make([]HealthCheck, len(hs.checks))
+	copy(checks, hs.checks)
+	hs.mu.RUnlock()
+
+	components := make([]ComponentHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			components[i] = hs.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, component := range components {
+		if component.Critical && component.Status != HealthStatusUp {
+			ready = false
+		}
+	}
+
+	report := &HealthReport{
+		Status:     HealthStatusReady,
+		Components: components,
+	}
+	if !ready {
+		report.Status = HealthStatusNotReady
+	}
+
+	return report, ready
+}
+
+func (hs *HealthService) run(ctx context.Context, check HealthCheck) ComponentHealth {
+	if hs.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hs.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := check.Check(ctx)
+	latency := time.Since(start)
+
+	component := ComponentHealth{
+		Name:      check.Name,
+		Status:    HealthStatusUp,
+		Critical:  check.Critical,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, hs.Log).Warnf("Health check %s failed: %v", check.Name, err)
+		component.Status = HealthStatusDown
+		component.Error = err.Error()
+	}
+
+	return component
+}
+
+// DatabaseState is the reachability of the database as last checked by its watchdog,
+// i.e. a database.Manager
+type DatabaseState interface {
+	Healthy() bool
+	LastError() error
+	LastCheck() time.Time
+}
+
+// DatabaseHealthCheck reports the state kept by the database watchdog rather than pinging the
+// database again, so probes never wait on an unreachable database
+func DatabaseHealthCheck(state DatabaseState) HealthCheck {
+	return HealthCheck{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			if state.Healthy() {
+				return nil
+			}
+
+			return fmt.Errorf("check at %s failed: %w", state.LastCheck().UTC().Format(time.RFC3339), state.LastError())
+		},
+	}
+}
+
+// MigrationsHealthCheck fails while embedded migrations are still pending
+func MigrationsHealthCheck(migrator *migrations.Migrator) HealthCheck {
+	return HealthCheck{
+		Name:     "migrations",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			// Probes only read, they never create the schema_migrations table
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d pending migrations", pending)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"simpler-products/allocation"
+	custom_errors "simpler-products/errors"
+	"simpler-products/logging"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// allocationAttempts bounds the attempts to reserve an allocation, which is planned again when
+// the stock changes between planning and reserving
+const allocationAttempts = 3
+
+type InventoryServiceInterface interface {
+	GetStock(ctx context.Context, productID string) (*models.Stock, error)
+	GetVariantStock(ctx context.Context, productID, variantID string) (*models.Stock, error)
+	MoveStock(ctx context.Context, movement models.StockMovement) (*models.StockMovement, error)
+	TransferStock(ctx context.Context, transfer models.StockTransfer) ([]models.StockMovement, error)
+	AllocateStock(ctx context.Context, productID string, request models.AllocationRequest) (*models.AllocationPlan, error)
+	GetStockMovements(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, int, error)
+}
+
+// InventoryService keeps track of the stock of products and of their variants in each
+// warehouse. Every change of stock is a movement, recorded in the ledger of the product.
+type InventoryService struct {
+	Repo       repositories.StockRepository
+	Warehouses repositories.WarehouseRepository
+	Variants   repositories.VariantRepository
+	Log        *logrus.Logger
+	// QueryTimeout bounds the storage calls made while serving a request, zero disables it
+	QueryTimeout time.Duration
+	// Observer, when set, is notified of the duration and outcome of every method call
+	Observer MethodObserver
+	// AllocationStrategy is the strategy of the allocation requests without one, by priority when empty
+	AllocationStrategy string
+	// AllocationSplit tells whether the allocation requests not telling otherwise may be split
+	AllocationSplit bool
+}
+
+func (is *InventoryService) GetStock(ctx context.Context, productID string) (stock *models.Stock, err error) {
+	ctx, end := is.begin(ctx, "GetStock")
+	defer func() { end(err) }()
+
+	log := is.logger(ctx)
+	log.Debugf("Fetching the stock of product with ID: %v", productID)
+
+	stock, err = is.Repo.Get(ctx, productID)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error fetching stock: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return stock, nil
+}
+
+func (is *InventoryService) GetVariantStock(ctx context.Context, productID, variantID string) (stock *models.Stock, err error) {
+	ctx, end := is.begin(ctx, "GetVariantStock")
+	defer func() { end(err) }()
+
+	log := is.logger(ctx)
+	log.Debugf("Fetching the stock of variant with ID: %v of product with ID: %v", variantID, productID)
+
+	if err := is.checkVariant(ctx, productID, variantID); err != nil {
+		return nil, err
+	}
+
+	stock, err = is.Repo.Get(ctx, productID)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error fetching stock: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	variantStock := stock.OfVariant(variantID)
+	return &variantStock, nil
+}
+
+func (is *InventoryService) MoveStock(ctx context.Context, movement models.StockMovement) (applied *models.StockMovement, err error) {
+	ctx, end := is.begin(ctx, "MoveStock")
+	defer func() { end(err) }()
+
+	log := is.logger(ctx)
+	log.Debugf("Moving the stock of product with ID: %v, movement: %s %d", movement.ProductID, movement.Type, movement.Quantity)
+
+	if movement.WarehouseID == "" {
+		movement.WarehouseID = models.DefaultWarehouseID
+	}
+	if err := is.checkVariant(ctx, movement.ProductID, movement.VariantID); err != nil {
+		return nil, err
+	}
+	movement.CreatedAt = time.Now().UTC()
+
+	movements, err := is.Repo.Move(ctx, movement.ProductID, []models.StockMovement{movement})
+	if err != nil {
+		if !isStockError(err) {
+			log.Errorf("Error moving stock: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return &movements[0], nil
+}
+
+func (is *InventoryService) TransferStock(ctx context.Context, transfer models.StockTransfer) (movements []models.StockMovement, err error) {
+	ctx, end := is.begin(ctx, "TransferStock")
+	defer func() { end(err) }()
+
+	log := is.logger(ctx)
+	log.Debugf("Transferring %d of product with ID: %v from warehouse %v to %v", transfer.Quantity, transfer.ProductID, transfer.From, transfer.To)
+
+	if err := is.checkVariant(ctx, transfer.ProductID, transfer.VariantID); err != nil {
+		return nil, err
+	}
+
+	// The paired movements of the transfer are applied together, or not at all
+	now := time.Now().UTC()
+	transferID := uuid.NewString()
+	movements, err = is.Repo.Move(ctx, transfer.ProductID, []models.StockMovement{
+		{VariantID: transfer.VariantID, WarehouseID: transfer.From, Type: models.StockTransferOut, Quantity: transfer.Quantity, TransferID: transferID, Reason: transfer.Reason, CreatedAt: now},
+		{VariantID: transfer.VariantID, WarehouseID: transfer.To, Type: models.StockTransferIn, Quantity: transfer.Quantity, TransferID: transferID, Reason: transfer.Reason, CreatedAt: now},
+	})
+	if err != nil {
+		if !isStockError(err) {
+			log.Errorf("Error transferring stock: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return movements, nil
+}
+
+func (is *InventoryService) AllocateStock(ctx context.Context, productID string, request models.AllocationRequest) (plan *models.AllocationPlan, err error) {
+	ctx, end := is.begin(ctx, "AllocateStock")
+	defer func() { end(err) }()
+
+	log := is.logger(ctx)
+	log.Debugf("Allocating %d of product with ID: %v, strategy: %q, region: %q", request.Quantity, productID, request.Strategy, request.Region)
+
+	if request.Strategy == "" {
+		request.Strategy = cmp.Or(is.AllocationStrategy, models.AllocatePriority)
+	}
+	if request.Split == nil {
+		request.Split = &is.AllocationSplit
+	}
+	if err := is.checkVariant(ctx, productID, request.VariantID); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		plan, err = is.allocate(ctx, productID, request)
+		// The stock planned from may have been reserved in the meantime
+		if errors.Is(err, custom_errors.ErrInsufficientStock) && request.Reserve && attempt < allocationAttempts && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			if !isStockError(err) {
+				log.Errorf("Error allocating stock: %v", err)
+			}
+			return nil, contextError(ctx, err)
+		}
+
+		return plan, nil
+	}
+}
+
+// allocate plans an allocation from the current stock, and reserves it when requested
+func (is *InventoryService) allocate(ctx context.Context, productID string, request models.AllocationRequest) (*models.AllocationPlan, error) {
+	stock, err := is.Repo.Get(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	warehouses, err := is.Warehouses.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations, err := allocation.Plan(warehouses, stock.OfVariant(request.VariantID).Warehouses, request)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &models.AllocationPlan{
+		ProductID:   productID,
+		VariantID:   request.VariantID,
+		Quantity:    request.Quantity,
+		Strategy:    request.Strategy,
+		Allocations: allocations,
+	}
+	if !request.Reserve {
+		return plan, nil
+	}
+
+	now := time.Now().UTC()
+	reservations := make([]models.StockMovement, 0, len(allocations))
+	for _, allocated := range allocations {
+		reservations = append(reservations, models.StockMovement{
+			VariantID:   request.VariantID,
+			WarehouseID: allocated.WarehouseID,
+			Type:        models.StockReserve,
+			Quantity:    allocated.Quantity,
+			Reason:      request.Reason,
+			CreatedAt:   now,
+		})
+	}
+	if _, err := is.Repo.Move(ctx, productID, reservations); err != nil {
+		return nil, err
+	}
+	plan.Reserved = true
+
+	return plan, nil
+}
+
+func (is *InventoryService) GetStockMovements(ctx context.Context, productID string, limit, offset int) (movements []models.StockMovement, total int, err error) {
+	ctx, end := is.begin(ctx, "GetStockMovements")
+	defer func() { end(err) }()
+
+	log := is.logger(ctx)
+	log.Debugf("Fetching the stock movements of product with ID: %v, limit: %d, offset: %d", productID, limit, offset)
+
+	// The ledger of a missing product is not found rather than empty
+	if _, err := is.Repo.Get(ctx, productID); err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error fetching stock: %v", err)
+		}
+		return nil, 0, contextError(ctx, err)
+	}
+
+	total, err = is.Repo.CountMovements(ctx, productID)
+	if err != nil {
+		log.Errorf("Error counting stock movements: %v", err)
+		return nil, 0, contextError(ctx, err)
+	}
+
+	movements, err = is.Repo.Movements(ctx, productID, limit, offset)
+	if err != nil {
+		log.Errorf("Error fetching stock movements: %v", err)
+		return nil, 0, contextError(ctx, err)
+	}
+
+	return movements, total, nil
+}
+
+// checkVariant returns ErrVariantNotFound when variantID is set and is not a variant of the
+// product
+func (is *InventoryService) checkVariant(ctx context.Context, productID, variantID string) error {
+	if variantID == "" {
+		return nil
+	}
+
+	if _, err := is.Variants.Get(ctx, productID, variantID); err != nil {
+		if !errors.Is(err, custom_errors.ErrVariantNotFound) {
+			is.logger(ctx).Errorf("Error fetching variant: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+// isStockError reports whether err is the expected outcome of a movement, not a failure
+func isStockError(err error) bool {
+	return errors.Is(err, custom_errors.ErrProductNotFound) ||
+		errors.Is(err, custom_errors.ErrWarehouseNotFound) ||
+		errors.Is(err, custom_errors.ErrVariantNotFound) ||
+		errors.Is(err, custom_errors.ErrNoSingleWarehouse) ||
+		errors.Is(err, custom_errors.ErrInsufficientStock) ||
+		errors.Is(err, custom_errors.ErrInsufficientReservedStock)
+}
+
+// begin prepares the context of a service call, as ProductsService.begin does
+func (is *InventoryService) begin(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, end := observeCall(ctx, "InventoryService", "inventory", method, is.Observer)
+	ctx, cancel := withQueryTimeout(ctx, is.QueryTimeout)
+
+	return ctx, func(err error) {
+		cancel()
+		end(err)
+	}
+}
+
+func (is *InventoryService) logger(ctx context.Context) *logrus.Entry {
+	return logging.FromContext(ctx, is.Log)
+}
@@ -1,130 +1,260 @@
 package services
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	custom_errors "simpler-products/errors"
+	"simpler-products/logging"
 	"simpler-products/models"
+	"simpler-products/repositories"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
+const tracerName = "simpler-products/services"
+
 type ProductsServiceInterface interface {
-	GetAllProducts(limit, offset int) ([]models.Product, int, error)
-	GetProductById(id string) (*models.Product, error)
-	AddProduct(product *models.Product) error
-	UpdateProduct(id string, product *models.Product) (*models.Product, error)
-	DeleteProduct(id string) error
+	GetAllProducts(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error)
+	GetProductFacets(ctx context.Context, filter models.ProductFilter, query models.FacetQuery) (*models.Facets, error)
+	GetProductById(ctx context.Context, id string) (*models.Product, error)
+	AddProduct(ctx context.Context, product *models.Product) error
+	UpdateProduct(ctx context.Context, id string, product *models.Product) (*models.Product, error)
+	PatchProduct(ctx context.Context, id string, patch models.ProductPatch) (*models.Product, error)
+	DeleteProduct(ctx context.Context, id string, version int64) error
+	BulkProducts(ctx context.Context, operations []models.BulkOperation, atomic bool) ([]models.BulkResult, error)
+	ExportProducts(ctx context.Context, filter models.ProductFilter, sort []models.SortField, fn func(product models.Product) error) error
 }
 
 type ProductsService struct {
-	DB  *sql.DB
-	Log *logrus.Logger
+	Repo repositories.ProductRepository
+	Log  *logrus.Logger
+	// QueryTimeout bounds the storage calls made while serving a request, zero disables it
+	QueryTimeout time.Duration
+	// Observer, when set, is notified of the duration and outcome of every method call
+	Observer MethodObserver
+	// Index, when set, is updated with every product written
+	Index SearchIndex
+	// Categories resolves the category filter of listings into the categories it covers
+	Categories repositories.CategoryRepository
 }
 
-func (ps *ProductsService) GetAllProducts(limit, offset int) ([]models.Product, int, error) {
-	ps.Log.Debugf("Fetching products from database, limit: %d, offset: %d", limit, offset)
+func (ps *ProductsService) GetAllProducts(ctx context.Context, query models.ProductQuery) (products []models.Product, totalCount int, err error) {
+	ctx, end := ps.begin(ctx, "GetAllProducts")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Fetching products from database, limit: %d, offset: %d, sort: %v", query.Limit, query.Offset, query.Sort)
 
-	// 1. Get the total count of products
-	var totalCount int
-	err := ps.DB.QueryRow("SELECT COUNT(*) FROM Products").Scan(&totalCount)
+	query.Filter, err = ps.resolveCategory(ctx, query.Filter)
 	if err != nil {
-		ps.Log.Errorf("Error getting total product count: %v", err)
 		return nil, 0, err
 	}
 
+	// 1. Get the total count of the products matching the filters
+	totalCount, err = ps.Repo.Count(ctx, query.Filter)
+	if err != nil {
+		log.Errorf("Error getting total product count: %v", err)
+		return nil, 0, contextError(ctx, err)
+	}
+
 	// 2. Fetch paginated products
-	rows, err := ps.DB.Query("SELECT * FROM Products LIMIT ? OFFSET ?", limit, offset)
+	products, err = ps.Repo.List(ctx, query)
 	if err != nil {
-		ps.Log.Errorf("Error fetching products: %v", err)
-		return nil, 0, err
+		log.Errorf("Error fetching products: %v", err)
+		return nil, 0, contextError(ctx, err)
+	}
+
+	return products, totalCount, nil
+}
+
+func (ps *ProductsService) GetProductFacets(ctx context.Context, filter models.ProductFilter, query models.FacetQuery) (facets *models.Facets, err error) {
+	ctx, end := ps.begin(ctx, "GetProductFacets")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Computing product facets, filter: %+v", filter)
+
+	filter, err = ps.resolveCategory(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
-	defer rows.Close()
 
-	products := make([]models.Product, 0)
-	for rows.Next() {
-		var product models.Product
-		if err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.Price); err != nil {
-			ps.Log.Errorf("Error scanning product row: %v", err)
-			return nil, 0, err
+	facets = &models.Facets{}
+	if query.Price != nil {
+		facets.Price, err = ps.Repo.PriceFacet(ctx, filter, *query.Price)
+		if err != nil {
+			log.Errorf("Error computing price facet: %v", err)
+			return nil, contextError(ctx, err)
 		}
-		products = append(products, product)
 	}
 
-	return products, totalCount, nil
+	return facets, nil
 }
 
-func (ps *ProductsService) GetProductById(id string) (*models.Product, error) {
-	ps.Log.Debugf("Fetching product with ID: %v from database", id)
+func (ps *ProductsService) GetProductById(ctx context.Context, id string) (product *models.Product, err error) {
+	ctx, end := ps.begin(ctx, "GetProductById")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Fetching product with ID: %v from database", id)
 
-	var product models.Product
-	err := ps.DB.QueryRow("SELECT * FROM Products WHERE id = ?", id).Scan(&product.ID, &product.Name, &product.Description, &product.Price)
+	product, err = ps.Repo.Get(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, custom_errors.ErrProductNotFound
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error fetching product: %v", err)
 		}
-		ps.Log.Errorf("Error fetching product: %v", err)
-		return nil, err
+		return nil, contextError(ctx, err)
 	}
 
-	return &product, nil
+	return product, nil
 }
 
-func (ps *ProductsService) AddProduct(product *models.Product) error {
-	ps.Log.Debugf("Creating new product in database, data: %+v", product)
+func (ps *ProductsService) AddProduct(ctx context.Context, product *models.Product) (err error) {
+	ctx, end := ps.begin(ctx, "AddProduct")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Creating new product in database, data: %+v", product)
 
-	uuid := uuid.NewString()
-	_, err := ps.DB.Exec("INSERT INTO Products (id, name, description, price) VALUES (?, ?, ?, ?)", uuid, product.Name, product.Description, product.Price)
+	createdProduct, err := ps.Repo.Insert(ctx, uuid.NewString(), product)
 	if err != nil {
-		ps.Log.Errorf("Error creating new product: %v", err)
-		return err
+		log.Errorf("Error creating new product: %v", err)
+		return contextError(ctx, err)
 	}
 
-	product.ID = uuid
+	*product = *createdProduct
+	if ps.Index != nil {
+		ps.Index.Add(*createdProduct)
+	}
 
 	return nil
 }
 
-func (ps *ProductsService) UpdateProduct(id string, product *models.Product) (*models.Product, error) {
-	ps.Log.Debugf("Updating product with ID: %v in database, data: %+v", id, product)
+func (ps *ProductsService) UpdateProduct(ctx context.Context, id string, product *models.Product) (updatedProduct *models.Product, err error) {
+	ctx, end := ps.begin(ctx, "UpdateProduct")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Updating product with ID: %v in database, data: %+v", id, product)
 
-	_, err := ps.DB.Exec("UPDATE Products SET name = ?, description = ?, price = ? WHERE id = ?", product.Name, product.Description, product.Price, id)
+	updatedProduct, err = ps.Repo.Update(ctx, id, product)
 	if err != nil {
-		ps.Log.Errorf("Error updating product: %v", err)
-		return nil, err
+		if !errors.Is(err, custom_errors.ErrProductNotFound) && !errors.Is(err, custom_errors.ErrVersionMismatch) {
+			log.Errorf("Error updating product: %v", err)
+		}
+		return nil, contextError(ctx, err)
 	}
 
-	// Fetch the updated product from the database
-	updatedProduct, err := ps.GetProductById(id)
+	if ps.Index != nil {
+		ps.Index.Add(*updatedProduct)
+	}
+
+	return updatedProduct, nil
+}
+
+// PatchProduct writes only the fields set in patch, leaving the others as they are stored
+func (ps *ProductsService) PatchProduct(ctx context.Context, id string, patch models.ProductPatch) (patchedProduct *models.Product, err error) {
+	ctx, end := ps.begin(ctx, "PatchProduct")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Patching product with ID: %v in database, fields: %v", id, patch.Fields())
+
+	patchedProduct, err = ps.Repo.Patch(ctx, id, patch)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, custom_errors.ErrProductNotFound
+		if !errors.Is(err, custom_errors.ErrProductNotFound) && !errors.Is(err, custom_errors.ErrVersionMismatch) {
+			log.Errorf("Error patching product: %v", err)
 		}
-		ps.Log.Errorf("Error updating product: %v", err)
-		return nil, err
+		return nil, contextError(ctx, err)
 	}
 
-	return updatedProduct, nil
+	if ps.Index != nil && !patch.IsEmpty() {
+		ps.Index.Add(*patchedProduct)
+	}
+
+	return patchedProduct, nil
 }
 
-func (ps *ProductsService) DeleteProduct(id string) error {
-	ps.Log.Debugf("Deleting product with ID: %v from database", id)
+// DeleteProduct deletes the product, only when it is at the given version unless that is zero
+func (ps *ProductsService) DeleteProduct(ctx context.Context, id string, version int64) (err error) {
+	ctx, end := ps.begin(ctx, "DeleteProduct")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Deleting product with ID: %v from database", id)
 
 	// Fetch the product to be deleted from the database
-	_, err := ps.GetProductById(id)
+	_, err = ps.Repo.Get(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return custom_errors.ErrProductNotFound
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error deleting product: %v", err)
 		}
-		ps.Log.Errorf("Error deleting product: %v", err)
-		return err
+		return contextError(ctx, err)
 	}
 
-	_, err = ps.DB.Exec("DELETE FROM Products WHERE id = ?", id)
-	if err != nil {
-		ps.Log.Errorf("Error deleting product: %v", err)
-		return err
+	if err = ps.Repo.Delete(ctx, id, version); err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) && !errors.Is(err, custom_errors.ErrVersionMismatch) {
+			log.Errorf("Error deleting product: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	if ps.Index != nil {
+		ps.Index.Remove(id)
 	}
 
 	return nil
 }
+
+// resolveCategory sets the CategoryIDs of filter to its category and the descendants of it
+func (ps *ProductsService) resolveCategory(ctx context.Context, filter models.ProductFilter) (models.ProductFilter, error) {
+	if filter.Category == "" {
+		return filter, nil
+	}
+	if ps.Categories == nil {
+		return filter, custom_errors.ErrCategoryNotFound
+	}
+
+	categories, err := ps.Categories.List(ctx)
+	if err != nil {
+		ps.logger(ctx).Errorf("Error fetching categories: %v", err)
+		return filter, contextError(ctx, err)
+	}
+	if !slices.ContainsFunc(categories, func(category models.Category) bool { return category.ID == filter.Category }) {
+		return filter, custom_errors.ErrCategoryNotFound
+	}
+
+	filter.CategoryIDs = models.Descendants(categories, filter.Category)
+
+	return filter, nil
+}
+
+// begin prepares the context of a service call, starting a span for it, and returns
+// the function to call with its result
+func (ps *ProductsService) begin(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, end := ps.observe(ctx, method)
+	ctx, cancel := ps.withTimeout(ctx)
+
+	return ctx, func(err error) {
+		cancel()
+		end(err)
+	}
+}
+
+// observe is begin without QueryTimeout, for methods lasting as long as the client reads
+func (ps *ProductsService) observe(ctx context.Context, method string) (context.Context, func(err error)) {
+	return observeCall(ctx, "ProductsService", "products", method, ps.Observer)
+}
+
+// logger returns the request-scoped entry of ctx, so that logs are correlated with the
+// request ID and the current trace
+func (ps *ProductsService) logger(ctx context.Context) *logrus.Entry {
+	return logging.FromContext(ctx, ps.Log)
+}
+
+func (ps *ProductsService) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withQueryTimeout(ctx, ps.QueryTimeout)
+}
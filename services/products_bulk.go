@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/repositories"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// BulkProducts applies the operations in order and returns their results in the same order.
+// When atomic is set they run in a single transaction: the first failing operation rolls
+// back the ones before it, the ones after it are not attempted, and all of them but the
+// failing one fail with ErrBulkRolledBack. Otherwise every operation is applied on its own.
+// The error is only set when the request fails as a whole, e.g. when it is canceled.
+func (ps *ProductsService) BulkProducts(ctx context.Context, operations []models.BulkOperation, atomic bool) (results []models.BulkResult, err error) {
+	ctx, end := ps.begin(ctx, "BulkProducts")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Applying %d bulk operations, atomic: %v", len(operations), atomic)
+
+	results = make([]models.BulkResult, len(operations))
+	if !atomic {
+		for i, operation := range operations {
+			results[i] = applyBulkOperation(ctx, ps.Repo, operation)
+			if ctx.Err() != nil {
+				return nil, contextError(ctx, ctx.Err())
+			}
+		}
+		logBulkErrors(log, operations, results)
+		ps.indexBulkResults(operations, results)
+
+		return results, nil
+	}
+
+	failed := -1
+	err = ps.Repo.InTransaction(ctx, func(repo repositories.ProductRepository) error {
+		for i, operation := range operations {
+			results[i] = applyBulkOperation(ctx, repo, operation)
+			if results[i].Err != nil {
+				failed = i
+				return results[i].Err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// The transaction itself failed, or the request was canceled
+		if failed < 0 || ctx.Err() != nil {
+			log.Errorf("Error applying bulk operations: %v", err)
+			return nil, contextError(ctx, err)
+		}
+
+		logBulkErrors(log, operations, results)
+		for i := range results {
+			if i != failed {
+				results[i] = models.BulkResult{Err: custom_errors.ErrBulkRolledBack}
+			}
+		}
+
+		return results, nil
+	}
+
+	ps.indexBulkResults(operations, results)
+
+	return results, nil
+}
+
+// applyBulkOperation applies a single bulk operation with repo
+func applyBulkOperation(ctx context.Context, repo repositories.ProductRepository, operation models.BulkOperation) models.BulkResult {
+	var product *models.Product
+	var err error
+
+	switch operation.Op {
+	case models.BulkCreate:
+		product, err = repo.Insert(ctx, uuid.NewString(), operation.Product)
+	case models.BulkUpdate:
+		update := *operation.Product
+		update.Version = operation.Version
+		product, err = repo.Update(ctx, operation.ID, &update)
+	case models.BulkUpsert:
+		product, err = repo.Update(ctx, operation.ID, operation.Product)
+		if errors.Is(err, custom_errors.ErrProductNotFound) {
+			product, err = repo.Insert(ctx, operation.ID, operation.Product)
+		}
+	case models.BulkDelete:
+		// Not every backend reports deleting a missing product
+		if _, err = repo.Get(ctx, operation.ID); err == nil {
+			err = repo.Delete(ctx, operation.ID, operation.Version)
+		}
+	default:
+		err = fmt.Errorf("unknown bulk operation %q", operation.Op)
+	}
+
+	return models.BulkResult{Product: product, Err: err}
+}
+
+// logBulkErrors logs the operations that failed for other reasons than a missing or modified product
+func logBulkErrors(log *logrus.Entry, operations []models.BulkOperation, results []models.BulkResult) {
+	for i, result := range results {
+		if result.Err != nil && !errors.Is(result.Err, custom_errors.ErrProductNotFound) && !errors.Is(result.Err, custom_errors.ErrVersionMismatch) {
+			log.Errorf("Error applying bulk operation %d (%s): %v", i, operations[i].Op, result.Err)
+		}
+	}
+}
+
+// indexBulkResults updates the search index with the applied operations
+func (ps *ProductsService) indexBulkResults(operations []models.BulkOperation, results []models.BulkResult) {
+	if ps.Index == nil {
+		return
+	}
+
+	for i, result := range results {
+		switch {
+		case result.Err != nil:
+		case operations[i].Op == models.BulkDelete:
+			ps.Index.Remove(operations[i].ID)
+		default:
+			ps.Index.Add(*result.Product)
+		}
+	}
+}
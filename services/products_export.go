@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"simpler-products/models"
+)
+
+// exportBatchSize is the number of products read at once while exporting
+const exportBatchSize = 500
+
+// ExportProducts calls fn with every product matching filter, in the order of sort. Products
+// are read in keyset-paginated batches, each bounded by QueryTimeout, so that no connection is
+// held while fn, e.g. writing to the client, consumes them, however long the export lasts.
+func (ps *ProductsService) ExportProducts(ctx context.Context, filter models.ProductFilter, sort []models.SortField, fn func(product models.Product) error) (err error) {
+	ctx, end := ps.observe(ctx, "ExportProducts")
+	defer func() { end(err) }()
+
+	log := ps.logger(ctx)
+	log.Debugf("Exporting products, filter: %+v, sort: %v", filter, sort)
+
+	filter, err = ps.resolveCategory(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	exported := 0
+	query := models.ProductQuery{Filter: filter, Sort: sort, Limit: exportBatchSize}
+	for {
+		products, err := ps.exportBatch(ctx, query)
+		if err != nil {
+			log.Errorf("Error exporting products after %d products: %v", exported, err)
+			return err
+		}
+
+		for _, product := range products {
+			if err := fn(product); err != nil {
+				log.Errorf("Error exporting products after %d products: %v", exported, err)
+				return contextError(ctx, err)
+			}
+			exported++
+		}
+
+		if len(products) < query.Limit {
+			break
+		}
+
+		// Resume after the last product exported, whatever has been written meanwhile
+		keyset := models.KeysetOf(products[len(products)-1], false)
+		query.Keyset = &keyset
+	}
+
+	log.Debugf("Exported %d products", exported)
+
+	return nil
+}
+
+// exportBatch reads a batch of the products of an export, within QueryTimeout
+func (ps *ProductsService) exportBatch(ctx context.Context, query models.ProductQuery) ([]models.Product, error) {
+	ctx, cancel := ps.withTimeout(ctx)
+	defer cancel()
+
+	products, err := ps.Repo.List(ctx, query)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return products, nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"simpler-products/logging"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/search"
+
+	"github.com/sirupsen/logrus"
+)
+
+// indexBatchSize is the number of products read at once while building the search index
+const indexBatchSize = 500
+
+// DefaultMaxSuggestions is the number of suggestions returned at most, unless configured otherwise
+const DefaultMaxSuggestions = 10
+
+type SearchServiceInterface interface {
+	SearchProducts(ctx context.Context, query string, limit, offset int) ([]search.Hit, int, error)
+	SearchFacets(ctx context.Context, query string, facets models.FacetQuery) (*models.Facets, error)
+	SuggestProducts(ctx context.Context, prefix string, limit int, fuzzy bool) ([]search.Suggestion, error)
+}
+
+// SearchIndex is kept in sync with the products written through ProductsService
+type SearchIndex interface {
+	Add(product models.Product)
+	Remove(id string)
+}
+
+// SearchService answers full-text searches from an in-process index. Each process keeps
+// its own index, so it only sees the writes made through its own ProductsService.
+type SearchService struct {
+	Index *search.Index
+	Log   *logrus.Logger
+
+	// MaxSuggestions caps the number of suggestions, DefaultMaxSuggestions when zero
+	MaxSuggestions int
+}
+
+func (ss *SearchService) SearchProducts(ctx context.Context, query string, limit, offset int) ([]search.Hit, int, error) {
+	logging.FromContext(ctx, ss.Log).Debugf("Searching products, query: %q, limit: %d, offset: %d", query, limit, offset)
+
+	if err := ctx.Err(); err != nil {
+		return nil, 0, contextError(ctx, err)
+	}
+
+	hits, total := ss.Index.Search(query, limit, offset)
+
+	return hits, total, nil
+}
+
+// SearchFacets summarizes all the products matching query, not only a page of them
+func (ss *SearchService) SearchFacets(ctx context.Context, query string, facets models.FacetQuery) (*models.Facets, error) {
+	logging.FromContext(ctx, ss.Log).Debugf("Computing search facets, query: %q", query)
+
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return ss.Index.Facets(query, facets), nil
+}
+
+// SuggestProducts returns product names completing prefix. A limit of zero, or above the
+// configured maximum, returns the maximum number of suggestions.
+func (ss *SearchService) SuggestProducts(ctx context.Context, prefix string, limit int, fuzzy bool) ([]search.Suggestion, error) {
+	logging.FromContext(ctx, ss.Log).Debugf("Suggesting products, prefix: %q, limit: %d, fuzzy: %t", prefix, limit, fuzzy)
+
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	maxSuggestions := ss.MaxSuggestions
+	if maxSuggestions <= 0 {
+		maxSuggestions = DefaultMaxSuggestions
+	}
+	if limit <= 0 || limit > maxSuggestions {
+		limit = maxSuggestions
+	}
+
+	return ss.Index.Suggest(prefix, limit, fuzzy), nil
+}
+
+// BuildIndex loads every product of repo into the index, and returns how many there are
+func (ss *SearchService) BuildIndex(ctx context.Context, repo repositories.ProductRepository) (int, error) {
+	indexed := 0
+	query := models.ProductQuery{Limit: indexBatchSize}
+
+	for {
+		products, err := repo.List(ctx, query)
+		if err != nil {
+			return indexed, err
+		}
+
+		for _, product := range products {
+			ss.Index.Add(product)
+		}
+		indexed += len(products)
+
+		if len(products) < query.Limit {
+			return indexed, nil
+		}
+
+		// Resume after the last product read, whatever has been written meanwhile
+		keyset := models.KeysetOf(products[len(products)-1], false)
+		query.Keyset = &keyset
+	}
+}
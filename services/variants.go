@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"errors"
+	custom_errors "simpler-products/errors"
+	"simpler-products/logging"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type VariantsServiceInterface interface {
+	GetProductOptions(ctx context.Context, productID string) ([]models.ProductOption, error)
+	SetProductOptions(ctx context.Context, productID string, options []models.ProductOption) ([]models.ProductOption, error)
+	ListVariants(ctx context.Context, productID string) ([]models.Variant, error)
+	GetVariant(ctx context.Context, productID, id string) (*models.Variant, error)
+	AddVariant(ctx context.Context, productID string, variant *models.Variant) error
+	GenerateVariants(ctx context.Context, productID, skuPrefix string, price *float64) ([]models.Variant, error)
+	UpdateVariant(ctx context.Context, productID, id string, variant *models.Variant) (*models.Variant, error)
+	DeleteVariant(ctx context.Context, productID, id string) error
+}
+
+// VariantsService manages the options of products and the variants combining their values
+type VariantsService struct {
+	Repo     repositories.VariantRepository
+	Products repositories.ProductRepository
+	Stock    repositories.StockRepository
+	Log      *logrus.Logger
+	// QueryTimeout bounds the storage calls made while serving a request, zero disables it
+	QueryTimeout time.Duration
+	// Observer, when set, is notified of the duration and outcome of every method call
+	Observer MethodObserver
+}
+
+func (vs *VariantsService) GetProductOptions(ctx context.Context, productID string) (options []models.ProductOption, err error) {
+	ctx, end := vs.begin(ctx, "GetProductOptions")
+	defer func() { end(err) }()
+
+	log := vs.logger(ctx)
+	log.Debugf("Fetching the options of product with ID: %v", productID)
+
+	if err := vs.checkProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	options, err = vs.Repo.Options(ctx, productID)
+	if err != nil {
+		log.Errorf("Error fetching product options: %v", err)
+		return nil, contextError(ctx, err)
+	}
+
+	return options, nil
+}
+
+func (vs *VariantsService) SetProductOptions(ctx context.Context, productID string, options []models.ProductOption) (_ []models.ProductOption, err error) {
+	ctx, end := vs.begin(ctx, "SetProductOptions")
+	defer func() { end(err) }()
+
+	log := vs.logger(ctx)
+	log.Debugf("Setting the options of product with ID: %v, options: %+v", productID, options)
+
+	if err := vs.checkProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	// Options are never changed from under the variants using them
+	if err := vs.Repo.SetOptions(ctx, productID, options); err != nil {
+		if !errors.Is(err, custom_errors.ErrOptionsInUse) {
+			log.Errorf("Error setting product options: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return options, nil
+}
+
+func (vs *VariantsService) ListVariants(ctx context.Context, productID string) (variants []models.Variant, err error) {
+	ctx, end := vs.begin(ctx, "ListVariants")
+	defer func() { end(err) }()
+
+	log := vs.logger(ctx)
+	log.Debugf("Fetching the variants of product with ID: %v", productID)
+
+	if err := vs.checkProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	variants, err = vs.Repo.List(ctx, productID)
+	if err != nil {
+		log.Errorf("Error fetching variants: %v", err)
+		return nil, contextError(ctx, err)
+	}
+
+	return variants, nil
+}
+
+func (vs *VariantsService) GetVariant(ctx context.Context, productID, id string) (variant *models.Variant, err error) {
+	ctx, end := vs.begin(ctx, "GetVariant")
+	defer func() { end(err) }()
+
+	log := vs.logger(ctx)
+	log.Debugf("Fetching variant with ID: %v of product with ID: %v", id, productID)
+
+	if err := vs.checkProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	variant, err = vs.Repo.Get(ctx, productID, id)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrVariantNotFound) {
+			log.Errorf("Error fetching variant: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return variant, nil
+}
+
+func (vs *VariantsService) AddVariant(ctx context.Context, productID string, variant *models.Variant) (err error) {
+	ctx, end := vs.begin(ctx, "AddVariant")
+	defer func() { end(err) }()
+
+	log := vs.logger(ctx)
+	log.Debugf("Creating new variant of product with ID: %v, data: %+v", productID, variant)
+
+	variant.ID = uuid.NewString()
+	variant.ProductID = productID
+	if err := vs.checkOptions(ctx, *variant); err != nil {
+		return err
+	}
+
+	if err := vs.Repo.Insert(ctx, []models.Variant{*variant}); err != nil {
+		if !isVariantError(err) {
+			log.Errorf("Error creating new variant: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+// GenerateVariants creates a variant for every combination of the option values of the
+// product without one yet, priced at price when set. Their SKU is made of skuPrefix, or of
+// the name of the product when empty, followed by their option values. Options with more than
+// models.MaxVariantCombinations combinations are refused rather than built in memory.
+func (vs *VariantsService) GenerateVariants(ctx context.Context, productID, skuPrefix string, price *float64) (generated []models.Variant, err error) {
+	ctx, end := vs.begin(ctx, "GenerateVariants")
+	defer func() { end(err) }()
+
+	log := vs.logger(ctx)
+	log.Debugf("Generating the variants of product with ID: %v", productID)
+
+	product, err := vs.Products.Get(ctx, productID)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error fetching product: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+	if skuPrefix == "" {
+		skuPrefix = models.SKUSegment(product.Name)
+	}
+	// Names without letters or digits fall back to the product ID
+	if skuPrefix == "" {
+		skuPrefix = models.SKUSegment(productID)
+	}
+
+	options, err := vs.Repo.Options(ctx, productID)
+	if err != nil {
+		log.Errorf("Error fetching product options: %v", err)
+		return nil, contextError(ctx, err)
+	}
+	if models.CountCombinations(options) > models.MaxVariantCombinations {
+		return nil, custom_errors.ErrTooManyVariants
+	}
+
+	variants, err := vs.Repo.List(ctx, productID)
+	if err != nil {
+		log.Errorf("Error fetching variants: %v", err)
+		return nil, contextError(ctx, err)
+	}
+	existing := make(map[string]bool, len(variants))
+	skus := make(map[string]bool, len(variants))
+	for _, variant := range variants {
+		existing[variant.OptionsKey()] = true
+		skus[variant.SKU] = true
+	}
+
+	generated = make([]models.Variant, 0)
+	for _, combination := range models.Combinations(options) {
+		variant := models.Variant{ID: uuid.NewString(), ProductID: productID, Options: combination, Price: price}
+		if existing[variant.OptionsKey()] {
+			continue
+		}
+
+		segments := []string{skuPrefix}
+		for _, option := range options {
+			segments = append(segments, models.SKUSegment(combination[option.Name]))
+		}
+		// Values differing only by punctuation, e.g. "S/M" and "S M", share their segments,
+		// the SKUs taken are told apart by a counter
+		variant.SKU = models.GenerateSKU(segments...)
+		for n := 2; skus[variant.SKU]; n++ {
+			variant.SKU = models.GenerateSKU(append(segments, strconv.Itoa(n))...)
+		}
+		skus[variant.SKU] = true
+		generated = append(generated, variant)
+	}
+
+	if err := vs.Repo.Insert(ctx, generated); err != nil {
+		if !isVariantError(err) {
+			log.Errorf("Error generating variants: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return generated, nil
+}
+
+func (vs *VariantsService) UpdateVariant(ctx context.Context, productID, id string, variant *models.Variant) (_ *models.Variant, err error) {
+	ctx, end := vs.begin(ctx, "UpdateVariant")
+	defer func() { end(err) }()
+
+	log := vs.logger(ctx)
+	log.Debugf("Updating variant with ID: %v of product with ID: %v, data: %+v", id, productID, variant)
+
+	if _, err := vs.Repo.Get(ctx, productID, id); err != nil {
+		if !errors.Is(err, custom_errors.ErrVariantNotFound) {
+			log.Errorf("Error fetching variant: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	variant.ID = id
+	variant.ProductID = productID
+	if err := vs.checkOptions(ctx, *variant); err != nil {
+		return nil, err
+	}
+
+	if err := vs.Repo.Update(ctx, *variant); err != nil {
+		if !isVariantError(err) {
+			log.Errorf("Error updating variant: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return variant, nil
+}
+
+func (vs *VariantsService) DeleteVariant(ctx context.Context, productID, id string) (err error) {
+	ctx, end := vs.begin(ctx, "DeleteVariant")
+	defer func() { end(err) }()
+
+	log := vs.logger(ctx)
+	log.Debugf("Deleting variant with ID: %v of product with ID: %v", id, productID)
+
+	if _, err := vs.Repo.Get(ctx, productID, id); err != nil {
+		if !errors.Is(err, custom_errors.ErrVariantNotFound) {
+			log.Errorf("Error fetching variant: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	// Stock is never dropped along with its variant, it is shipped or adjusted first
+	stock, err := vs.Stock.Get(ctx, productID)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			log.Errorf("Error fetching stock: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+	if variantStock := stock.OfVariant(id); variantStock.OnHand > 0 || variantStock.Reserved > 0 {
+		return custom_errors.ErrVariantNotEmpty
+	}
+
+	if err := vs.Repo.Delete(ctx, productID, id); err != nil {
+		if !errors.Is(err, custom_errors.ErrVariantNotFound) {
+			log.Errorf("Error deleting variant: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+// checkProduct returns ErrProductNotFound when there is no product with the given ID
+func (vs *VariantsService) checkProduct(ctx context.Context, productID string) error {
+	if _, err := vs.Products.Get(ctx, productID); err != nil {
+		if !errors.Is(err, custom_errors.ErrProductNotFound) {
+			vs.logger(ctx).Errorf("Error fetching product: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+// checkOptions checks that the product of the variant exists, and that the variant holds a
+// value of each of its options
+func (vs *VariantsService) checkOptions(ctx context.Context, variant models.Variant) error {
+	if err := vs.checkProduct(ctx, variant.ProductID); err != nil {
+		return err
+	}
+
+	options, err := vs.Repo.Options(ctx, variant.ProductID)
+	if err != nil {
+		vs.logger(ctx).Errorf("Error fetching product options: %v", err)
+		return contextError(ctx, err)
+	}
+	if !variant.Matches(options) {
+		return custom_errors.ErrInvalidVariantOptions
+	}
+
+	return nil
+}
+
+// isVariantError reports whether err is the expected outcome of storing a variant, not a failure
+func isVariantError(err error) bool {
+	return errors.Is(err, custom_errors.ErrProductNotFound) ||
+		errors.Is(err, custom_errors.ErrVariantNotFound) ||
+		errors.Is(err, custom_errors.ErrDuplicateSKU) ||
+		errors.Is(err, custom_errors.ErrDuplicateVariant)
+}
+
+// begin prepares the context of a service call, as ProductsService.begin does
+func (vs *VariantsService) begin(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, end := observeCall(ctx, "VariantsService", "variants", method, vs.Observer)
+	ctx, cancel := withQueryTimeout(ctx, vs.QueryTimeout)
+
+	return ctx, func(err error) {
+		cancel()
+		end(err)
+	}
+}
+
+func (vs *VariantsService) logger(ctx context.Context) *logrus.Entry {
+	return logging.FromContext(ctx, vs.Log)
+}
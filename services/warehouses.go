@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	custom_errors "simpler-products/errors"
+	"simpler-products/logging"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type WarehousesServiceInterface interface {
+	ListWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	GetWarehouse(ctx context.Context, id string) (*models.Warehouse, error)
+	AddWarehouse(ctx context.Context, warehouse *models.Warehouse) error
+	UpdateWarehouse(ctx context.Context, id string, warehouse *models.Warehouse) (*models.Warehouse, error)
+	DeleteWarehouse(ctx context.Context, id string) error
+}
+
+// WarehousesService manages the warehouses products are stocked in
+type WarehousesService struct {
+	Repo  repositories.WarehouseRepository
+	Stock repositories.StockRepository
+	Log   *logrus.Logger
+	// QueryTimeout bounds the storage calls made while serving a request, zero disables it
+	QueryTimeout time.Duration
+	// Observer, when set, is notified of the duration and outcome of every method call
+	Observer MethodObserver
+}
+
+func (ws *WarehousesService) ListWarehouses(ctx context.Context) (warehouses []models.Warehouse, err error) {
+	ctx, end := ws.begin(ctx, "ListWarehouses")
+	defer func() { end(err) }()
+
+	log := ws.logger(ctx)
+	log.Debug("Fetching warehouses from database")
+
+	warehouses, err = ws.Repo.List(ctx)
+	if err != nil {
+		log.Errorf("Error fetching warehouses: %v", err)
+		return nil, contextError(ctx, err)
+	}
+
+	return warehouses, nil
+}
+
+func (ws *WarehousesService) GetWarehouse(ctx context.Context, id string) (warehouse *models.Warehouse, err error) {
+	ctx, end := ws.begin(ctx, "GetWarehouse")
+	defer func() { end(err) }()
+
+	log := ws.logger(ctx)
+	log.Debugf("Fetching warehouse with ID: %v from database", id)
+
+	warehouse, err = ws.Repo.Get(ctx, id)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrWarehouseNotFound) {
+			log.Errorf("Error fetching warehouse: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return warehouse, nil
+}
+
+func (ws *WarehousesService) AddWarehouse(ctx context.Context, warehouse *models.Warehouse) (err error) {
+	ctx, end := ws.begin(ctx, "AddWarehouse")
+	defer func() { end(err) }()
+
+	log := ws.logger(ctx)
+	log.Debugf("Creating new warehouse in database, data: %+v", warehouse)
+
+	createdWarehouse, err := ws.Repo.Insert(ctx, uuid.NewString(), warehouse)
+	if err != nil {
+		log.Errorf("Error creating new warehouse: %v", err)
+		return contextError(ctx, err)
+	}
+
+	*warehouse = *createdWarehouse
+
+	return nil
+}
+
+func (ws *WarehousesService) UpdateWarehouse(ctx context.Context, id string, warehouse *models.Warehouse) (updatedWarehouse *models.Warehouse, err error) {
+	ctx, end := ws.begin(ctx, "UpdateWarehouse")
+	defer func() { end(err) }()
+
+	log := ws.logger(ctx)
+	log.Debugf("Updating warehouse with ID: %v in database, data: %+v", id, warehouse)
+
+	updatedWarehouse, err = ws.Repo.Update(ctx, id, warehouse)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrWarehouseNotFound) {
+			log.Errorf("Error updating warehouse: %v", err)
+		}
+		return nil, contextError(ctx, err)
+	}
+
+	return updatedWarehouse, nil
+}
+
+func (ws *WarehousesService) DeleteWarehouse(ctx context.Context, id string) (err error) {
+	ctx, end := ws.begin(ctx, "DeleteWarehouse")
+	defer func() { end(err) }()
+
+	log := ws.logger(ctx)
+	log.Debugf("Deleting warehouse with ID: %v from database", id)
+
+	// Stock is never dropped along with its warehouse, it is transferred or shipped first
+	holds, err := ws.Stock.HoldsStock(ctx, id)
+	if err != nil {
+		log.Errorf("Error checking the stock of the warehouse: %v", err)
+		return contextError(ctx, err)
+	}
+	if holds {
+		return custom_errors.ErrWarehouseNotEmpty
+	}
+
+	if err := ws.Repo.Delete(ctx, id); err != nil {
+		if !errors.Is(err, custom_errors.ErrWarehouseNotFound) {
+			log.Errorf("Error deleting warehouse: %v", err)
+		}
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+// begin prepares the context of a service call, as ProductsService.begin does
+func (ws *WarehousesService) begin(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, end := observeCall(ctx, "WarehousesService", "warehouses", method, ws.Observer)
+	ctx, cancel := withQueryTimeout(ctx, ws.QueryTimeout)
+
+	return ctx, func(err error) {
+		cancel()
+		end(err)
+	}
+}
+
+func (ws *WarehousesService) logger(ctx context.Context) *logrus.Entry {
+	return logging.FromContext(ctx, ws.Log)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"simpler-products/allocation"
+	"simpler-products/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	custom_errors "simpler-products/errors"
+)
+
+func TestRegionAffinity(t *testing.T) {
+	assert.Equal(t, 3, models.RegionAffinity("EU-DE-BER", "eu-de-ber"))
+	assert.Equal(t, 2, models.RegionAffinity("EU-DE-BER", "EU-DE-MUC"))
+	assert.Equal(t, 1, models.RegionAffinity("EU-DE", "EU-FR-PAR"))
+	assert.Equal(t, 0, models.RegionAffinity("EU-DE", "US-CA"))
+	assert.Equal(t, 0, models.RegionAffinity("", "EU"))
+}
+
+func TestAllocationPlan(t *testing.T) {
+	warehouses := []models.Warehouse{
+		{ID: "athens", Region: "EU-GR-ATH", Priority: 2},
+		{ID: "berlin", Region: "EU-DE-BER", Priority: 1},
+		{ID: "munich", Region: "EU-DE-MUC", Priority: 3},
+		{ID: "newark", Region: "US-NJ-EWR", Priority: 0},
+	}
+	stock := []models.WarehouseStock{
+		models.NewWarehouseStock("athens", 10, 0),
+		models.NewWarehouseStock("berlin", 4, 1),
+		models.NewWarehouseStock("munich", 6, 0),
+		models.NewWarehouseStock("newark", 2, 0),
+	}
+	split, single := true, false
+
+	for name, tc := range map[string]struct {
+		request     models.AllocationRequest
+		allocations []models.Allocation
+		err         error
+	}{
+		"PrioritySingle": {
+			request:     models.AllocationRequest{Quantity: 3, Strategy: models.AllocatePriority, Split: &single},
+			allocations: []models.Allocation{{WarehouseID: "berlin", Quantity: 3}},
+		},
+		"PrioritySingleSkipsShortWarehouses": {
+			request:     models.AllocationRequest{Quantity: 5, Strategy: models.AllocatePriority},
+			allocations: []models.Allocation{{WarehouseID: "athens", Quantity: 5}},
+		},
+		"PrioritySplit": {
+			request: models.AllocationRequest{Quantity: 8, Strategy: models.AllocatePriority, Split: &split},
+			allocations: []models.Allocation{
+				{WarehouseID: "newark", Quantity: 2},
+				{WarehouseID: "berlin", Quantity: 3},
+				{WarehouseID: "athens", Quantity: 3},
+			},
+		},
+		"NearestSingle": {
+			request:     models.AllocationRequest{Quantity: 5, Strategy: models.AllocateNearest, Region: "EU-DE", Split: &single},
+			allocations: []models.Allocation{{WarehouseID: "munich", Quantity: 5}},
+		},
+		"NearestSplit": {
+			request: models.AllocationRequest{Quantity: 12, Strategy: models.AllocateNearest, Region: "EU-DE-MUC", Split: &split},
+			allocations: []models.Allocation{
+				{WarehouseID: "munich", Quantity: 6},
+				{WarehouseID: "berlin", Quantity: 3},
+				{WarehouseID: "athens", Quantity: 3},
+			},
+		},
+		"NoSingleWarehouse": {
+			request: models.AllocationRequest{Quantity: 11, Strategy: models.AllocatePriority, Split: &single},
+			err:     custom_errors.ErrNoSingleWarehouse,
+		},
+		"InsufficientStock": {
+			request: models.AllocationRequest{Quantity: 22, Strategy: models.AllocatePriority, Split: &split},
+			err:     custom_errors.ErrInsufficientStock,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			allocations, err := allocation.Plan(warehouses, stock, tc.request)
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.allocations, allocations)
+		})
+	}
+
+	t.Run("UnknownStrategy", func(t *testing.T) {
+		_, err := allocation.Plan(warehouses, stock, models.AllocationRequest{Quantity: 1, Strategy: "cheapest"})
+		assert.Error(t, err)
+	})
+}
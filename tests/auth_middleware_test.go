@@ -76,6 +76,27 @@ func TestJWTAuthMiddleware(t *testing.T) {
 		assert.Equal(t, http.StatusOK, c.Writer.Status()) // Should proceed to the next handler
 	})
 
+	t.Run("ValidTokenSetsCaller", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub": "client-42",
+			"exp": time.Now().Add(time.Hour * 24).Unix(),
+		})
+		tokenString, err := token.SignedString(privateKey)
+		assert.NoError(t, err)
+
+		req, _ := http.NewRequest("GET", "/api/v1/v1/products", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenString)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		middlewares.JWTAuthMiddleware()(c)
+
+		// The subject scopes the caller's idempotency keys
+		assert.Equal(t, "client-42", c.GetString(middlewares.CallerKey))
+	})
+
 	t.Run("InvalidToken", func(t *testing.T) {
 		// Create an invalid token (e.g., with a different private key)
 		invalidPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
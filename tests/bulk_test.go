@@ -0,0 +1,272 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	controllers "simpler-products/controllers/v1"
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/services"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type bulkItem struct {
+	Index   int                 `json:"index"`
+	Op      string              `json:"op"`
+	ID      string              `json:"id"`
+	Status  int                 `json:"status"`
+	Product *models.Product     `json:"product"`
+	Errors  []map[string]string `json:"errors"`
+}
+
+func bulkStatuses(items []bulkItem) []int {
+	statuses := make([]int, 0, len(items))
+	for _, item := range items {
+		statuses = append(statuses, item.Status)
+	}
+	return statuses
+}
+
+func TestBulkProductsController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bulk := func(mockService *mockProductService, body string) (*gin.Context, []bulkItem) {
+		req, _ := http.NewRequest("POST", "/products/bulk", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		controllers.BulkProducts(mockService)(c)
+
+		data, _ := c.Get("data")
+		var items []bulkItem
+		if data != nil {
+			encoded, _ := json.Marshal(data)
+			assert.NoError(t, json.Unmarshal(encoded, &items))
+		}
+		return c, items
+	}
+
+	t.Run("BestEffort", func(t *testing.T) {
+		created := &models.Product{ID: "generated-uuid", Name: "Kettle", Description: "Boils water", Price: 30}
+		mockService := &mockProductService{
+			bulkResults: []models.BulkResult{
+				{Product: created},
+				{Err: custom_errors.ErrProductNotFound},
+				{},
+			},
+		}
+
+		c, items := bulk(mockService, `{"mode": "best_effort", "operations": [
+			{"op": "create", "product": {"name": "Kettle", "description": "Boils water", "price": 30}},
+			{"op": "update", "id": "uuid1", "product": {"name": "Kettle"}},
+			{"op": "update", "id": "uuid2", "version": 3, "product": {"name": "Teapot", "description": "Brews tea", "price": 20}},
+			{"op": "delete", "id": "uuid3"}
+		]}`)
+
+		// The invalid update is reported, the other operations are applied
+		assert.Equal(t, http.StatusOK, c.Writer.Status())
+		assert.False(t, mockService.atomic)
+		assert.Equal(t, []models.BulkOperation{
+			{Op: models.BulkCreate, Product: &models.Product{Name: "Kettle", Description: "Boils water", Price: 30}},
+			{Op: models.BulkUpdate, ID: "uuid2", Version: 3, Product: &models.Product{Name: "Teapot", Description: "Brews tea", Price: 20}},
+			{Op: models.BulkDelete, ID: "uuid3"},
+		}, mockService.operations)
+
+		assert.Equal(t, []int{http.StatusCreated, http.StatusBadRequest, http.StatusNotFound, http.StatusNoContent}, bulkStatuses(items))
+		assert.Equal(t, created, items[0].Product)
+		assert.Equal(t, "generated-uuid", items[0].ID)
+		assert.Equal(t, []map[string]string{{"message": "Description is required"}, {"message": "Price is required"}}, items[1].Errors)
+		assert.Equal(t, []map[string]string{{"message": "product not found"}}, items[2].Errors)
+		assert.Equal(t, 3, items[3].Index)
+	})
+
+	t.Run("AllOrNothingWithInvalidOperation", func(t *testing.T) {
+		mockService := &mockProductService{}
+
+		c, items := bulk(mockService, `{"operations": [
+			{"op": "delete", "id": "uuid1"},
+			{"op": "rename", "id": "uuid2"},
+			{"op": "create", "id": "uuid3", "product": {"name": "Kettle", "description": "Boils water", "price": 30}},
+			{"op": "delete"},
+			{"op": "update", "id": "uuid4"},
+			{"op": "create", "product": {"name": "Kettle", "description": "Boils water", "price": "cheap"}}
+		]}`)
+
+		// Nothing is applied
+		assert.Equal(t, http.StatusBadRequest, c.Writer.Status())
+		assert.Nil(t, mockService.operations)
+		assert.Equal(t, []int{
+			http.StatusFailedDependency,
+			http.StatusBadRequest,
+			http.StatusBadRequest,
+			http.StatusBadRequest,
+			http.StatusBadRequest,
+			http.StatusBadRequest,
+		}, bulkStatuses(items))
+		assert.Equal(t, "op must be one of: create, update, delete", items[1].Errors[0]["message"])
+		assert.Equal(t, "id cannot be set when creating a product", items[2].Errors[0]["message"])
+		assert.Equal(t, "id is required to delete a product", items[3].Errors[0]["message"])
+		assert.Equal(t, "product is required to update a product", items[4].Errors[0]["message"])
+		assert.Equal(t, "product.price is invalid", items[5].Errors[0]["message"])
+	})
+
+	t.Run("AllOrNothingRolledBack", func(t *testing.T) {
+		mockService := &mockProductService{
+			bulkResults: []models.BulkResult{
+				{Err: custom_errors.ErrBulkRolledBack},
+				{Err: custom_errors.ErrVersionMismatch},
+			},
+		}
+
+		c, items := bulk(mockService, `{"mode": "all_or_nothing", "operations": [
+			{"op": "delete", "id": "uuid1"},
+			{"op": "delete", "id": "uuid2", "version": 1}
+		]}`)
+
+		// The request fails with the status of the operation that failed
+		assert.True(t, mockService.atomic)
+		assert.Equal(t, http.StatusPreconditionFailed, c.Writer.Status())
+		assert.Equal(t, []int{http.StatusFailedDependency, http.StatusPreconditionFailed}, bulkStatuses(items))
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		for _, body := range []string{
+			`not json`,
+			`{"operations": []}`,
+			`{"mode": "eventually", "operations": [{"op": "delete", "id": "uuid1"}]}`,
+			`{"operations": [` + strings.Repeat(`{"op": "delete", "id": "uuid1"},`, 1000) + `{"op": "delete", "id": "uuid1"}]}`,
+		} {
+			mockService := &mockProductService{}
+			c, items := bulk(mockService, body)
+
+			_, errorsExist := c.Get("errors")
+			assert.Equal(t, http.StatusBadRequest, c.Writer.Status())
+			assert.True(t, errorsExist)
+			assert.Nil(t, items)
+			assert.Nil(t, mockService.operations)
+		}
+	})
+
+	t.Run("ServiceError", func(t *testing.T) {
+		mockService := &mockProductService{err: custom_errors.ErrRequestTimeout}
+
+		c, _ := bulk(mockService, `{"operations": [{"op": "delete", "id": "uuid1"}]}`)
+
+		err, _ := c.Get("errors")
+		assert.Equal(t, custom_errors.ErrRequestTimeout, err)
+	})
+}
+
+func TestBulkProductsService(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	productService := &services.ProductsService{
+		Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:  logrus.New(),
+	}
+
+	operations := []models.BulkOperation{
+		{Op: models.BulkCreate, Product: &models.Product{Name: "Kettle", Description: "Boils water", Price: 30}},
+		{Op: models.BulkDelete, ID: "uuid1"},
+	}
+
+	t.Run("AllOrNothingCommits", func(t *testing.T) {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("INSERT INTO Products \\(id, name, description, price\\) VALUES \\(\\?, \\?, \\?, \\?\\)").
+			WithArgs(sqlmock.AnyArg(), "Kettle", "Boils water", 30.0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Description A", 10.99, 1))
+		dbMock.ExpectExec("DELETE FROM Products WHERE id = \\?").
+			WithArgs("uuid1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		results, err := productService.BulkProducts(context.Background(), operations, true)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, "Kettle", results[0].Product.Name)
+		assert.Equal(t, int64(1), results[0].Product.Version)
+		assert.Equal(t, models.BulkResult{}, results[1])
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("AllOrNothingRollsBack", func(t *testing.T) {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("INSERT INTO Products").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillReturnError(sql.ErrNoRows)
+		dbMock.ExpectRollback()
+
+		results, err := productService.BulkProducts(context.Background(), operations, true)
+
+		// The create is rolled back because the delete failed
+		assert.NoError(t, err)
+		assert.Equal(t, []models.BulkResult{
+			{Err: custom_errors.ErrBulkRolledBack},
+			{Err: custom_errors.ErrProductNotFound},
+		}, results)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("BestEffortAppliesEachOperation", func(t *testing.T) {
+		dbMock.ExpectExec("INSERT INTO Products").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillReturnError(sql.ErrNoRows)
+
+		results, err := productService.BulkProducts(context.Background(), operations, false)
+
+		assert.NoError(t, err)
+		assert.NoError(t, results[0].Err)
+		assert.True(t, errors.Is(results[1].Err, custom_errors.ErrProductNotFound))
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("TransactionError", func(t *testing.T) {
+		dbMock.ExpectBegin().WillReturnError(errors.New("database error"))
+
+		results, err := productService.BulkProducts(context.Background(), operations, true)
+
+		assert.Error(t, err)
+		assert.Nil(t, results)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/services"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	custom_errors "simpler-products/errors"
+)
+
+func TestCategoryTree(t *testing.T) {
+	home, lighting, missing := "home", "lighting", "missing"
+	categories := []models.Category{
+		{ID: "garden", Name: "Garden"},
+		{ID: "home", Name: "Home"},
+		{ID: "lamps", Name: "Lamps", ParentID: &lighting},
+		{ID: "lighting", Name: "Lighting", ParentID: &home},
+		{ID: "orphan", Name: "Orphan", ParentID: &missing},
+		{ID: "rugs", Name: "Rugs", ParentID: &home},
+	}
+
+	t.Run("Tree", func(t *testing.T) {
+		roots := models.CategoryTree(categories)
+
+		// Categories whose parent is missing are shown as roots rather than dropped
+		assert.Len(t, roots, 3)
+		assert.Equal(t, []string{"garden", "home", "orphan"}, []string{roots[0].ID, roots[1].ID, roots[2].ID})
+		assert.Len(t, roots[1].Children, 2)
+		assert.Equal(t, "lighting", roots[1].Children[0].ID)
+		assert.Equal(t, "rugs", roots[1].Children[1].ID)
+		assert.Equal(t, "lamps", roots[1].Children[0].Children[0].ID)
+		assert.Empty(t, roots[0].Children)
+	})
+
+	t.Run("Descendants", func(t *testing.T) {
+		assert.Equal(t, []string{"home", "lighting", "rugs", "lamps"}, models.Descendants(categories, "home"))
+		assert.Equal(t, []string{"lamps"}, models.Descendants(categories, "lamps"))
+		assert.Equal(t, []string{"unknown"}, models.Descendants(categories, "unknown"))
+	})
+}
+
+func TestCategoriesService(t *testing.T) {
+	ctx := context.Background()
+	products := repositories.NewInMemoryProductRepository()
+	categoryService := &services.CategoriesService{
+		Repo:     repositories.NewInMemoryCategoryRepository(),
+		Products: products,
+		Log:      logrus.New(),
+	}
+
+	home := &models.Category{Name: "Home"}
+	assert.NoError(t, categoryService.AddCategory(ctx, home))
+	lighting := &models.Category{Name: "Lighting", ParentID: &home.ID}
+	assert.NoError(t, categoryService.AddCategory(ctx, lighting))
+
+	t.Run("EmptyParentIsTopLevel", func(t *testing.T) {
+		empty := ""
+		garden := &models.Category{Name: "Garden", ParentID: &empty}
+		assert.NoError(t, categoryService.AddCategory(ctx, garden))
+		assert.Nil(t, garden.ParentID)
+		assert.NoError(t, categoryService.DeleteCategory(ctx, garden.ID))
+	})
+
+	t.Run("ParentNotFound", func(t *testing.T) {
+		missing := "missing"
+		err := categoryService.AddCategory(ctx, &models.Category{Name: "Orphan", ParentID: &missing})
+		assert.ErrorIs(t, err, custom_errors.ErrParentCategoryNotFound)
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		_, err := categoryService.UpdateCategory(ctx, home.ID, &models.Category{Name: "Home", ParentID: &lighting.ID})
+		assert.ErrorIs(t, err, custom_errors.ErrCategoryCycle)
+
+		_, err = categoryService.UpdateCategory(ctx, home.ID, &models.Category{Name: "Home", ParentID: &home.ID})
+		assert.ErrorIs(t, err, custom_errors.ErrCategoryCycle)
+	})
+
+	t.Run("UpdateNotFound", func(t *testing.T) {
+		_, err := categoryService.UpdateCategory(ctx, "missing", &models.Category{Name: "Missing"})
+		assert.ErrorIs(t, err, custom_errors.ErrCategoryNotFound)
+	})
+
+	t.Run("ProductCategories", func(t *testing.T) {
+		_, err := products.Insert(ctx, "uuid1", &models.Product{Name: "Desk Lamp", Description: "Lights the desk", Price: 25})
+		assert.NoError(t, err)
+
+		categories, err := categoryService.SetProductCategories(ctx, "uuid1", []string{lighting.ID, lighting.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Category{*lighting}, categories)
+
+		_, err = categoryService.SetProductCategories(ctx, "uuid1", []string{lighting.ID, "missing"})
+		assert.ErrorIs(t, err, custom_errors.ErrUnknownCategories)
+
+		_, err = categoryService.SetProductCategories(ctx, "missing", []string{lighting.ID})
+		assert.ErrorIs(t, err, custom_errors.ErrProductNotFound)
+
+		// Failed assignments leave the previous ones
+		categories, err = categoryService.GetProductCategories(ctx, "uuid1")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Category{*lighting}, categories)
+	})
+
+	t.Run("DeleteNotEmpty", func(t *testing.T) {
+		// Home holds Lighting, Lighting holds a product
+		assert.ErrorIs(t, categoryService.DeleteCategory(ctx, home.ID), custom_errors.ErrCategoryNotEmpty)
+		assert.ErrorIs(t, categoryService.DeleteCategory(ctx, lighting.ID), custom_errors.ErrCategoryNotEmpty)
+
+		_, err := categoryService.SetProductCategories(ctx, "uuid1", []string{})
+		assert.NoError(t, err)
+		assert.NoError(t, categoryService.DeleteCategory(ctx, lighting.ID))
+		assert.NoError(t, categoryService.DeleteCategory(ctx, home.ID))
+		assert.ErrorIs(t, categoryService.DeleteCategory(ctx, home.ID), custom_errors.ErrCategoryNotFound)
+	})
+}
+
+func TestProductsServiceCategoryFilter(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	productService := &services.ProductsService{
+		Repo:       repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:        logrus.New(),
+		Categories: repositories.NewSQLCategoryRepository(db, repositories.MySQL),
+	}
+
+	t.Run("IncludesDescendants", func(t *testing.T) {
+		dbMock.ExpectQuery("^SELECT id, name, parent_id FROM Categories ORDER BY name, id$").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).
+				AddRow("home", "Home", nil).
+				AddRow("lamps", "Lamps", "lighting").
+				AddRow("lighting", "Lighting", "home").
+				AddRow("rugs", "Rugs", nil))
+		dbMock.ExpectQuery("^SELECT COUNT\\(\\*\\) FROM Products WHERE id IN \\(SELECT product_id FROM ProductCategories WHERE category_id IN \\(\\?, \\?, \\?\\)\\)$").
+			WithArgs("home", "lighting", "lamps").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		dbMock.ExpectQuery("^SELECT id, name, description, price, version FROM Products WHERE id IN \\(SELECT product_id FROM ProductCategories WHERE category_id IN \\(\\?, \\?, \\?\\)\\) ORDER BY id ASC LIMIT \\? OFFSET \\?$").
+			WithArgs("home", "lighting", "lamps", 10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Desk Lamp", "Lights the desk", 25.0, 1))
+
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{
+			Filter: models.ProductFilter{Category: "home"},
+			Limit:  10,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Len(t, products, 1)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("CategoryNotFound", func(t *testing.T) {
+		dbMock.ExpectQuery("SELECT (.+) FROM Categories").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow("home", "Home", nil))
+
+		_, _, err := productService.GetAllProducts(context.Background(), models.ProductQuery{
+			Filter: models.ProductFilter{Category: "missing"},
+			Limit:  10,
+		})
+
+		assert.ErrorIs(t, err, custom_errors.ErrCategoryNotFound)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestSQLCategoryRepository(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repositories.NewSQLCategoryRepository(db, repositories.Postgres)
+	parentID := "home"
+
+	t.Run("Insert", func(t *testing.T) {
+		dbMock.ExpectExec("^INSERT INTO Categories \\(id, name, parent_id\\) VALUES \\(\\$1, \\$2, \\$3\\)$").
+			WithArgs("lighting", "Lighting", "home").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		created, err := repo.Insert(context.Background(), "lighting", &models.Category{Name: "Lighting", ParentID: &parentID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Category{ID: "lighting", Name: "Lighting", ParentID: &parentID}, created)
+	})
+
+	t.Run("UpdateNotFound", func(t *testing.T) {
+		dbMock.ExpectExec("^UPDATE Categories SET name = \\$1, parent_id = \\$2 WHERE id = \\$3$").
+			WithArgs("Lighting", nil, "missing").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery("^SELECT id, name, parent_id FROM Categories WHERE id = \\$1$").
+			WithArgs("missing").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}))
+
+		_, err := repo.Update(context.Background(), "missing", &models.Category{Name: "Lighting"})
+
+		assert.ErrorIs(t, err, custom_errors.ErrCategoryNotFound)
+	})
+
+	t.Run("DeleteNotFound", func(t *testing.T) {
+		dbMock.ExpectExec("^DELETE FROM Categories WHERE id = \\$1$").
+			WithArgs("missing").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.Delete(context.Background(), "missing"), custom_errors.ErrCategoryNotFound)
+	})
+
+	t.Run("DeleteInUse", func(t *testing.T) {
+		// A product assigned after the category was checked is caught by the schema
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("^DELETE FROM Categories WHERE id = \\$1$").
+			WithArgs("lighting").
+			WillReturnError(&pgconn.PgError{Code: "23503"})
+		dbMock.ExpectRollback()
+
+		err := repo.InTransaction(context.Background(), func(tx repositories.CategoryRepository) error {
+			return tx.Delete(context.Background(), "lighting")
+		})
+
+		assert.ErrorIs(t, err, custom_errors.ErrCategoryNotEmpty)
+	})
+
+	t.Run("DatabaseError", func(t *testing.T) {
+		dbMock.ExpectQuery("SELECT (.+) FROM Categories").
+			WillReturnError(errors.New("database error"))
+
+		_, err := repo.List(context.Background())
+
+		assert.EqualError(t, err, "database error")
+	})
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"errors"
+	"simpler-products/database"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseManager(t *testing.T) {
+	opts := database.DefaultOptions()
+	opts.HealthCheckInterval = 5 * time.Millisecond
+	opts.PingTimeout = 100 * time.Millisecond
+	opts.MinBackoff = time.Millisecond
+	opts.MaxBackoff = 5 * time.Millisecond
+
+	t.Run("TracksHealthAcrossOutages", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		// The database goes away for two checks and then comes back
+		dbMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		dbMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		for i := 0; i < 1000; i++ {
+			dbMock.ExpectPing()
+		}
+
+		manager := database.NewManager(db, logrus.New(), opts)
+		assert.True(t, manager.Healthy())
+
+		manager.Start()
+
+		assert.Eventually(t, func() bool { return !manager.Healthy() }, time.Second, time.Millisecond)
+		assert.Error(t, manager.LastError())
+
+		assert.Eventually(t, manager.Healthy, time.Second, time.Millisecond)
+		assert.NoError(t, manager.LastError())
+		assert.False(t, manager.LastCheck().IsZero())
+
+		// The same pool is kept across reconnections
+		assert.Same(t, db, manager.DB)
+
+		// sqlmock reports the unused ping expectations on close, so only the shutdown matters here
+		_ = manager.Close()
+	})
+
+	t.Run("CloseStopsWatchdog", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		dbMock.ExpectClose()
+
+		manager := database.NewManager(db, logrus.New(), database.DefaultOptions())
+		manager.Start()
+
+		done := make(chan error)
+		go func() { done <- manager.Close() }()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Close did not return")
+		}
+
+		// No ping happened before the watchdog was stopped
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("CloseWithoutStart", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		dbMock.ExpectClose()
+
+		manager := database.NewManager(db, logrus.New(), opts)
+		assert.NoError(t, manager.Close())
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"simpler-products/commands"
+	"simpler-products/config"
+	"simpler-products/exporter"
+	"simpler-products/importer"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/services"
+	"simpler-products/validators"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newExportRepository(t *testing.T) repositories.ProductRepository {
+	t.Helper()
+
+	repo := repositories.NewInMemoryProductRepository()
+	for id, product := range map[string]models.Product{
+		"uuid1": {Name: "Desk Lamp", Description: "Lights the desk", Price: 25},
+		"uuid2": {Name: "Floor Lamp", Description: "Tall, \"bright\"\tand warm", Price: 80.5},
+		"uuid3": {Name: "Kettle", Description: "Boils <water>", Price: 30},
+	} {
+		_, err := repo.Insert(context.Background(), id, &product)
+		assert.NoError(t, err)
+	}
+
+	return repo
+}
+
+func TestExporter(t *testing.T) {
+	ex := &exporter.Exporter{Service: &services.ProductsService{Repo: newExportRepository(t), Log: logrus.New()}}
+	lamps := models.ProductFilter{Name: "lamp"}
+	byPrice := []models.SortField{{Field: "price", Descending: true}}
+
+	t.Run("CSV", func(t *testing.T) {
+		var out bytes.Buffer
+		exported, err := ex.Export(context.Background(), &out, models.FormatCSV, lamps, byPrice)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, exported)
+		assert.Equal(t, "id,name,description,price\n"+
+			"uuid2,Floor Lamp,\"Tall, \"\"bright\"\"\tand warm\",80.5\n"+
+			"uuid1,Desk Lamp,Lights the desk,25\n", out.String())
+	})
+
+	t.Run("TSV", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := ex.Export(context.Background(), &out, models.FormatTSV, models.ProductFilter{}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "id\tname\tdescription\tprice\n"+
+			"uuid1\tDesk Lamp\tLights the desk\t25\n"+
+			"uuid2\tFloor Lamp\t\"Tall, \"\"bright\"\"\tand warm\"\t80.5\n"+
+			"uuid3\tKettle\tBoils <water>\t30\n", out.String())
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := ex.Export(context.Background(), &out, models.FormatNDJSON, lamps, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `{"id":"uuid1","name":"Desk Lamp","description":"Lights the desk","price":25}`+"\n"+
+			`{"id":"uuid2","name":"Floor Lamp","description":"Tall, \"bright\"\tand warm","price":80.5}`+"\n", out.String())
+	})
+
+	t.Run("NoProducts", func(t *testing.T) {
+		var out bytes.Buffer
+		exported, err := ex.Export(context.Background(), &out, models.FormatCSV, models.ProductFilter{Name: "chair"}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, exported)
+		assert.Equal(t, "id,name,description,price\n", out.String())
+	})
+
+	t.Run("EscapesFormulas", func(t *testing.T) {
+		repo := repositories.NewInMemoryProductRepository()
+		for id, product := range map[string]models.Product{
+			"uuid1": {Name: "=HYPERLINK(\"http://evil\")", Description: "@SUM(A1:A9)", Price: 10},
+			"uuid2": {Name: "+cmd", Description: "-2 for 1", Price: 20},
+		} {
+			_, err := repo.Insert(context.Background(), id, &product)
+			assert.NoError(t, err)
+		}
+		ex := &exporter.Exporter{Service: &services.ProductsService{Repo: repo, Log: logrus.New()}}
+
+		var out bytes.Buffer
+		_, err := ex.Export(context.Background(), &out, models.FormatCSV, models.ProductFilter{}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "id,name,description,price\n"+
+			"uuid1,\"'=HYPERLINK(\"\"http://evil\"\")\",'@SUM(A1:A9),10\n"+
+			"uuid2,'+cmd,'-2 for 1,20\n", out.String())
+
+		// The quotes are dropped when importing the export back
+		imported := repositories.NewInMemoryProductRepository()
+		im := &importer.Importer{Service: &services.ProductsService{Repo: imported, Log: logrus.New()}}
+		_, err = im.Import(context.Background(), &out, models.FormatCSV)
+		assert.NoError(t, err)
+		product, err := imported.Get(context.Background(), "uuid2")
+		assert.NoError(t, err)
+		assert.Equal(t, "+cmd", product.Name)
+		assert.Equal(t, "-2 for 1", product.Description)
+	})
+
+	t.Run("ImportsBack", func(t *testing.T) {
+		for _, format := range []string{models.FormatCSV, models.FormatNDJSON} {
+			var out bytes.Buffer
+			_, err := ex.Export(context.Background(), &out, format, models.ProductFilter{}, nil)
+			assert.NoError(t, err)
+
+			repo := repositories.NewInMemoryProductRepository()
+			im := &importer.Importer{Service: &services.ProductsService{Repo: repo, Log: logrus.New()}}
+			report, err := im.Import(context.Background(), &out, format)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, report.Created, format)
+
+			imported, err := repo.List(context.Background(), models.ProductQuery{Limit: 10})
+			assert.NoError(t, err)
+			exported, err := newExportRepository(t).List(context.Background(), models.ProductQuery{Limit: 10})
+			assert.NoError(t, err)
+			// Imported products are sanitized like the products sent as JSON
+			for i := range exported {
+				validators.SanitizeProduct(&exported[i])
+			}
+			assert.Equal(t, exported, imported, format)
+		}
+	})
+
+	t.Run("ServiceError", func(t *testing.T) {
+		ex := &exporter.Exporter{Service: &mockProductService{err: errors.New("database error")}}
+
+		var out bytes.Buffer
+		_, err := ex.Export(context.Background(), &out, models.FormatCSV, models.ProductFilter{}, nil)
+
+		// Nothing is written when the export fails before any product is read
+		assert.Error(t, err)
+		assert.Empty(t, out.String())
+	})
+}
+
+func TestExportProductsService(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	productService := &services.ProductsService{
+		Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:  logrus.New(),
+	}
+
+	priceMin := 20.0
+	filter := models.ProductFilter{PriceMin: &priceMin}
+	sort := []models.SortField{{Field: "price", Descending: true}}
+
+	t.Run("ReadsInBatches", func(t *testing.T) {
+		dbMock.ExpectQuery("^SELECT id, name, description, price, version FROM Products WHERE price >= \\? ORDER BY price DESC, id ASC LIMIT \\? OFFSET \\?$").
+			WithArgs(20.0, 500, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid2", "Product B", "Description B", 80.5, 1).
+				AddRow("uuid1", "Product A", "Description A", 25.0, 3))
+
+		var exported []models.Product
+		err := productService.ExportProducts(context.Background(), filter, sort, func(product models.Product) error {
+			exported = append(exported, product)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Product{
+			{ID: "uuid2", Name: "Product B", Description: "Description B", Price: 80.5, Version: 1},
+			{ID: "uuid1", Name: "Product A", Description: "Description A", Price: 25, Version: 3},
+		}, exported)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("StopsOnWriteError", func(t *testing.T) {
+		dbMock.ExpectQuery("SELECT (.+) FROM Products").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid2", "Product B", "Description B", 80.5, 1).
+				AddRow("uuid1", "Product A", "Description A", 25.0, 3))
+
+		calls := 0
+		writeErr := errors.New("broken pipe")
+		err := productService.ExportProducts(context.Background(), filter, sort, func(product models.Product) error {
+			calls++
+			return writeErr
+		})
+
+		assert.ErrorIs(t, err, writeErr)
+		assert.Equal(t, 1, calls)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestExportReleasesConnection(t *testing.T) {
+	// The SQLite pool holds a single connection, which a paused export must not keep
+	repo := newSQLiteRepository(t)
+	productService := &services.ProductsService{Repo: repo, Log: logrus.New(), QueryTimeout: time.Second}
+	for _, id := range []string{"uuid1", "uuid2", "uuid3"} {
+		_, err := repo.Insert(context.Background(), id, &models.Product{Name: "Lamp " + id, Description: "Lights", Price: 10})
+		assert.NoError(t, err)
+	}
+
+	exported := 0
+	err := productService.ExportProducts(context.Background(), models.ProductFilter{}, nil, func(product models.Product) error {
+		exported++
+		if exported == 1 {
+			// Another request is served while the client has only read part of the export
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			products, _, err := productService.GetAllProducts(ctx, models.ProductQuery{Limit: 10})
+			assert.NoError(t, err)
+			assert.Len(t, products, 3)
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, exported)
+}
+
+func TestExportCommand(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	cfg := &config.Config{
+		DB:      repo.DB,
+		Dialect: repositories.SQLite,
+		Log:     logrus.New(),
+		Services: struct {
+			services.ProductsServiceInterface
+		}{
+			&services.ProductsService{Repo: repo, Log: logrus.New()},
+		},
+	}
+
+	for _, id := range []string{"uuid1", "uuid2"} {
+		_, err := repo.Insert(context.Background(), id, &models.Product{Name: "Lamp " + id, Description: "Lights", Price: 10})
+		assert.NoError(t, err)
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, commands.Run(cfg, []string{"export", "-format", "tsv", "-sort", "-id", "-price-max", "10"}, &out))
+	assert.Equal(t, "id\tname\tdescription\tprice\nuuid2\tLamp uuid2\tLights\t10\nuuid1\tLamp uuid1\tLights\t10\n", out.String())
+
+	out.Reset()
+	path := filepath.Join(t.TempDir(), "products.ndjson")
+	assert.NoError(t, commands.Run(cfg, []string{"export", "-format", "ndjson", "-name", "uuid1", "-o", path}, &out))
+	assert.Equal(t, "Exported 1 products to "+path+"\n", out.String())
+
+	written, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"uuid1","name":"Lamp uuid1","description":"Lights","price":10}`+"\n", string(written))
+
+	assert.EqualError(t, commands.Run(cfg, []string{"export", "-price-min", "-1", "-sort", "color"}, &out),
+		"price_min must be a non-negative number; cannot sort by 'color', allowed fields are: id, name, price")
+	assert.Error(t, commands.Run(cfg, []string{"export", "-format", "xml"}, &out))
+	assert.Error(t, commands.Run(cfg, []string{"export", "products.csv"}, &out))
+	assert.Error(t, commands.Run(&config.Config{Log: logrus.New()}, []string{"export"}, &out))
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"simpler-products/controllers"
+	"simpler-products/database"
+	"simpler-products/migrations"
+	"simpler-products/services"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func passingCheck(name string, critical bool) services.HealthCheck {
+	return services.HealthCheck{
+		Name:     name,
+		Critical: critical,
+		Check:    func(ctx context.Context) error { return nil },
+	}
+}
+
+func failingCheck(name string, critical bool) services.HealthCheck {
+	return services.HealthCheck{
+		Name:     name,
+		Critical: critical,
+		Check:    func(ctx context.Context) error { return errors.New(name + " unavailable") },
+	}
+}
+
+func TestHealthService(t *testing.T) {
+	log := logrus.New()
+	ctx := context.Background()
+
+	t.Run("AllChecksPass", func(t *testing.T) {
+		hs := &services.HealthService{Log: log}
+		hs.RegisterCheck(passingCheck("database", true))
+		hs.RegisterCheck(passingCheck("cache", false))
+
+		report, ready := hs.CheckReadiness(ctx)
+
+		assert.True(t, ready)
+		assert.Equal(t, services.HealthStatusReady, report.Status)
+		assert.Len(t, report.Components, 2)
+		assert.Equal(t, "database", report.Components[0].Name)
+		assert.Equal(t, services.HealthStatusUp, report.Components[0].Status)
+	})
+
+	t.Run("NonCriticalFailureKeepsReady", func(t *testing.T) {
+		hs := &services.HealthService{Log: log}
+		hs.RegisterCheck(passingCheck("database", true))
+		hs.RegisterCheck(failingCheck("cache", false))
+
+		report, ready := hs.CheckReadiness(ctx)
+
+		assert.True(t, ready)
+		assert.Equal(t, services.HealthStatusDown, report.Components[1].Status)
+		assert.Equal(t, "cache unavailable", report.Components[1].Error)
+	})
+
+	t.Run("CriticalFailure", func(t *testing.T) {
+		hs := &services.HealthService{Log: log}
+		hs.RegisterCheck(failingCheck("database", true))
+
+		report, ready := hs.CheckReadiness(ctx)
+
+		assert.False(t, ready)
+		assert.Equal(t, services.HealthStatusNotReady, report.Status)
+	})
+
+	t.Run("CheckTimeout", func(t *testing.T) {
+		hs := &services.HealthService{Log: log, Timeout: 10 * time.Millisecond}
+		hs.RegisterCheck(services.HealthCheck{
+			Name:     "database",
+			Critical: true,
+			Check: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+
+		report, ready := hs.CheckReadiness(ctx)
+
+		assert.False(t, ready)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Components[0].Error)
+	})
+
+	t.Run("ShuttingDown", func(t *testing.T) {
+		hs := &services.HealthService{Log: log}
+		hs.RegisterCheck(passingCheck("database", true))
+		hs.MarkShuttingDown()
+
+		report, ready := hs.CheckReadiness(ctx)
+
+		assert.False(t, ready)
+		assert.Equal(t, services.HealthStatusShuttingDown, report.Status)
+	})
+
+	t.Run("DatabaseAndMigrationChecks", func(t *testing.T) {
+		db := openSQLite(t)
+		migrator, err := migrations.NewMigrator(db, "sqlite", log)
+		assert.NoError(t, err)
+
+		hs := &services.HealthService{Log: log, Timeout: time.Second}
+		hs.RegisterCheck(services.DatabaseHealthCheck(database.NewManager(db, log, database.DefaultOptions())))
+		hs.RegisterCheck(services.MigrationsHealthCheck(migrator))
+
+		// Pending migrations make the service not ready
+		report, ready := hs.CheckReadiness(ctx)
+		assert.False(t, ready)
+		assert.Equal(t, services.HealthStatusUp, report.Components[0].Status)
+		assert.Equal(t, services.HealthStatusDown, report.Components[1].Status)
+		assert.False(t, tableExists(t, db, "schema_migrations"))
+
+		_, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+
+		_, ready = hs.CheckReadiness(ctx)
+		assert.True(t, ready)
+	})
+
+	t.Run("DatabaseDown", func(t *testing.T) {
+		checked := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		hs := &services.HealthService{Log: log}
+		hs.RegisterCheck(services.DatabaseHealthCheck(downDatabase{err: errors.New("connection refused"), checked: checked}))
+
+		// The state of the watchdog is reported as is, without pinging the database
+		report, ready := hs.CheckReadiness(ctx)
+
+		assert.False(t, ready)
+		assert.Equal(t, services.HealthStatusDown, report.Components[0].Status)
+		assert.Equal(t, "check at 2024-05-01T12:00:00Z failed: connection refused", report.Components[0].Error)
+	})
+}
+
+// downDatabase is the state of a database whose last health check failed
+type downDatabase struct {
+	err     error
+	checked time.Time
+}
+
+func (d downDatabase) Healthy() bool        { return false }
+func (d downDatabase) LastError() error     { return d.err }
+func (d downDatabase) LastCheck() time.Time { return d.checked }
+
+func TestReadinessController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logrus.New()
+
+	t.Run("Ready", func(t *testing.T) {
+		hs := &services.HealthService{Log: log}
+		hs.RegisterCheck(passingCheck("database", true))
+
+		req, _ := http.NewRequest("GET", "/api/health/ready", nil)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		controllers.Readiness(hs)(c)
+
+		data, _ := c.Get("data")
+		assert.Equal(t, http.StatusOK, c.Writer.Status())
+		assert.Equal(t, services.HealthStatusReady, data.(*services.HealthReport).Status)
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		hs := &services.HealthService{Log: log}
+		hs.RegisterCheck(failingCheck("database", true))
+
+		req, _ := http.NewRequest("GET", "/api/health/ready", nil)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		controllers.Readiness(hs)(c)
+
+		assert.Equal(t, http.StatusServiceUnavailable, c.Writer.Status())
+	})
+}
+
+func TestHealthRoutes(t *testing.T) {
+	router := newRouter(t, newSQLiteRepository(t))
+
+	req, _ := http.NewRequest("GET", "/api/health/live", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/health/ready", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Status int                   `json:"status"`
+		Data   services.HealthReport `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, services.HealthStatusReady, response.Data.Status)
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	custom_errors "simpler-products/errors"
+	"simpler-products/idempotency"
+	"simpler-products/middlewares"
+	"simpler-products/repositories"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := idempotency.NewStore(time.Hour)
+	store.Now = func() time.Time { return now }
+
+	response := idempotency.Response{Status: http.StatusCreated, Body: []byte(`{"status":201}`)}
+
+	// The first request is processed, concurrent retries are rejected until it completes
+	recorded, err := store.Begin("key", "fingerprint")
+	assert.NoError(t, err)
+	assert.Nil(t, recorded)
+
+	_, err = store.Begin("key", "fingerprint")
+	assert.ErrorIs(t, err, custom_errors.ErrIdempotencyKeyInProgress)
+
+	store.Complete("key", response)
+
+	recorded, err = store.Begin("key", "fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, &response, recorded)
+
+	// The key cannot be reused for another request
+	_, err = store.Begin("key", "other fingerprint")
+	assert.ErrorIs(t, err, custom_errors.ErrIdempotencyKeyReused)
+
+	// Released keys can be retried
+	recorded, err = store.Begin("released", "fingerprint")
+	assert.NoError(t, err)
+	assert.Nil(t, recorded)
+	store.Release("released")
+	recorded, err = store.Begin("released", "fingerprint")
+	assert.NoError(t, err)
+	assert.Nil(t, recorded)
+
+	// Expired keys are forgotten
+	now = now.Add(time.Hour)
+	recorded, err = store.Begin("key", "other fingerprint")
+	assert.NoError(t, err)
+	assert.Nil(t, recorded)
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	router := newRouter(t, repositories.NewInMemoryProductRepository())
+
+	post := func(key, remoteAddr, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/products", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set(middlewares.IdempotencyKeyHeader, key)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	total := func() int {
+		w, response := doRequest(t, router, "GET", "/api/v1/products", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return response.Pagination.Total
+	}
+
+	product := `{"name": "Kettle", "description": "Boils water", "price": 30}`
+
+	// Retries get the response of the first request, which is only processed once
+	first := post("create-kettle", "10.0.0.1:1234", product)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(middlewares.IdempotentReplayedHeader))
+
+	retry := post("create-kettle", "10.0.0.1:1234", product)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(middlewares.IdempotentReplayedHeader))
+	assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+	assert.NotEqual(t, first.Header().Get(middlewares.RequestIDHeader), retry.Header().Get(middlewares.RequestIDHeader))
+
+	// The envelope is replayed with the request ID of the retry
+	var created, replayed routerResponse
+	assert.NoError(t, json.Unmarshal(first.Body.Bytes(), &created))
+	assert.NoError(t, json.Unmarshal(retry.Body.Bytes(), &replayed))
+	assert.Equal(t, created.Data, replayed.Data)
+	assert.Equal(t, first.Header().Get(middlewares.RequestIDHeader), created.RequestID)
+	assert.Equal(t, retry.Header().Get(middlewares.RequestIDHeader), replayed.RequestID)
+	assert.Equal(t, 1, total())
+
+	// Reusing the key for another payload is a conflict
+	w := post("create-kettle", "10.0.0.1:1234", `{"name": "Teapot", "description": "Brews tea", "price": 20}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), custom_errors.ErrIdempotencyKeyReused.Error())
+	assert.Equal(t, 1, total())
+
+	// Keys are scoped to the caller
+	w = post("create-kettle", "10.0.0.2:1234", product)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(middlewares.IdempotentReplayedHeader))
+	assert.Equal(t, 2, total())
+
+	// Validation errors are replayed as well
+	w = post("invalid-kettle", "10.0.0.1:1234", `{"name": "Kettle"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = post("invalid-kettle", "10.0.0.1:1234", `{"name": "Kettle"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "true", w.Header().Get(middlewares.IdempotentReplayedHeader))
+
+	// Requests without a key are not deduplicated
+	assert.Equal(t, http.StatusCreated, post("", "10.0.0.1:1234", product).Code)
+	assert.Equal(t, http.StatusCreated, post("", "10.0.0.1:1234", product).Code)
+	assert.Equal(t, 4, total())
+
+	for _, key := range []string{"with spaces", strings.Repeat("k", 256)} {
+		w = post(key, "10.0.0.1:1234", product)
+		assert.Equal(t, http.StatusBadRequest, w.Code, key)
+	}
+	assert.Equal(t, 4, total())
+}
+
+func TestIdempotencyMiddlewareServerErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The first attempt fails, the request can then be retried with the same key
+	attempts := 0
+	router := gin.New()
+	router.Use(middlewares.ResponseFormatter(logrus.New()))
+	router.POST("/", middlewares.Idempotency(idempotency.NewStore(time.Hour)), func(c *gin.Context) {
+		attempts++
+		if attempts == 1 {
+			c.Set("errors", custom_errors.ErrServerShuttingDown)
+			return
+		}
+		c.Status(http.StatusCreated)
+		c.Set("data", attempts)
+	})
+
+	send := func() (int, int) {
+		req, _ := http.NewRequest("POST", "/", strings.NewReader("{}"))
+		req.Header.Set(middlewares.IdempotencyKeyHeader, "key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			Data int `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response.Data
+	}
+
+	status, _ := send()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	status, data := send()
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, 2, data)
+
+	status, data = send()
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, 2, data)
+	assert.Equal(t, 2, attempts)
+}
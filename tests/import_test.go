@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"simpler-products/commands"
+	"simpler-products/config"
+	custom_errors "simpler-products/errors"
+	"simpler-products/importer"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/services"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImporter(t *testing.T) {
+	newImporter := func() (*importer.Importer, repositories.ProductRepository) {
+		repo := repositories.NewInMemoryProductRepository()
+		service := &services.ProductsService{Repo: repo, Log: logrus.New()}
+		return &importer.Importer{Service: service, BatchSize: 2}, repo
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		im, repo := newImporter()
+		_, err := repo.Insert(context.Background(), "uuid1", &models.Product{Name: "Teapot", Description: "Brews tea", Price: 20})
+		assert.NoError(t, err)
+
+		file := "\ufeffPrice,Name,Description,ID\n" +
+			"30,Kettle,Boils water,\n" +
+			"25,Teapot,\"Brews tea, green or black\",uuid1\n" +
+			"5,Mug,,\n" +
+			"cheap,Cup,Holds coffee,\n" +
+			"12,Glass\n" +
+			"\n" +
+			"8,Saucer,Holds a cup,uuid2\n"
+
+		report, err := im.Import(context.Background(), strings.NewReader(file), models.FormatCSV)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &importer.Report{
+			Rows:    6,
+			Created: 2,
+			Updated: 1,
+			Failed:  3,
+			Errors: []importer.RowError{
+				{Line: 4, Errors: []map[string]string{{"message": "Description is required"}}},
+				{Line: 5, Errors: []map[string]string{{"message": "price is invalid"}}},
+				{Line: 6, Errors: []map[string]string{{"message": "row has 2 fields, the header has 4"}}},
+			},
+		}, report)
+
+		updated, err := repo.Get(context.Background(), "uuid1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Brews tea, green or black", updated.Description)
+		assert.Equal(t, 25.0, updated.Price)
+
+		created, err := repo.Get(context.Background(), "uuid2")
+		assert.NoError(t, err)
+		assert.Equal(t, "Saucer", created.Name)
+
+		count, err := repo.Count(context.Background(), models.ProductFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		im, repo := newImporter()
+
+		file := `{"name": "Kettle", "description": "Boils water", "price": 30}` + "\n" +
+			"\n" +
+			`{"id": "uuid1", "name": "Teapot", "description": "Brews tea", "price": 20}` + "\n" +
+			`{"name": "Mug", "description": "Holds tea", "price": "cheap"}` + "\n" +
+			`not json` + "\n" +
+			`{"name": "` + strings.Repeat("a", 100*1024) + `"}` + "\n" +
+			`{"name": "Cup", "description": "Holds coffee", "price": -1}`
+
+		report, err := im.Import(context.Background(), strings.NewReader(file), models.FormatNDJSON)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &importer.Report{
+			Rows:    6,
+			Created: 2,
+			Failed:  4,
+			Errors: []importer.RowError{
+				{Line: 4, Errors: []map[string]string{{"message": "price is invalid"}}},
+				{Line: 5, Errors: []map[string]string{{"message": "line is not a JSON object"}}},
+				{Line: 6, Errors: []map[string]string{{"message": "line is longer than 65536 bytes"}}},
+				{Line: 7, Errors: []map[string]string{{"message": "Price must be greater than 0"}}},
+			},
+		}, report)
+
+		count, err := repo.Count(context.Background(), models.ProductFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("Sanitized", func(t *testing.T) {
+		for format, file := range map[string]string{
+			models.FormatCSV: "id,name,description,price\n" +
+				"uuid1,<img src=x onerror=alert(1)>Kettle,Boils water,30\n" +
+				"uuid2,<script>x</script>,Holds tea,5\n",
+			models.FormatNDJSON: `{"id": "uuid1", "name": "<img src=x onerror=alert(1)>Kettle", "description": "Boils water", "price": 30}` + "\n" +
+				`{"id": "uuid2", "name": "<script>x</script>", "description": "Holds tea", "price": 5}`,
+		} {
+			im, repo := newImporter()
+
+			report, err := im.Import(context.Background(), strings.NewReader(file), format)
+
+			// Rows left empty by sanitizing fail validation
+			assert.NoError(t, err)
+			assert.Equal(t, 1, report.Created, format)
+			if assert.Len(t, report.Errors, 1, format) {
+				assert.Equal(t, "uuid2", report.Errors[0].ID)
+				assert.Equal(t, []map[string]string{{"message": "Name is required"}}, report.Errors[0].Errors)
+			}
+
+			created, err := repo.Get(context.Background(), "uuid1")
+			assert.NoError(t, err)
+			assert.Equal(t, "Kettle", created.Name, format)
+		}
+	})
+
+	t.Run("InvalidCSVHeader", func(t *testing.T) {
+		for _, file := range []string{
+			"",
+			"name,description\nKettle,Boils water\n",
+			"name,description,price,color\n",
+			"name,description,price,name\n",
+		} {
+			im, _ := newImporter()
+
+			report, err := im.Import(context.Background(), strings.NewReader(file), models.FormatCSV)
+
+			assert.True(t, errors.Is(err, custom_errors.ErrInvalidImportFile), file)
+			assert.Nil(t, report)
+		}
+	})
+
+	t.Run("ServiceError", func(t *testing.T) {
+		im := &importer.Importer{Service: &mockProductService{err: custom_errors.ErrRequestTimeout}}
+
+		report, err := im.Import(context.Background(), strings.NewReader("name,description,price\nKettle,Boils water,30\n"), models.FormatCSV)
+
+		assert.Equal(t, custom_errors.ErrRequestTimeout, err)
+		assert.Equal(t, 1, report.Rows)
+	})
+}
+
+func TestImportCommand(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	cfg := &config.Config{
+		DB:      repo.DB,
+		Dialect: repositories.SQLite,
+		Log:     logrus.New(),
+		Services: struct {
+			services.ProductsServiceInterface
+		}{
+			&services.ProductsService{Repo: repo, Log: logrus.New()},
+		},
+	}
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "products.csv")
+	assert.NoError(t, os.WriteFile(valid, []byte("name,description,price\nKettle,Boils water,30\nTeapot,Brews tea,20\n"), 0o600))
+	invalid := filepath.Join(dir, "products.jsonl")
+	assert.NoError(t, os.WriteFile(invalid, []byte(`{"name": "Mug", "description": "Holds tea", "price": 5}`+"\n"+`{"name": "Cup"}`+"\n"), 0o600))
+
+	var out bytes.Buffer
+	assert.NoError(t, commands.Run(cfg, []string{"import", valid}, &out))
+	assert.Equal(t, "Imported 2 of 2 rows: 2 created, 0 updated, 0 failed\n", out.String())
+
+	// Failed rows are listed, and fail the command
+	out.Reset()
+	assert.Error(t, commands.Run(cfg, []string{"import", "-batch-size", "1", invalid}, &out))
+	assert.Contains(t, out.String(), "Imported 1 of 2 rows: 1 created, 0 updated, 1 failed\n")
+	assert.Contains(t, out.String(), "Description is required; Price is required")
+
+	count, err := repo.Count(context.Background(), models.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	assert.Error(t, commands.Run(cfg, []string{"import", "-format", "ndjson", valid}, &out))
+	assert.Error(t, commands.Run(cfg, []string{"import", filepath.Join(dir, "products.xml")}, &out))
+	assert.Error(t, commands.Run(cfg, []string{"import"}, &out))
+	assert.Error(t, commands.Run(&config.Config{Log: logrus.New()}, []string{"import", valid}, &out))
+}
@@ -0,0 +1,336 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/services"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	custom_errors "simpler-products/errors"
+)
+
+func TestStockMovementDeltas(t *testing.T) {
+	for _, tc := range []struct {
+		movement models.StockMovement
+		onHand   int64
+		reserved int64
+	}{
+		{models.StockMovement{Type: models.StockAdjust, Quantity: -3}, -3, 0},
+		{models.StockMovement{Type: models.StockReserve, Quantity: 2}, 0, 2},
+		{models.StockMovement{Type: models.StockRelease, Quantity: 2}, 0, -2},
+		{models.StockMovement{Type: models.StockCommit, Quantity: 2}, -2, -2},
+		{models.StockMovement{Type: models.StockTransferOut, Quantity: 2}, -2, 0},
+		{models.StockMovement{Type: models.StockTransferIn, Quantity: 2}, 2, 0},
+	} {
+		onHand, reserved := tc.movement.Deltas()
+		assert.Equal(t, tc.onHand, onHand, tc.movement.Type)
+		assert.Equal(t, tc.reserved, reserved, tc.movement.Type)
+	}
+}
+
+func TestConcurrentStockReservations(t *testing.T) {
+	memoryProducts := repositories.NewInMemoryProductRepository()
+	sqliteProducts := newSQLiteRepository(t)
+
+	for name, tc := range map[string]struct {
+		products repositories.ProductRepository
+		stock    repositories.StockRepository
+	}{
+		"InMemory": {memoryProducts, repositories.NewInMemoryStockRepository(memoryProducts, repositories.NewInMemoryWarehouseRepository(), repositories.NewInMemoryVariantRepository())},
+		"SQLite":   {sqliteProducts, repositories.NewSQLStockRepository(sqliteProducts.DB, repositories.SQLite)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			inventoryService := &services.InventoryService{Repo: tc.stock, Log: logrus.New()}
+
+			_, err := tc.products.Insert(ctx, "uuid1", &models.Product{Name: "Desk Lamp", Description: "Lights the desk", Price: 25})
+			assert.NoError(t, err)
+			_, err = inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", Type: models.StockAdjust, Quantity: 10})
+			assert.NoError(t, err)
+
+			// 25 clients compete for the 10 items in stock
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			reserved, refused := 0, 0
+			for i := 0; i < 25; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", Type: models.StockReserve, Quantity: 1})
+
+					mu.Lock()
+					defer mu.Unlock()
+					if errors.Is(err, custom_errors.ErrInsufficientStock) {
+						refused++
+					} else if assert.NoError(t, err) {
+						reserved++
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, 10, reserved)
+			assert.Equal(t, 15, refused)
+
+			stock, err := inventoryService.GetStock(ctx, "uuid1")
+			assert.NoError(t, err)
+			assert.Equal(t, &models.Stock{
+				ProductID:  "uuid1",
+				OnHand:     10,
+				Reserved:   10,
+				Available:  0,
+				Warehouses: []models.WarehouseStock{{WarehouseID: models.DefaultWarehouseID, OnHand: 10, Reserved: 10, Available: 0}},
+			}, stock)
+
+			// Refused reservations are not recorded
+			movements, total, err := inventoryService.GetStockMovements(ctx, "uuid1", 100, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, 11, total)
+			for i, movement := range movements {
+				assert.Equal(t, int64(11-i), movement.Sequence)
+			}
+		})
+	}
+}
+
+func TestStockTransfersAndAllocation(t *testing.T) {
+	memoryProducts := repositories.NewInMemoryProductRepository()
+	memoryWarehouses := repositories.NewInMemoryWarehouseRepository()
+	sqliteProducts := newSQLiteRepository(t)
+
+	for name, tc := range map[string]struct {
+		products   repositories.ProductRepository
+		warehouses repositories.WarehouseRepository
+		stock      repositories.StockRepository
+	}{
+		"InMemory": {memoryProducts, memoryWarehouses, repositories.NewInMemoryStockRepository(memoryProducts, memoryWarehouses, repositories.NewInMemoryVariantRepository())},
+		"SQLite": {
+			sqliteProducts,
+			repositories.NewSQLWarehouseRepository(sqliteProducts.DB, repositories.SQLite),
+			repositories.NewSQLStockRepository(sqliteProducts.DB, repositories.SQLite),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			log := logrus.New()
+			inventoryService := &services.InventoryService{Repo: tc.stock, Warehouses: tc.warehouses, Log: log}
+			warehouseService := &services.WarehousesService{Repo: tc.warehouses, Stock: tc.stock, Log: log}
+
+			_, err := tc.products.Insert(ctx, "uuid1", &models.Product{Name: "Desk Lamp", Description: "Lights the desk", Price: 25})
+			assert.NoError(t, err)
+			berlin := &models.Warehouse{Name: "Berlin", Region: "EU-DE-BER", Priority: 1}
+			assert.NoError(t, warehouseService.AddWarehouse(ctx, berlin))
+			_, err = inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", Type: models.StockAdjust, Quantity: 10})
+			assert.NoError(t, err)
+
+			t.Run("Transfer", func(t *testing.T) {
+				movements, err := inventoryService.TransferStock(ctx, models.StockTransfer{ProductID: "uuid1", From: models.DefaultWarehouseID, To: berlin.ID, Quantity: 4})
+				assert.NoError(t, err)
+				assert.Len(t, movements, 2)
+				assert.Equal(t, []int64{2, 3}, []int64{movements[0].Sequence, movements[1].Sequence})
+				assert.Equal(t, []string{models.StockTransferOut, models.StockTransferIn}, []string{movements[0].Type, movements[1].Type})
+				assert.Equal(t, []int64{6, 4}, []int64{movements[0].OnHand, movements[1].OnHand})
+				assert.NotEmpty(t, movements[0].TransferID)
+				assert.Equal(t, movements[0].TransferID, movements[1].TransferID)
+
+				// Transfers are applied whole or not at all
+				_, err = inventoryService.TransferStock(ctx, models.StockTransfer{ProductID: "uuid1", From: berlin.ID, To: "missing", Quantity: 1})
+				assert.ErrorIs(t, err, custom_errors.ErrWarehouseNotFound)
+				_, err = inventoryService.TransferStock(ctx, models.StockTransfer{ProductID: "uuid1", From: berlin.ID, To: models.DefaultWarehouseID, Quantity: 5})
+				assert.ErrorIs(t, err, custom_errors.ErrInsufficientStock)
+
+				stock, err := inventoryService.GetStock(ctx, "uuid1")
+				assert.NoError(t, err)
+				assert.Equal(t, int64(10), stock.OnHand)
+				assert.ElementsMatch(t, []models.WarehouseStock{
+					models.NewWarehouseStock(berlin.ID, 4, 0),
+					models.NewWarehouseStock(models.DefaultWarehouseID, 6, 0),
+				}, stock.Warehouses)
+			})
+
+			t.Run("Allocate", func(t *testing.T) {
+				// Berlin comes first by priority, but cannot take 5 alone
+				plan, err := inventoryService.AllocateStock(ctx, "uuid1", models.AllocationRequest{Quantity: 5})
+				assert.NoError(t, err)
+				assert.Equal(t, models.AllocatePriority, plan.Strategy)
+				assert.Equal(t, []models.Allocation{{WarehouseID: models.DefaultWarehouseID, Quantity: 5}}, plan.Allocations)
+				assert.False(t, plan.Reserved)
+
+				split := true
+				plan, err = inventoryService.AllocateStock(ctx, "uuid1", models.AllocationRequest{
+					Quantity: 7,
+					Strategy: models.AllocateNearest,
+					Region:   "EU-DE",
+					Split:    &split,
+					Reserve:  true,
+					Reason:   "order-42",
+				})
+				assert.NoError(t, err)
+				assert.True(t, plan.Reserved)
+				assert.Equal(t, []models.Allocation{
+					{WarehouseID: berlin.ID, Quantity: 4},
+					{WarehouseID: models.DefaultWarehouseID, Quantity: 3},
+				}, plan.Allocations)
+
+				stock, err := inventoryService.GetStock(ctx, "uuid1")
+				assert.NoError(t, err)
+				assert.Equal(t, int64(7), stock.Reserved)
+				assert.Equal(t, int64(3), stock.Available)
+
+				_, err = inventoryService.AllocateStock(ctx, "uuid1", models.AllocationRequest{Quantity: 4, Reserve: true})
+				assert.ErrorIs(t, err, custom_errors.ErrInsufficientStock)
+			})
+
+			t.Run("DeleteWarehouse", func(t *testing.T) {
+				assert.ErrorIs(t, warehouseService.DeleteWarehouse(ctx, berlin.ID), custom_errors.ErrWarehouseNotEmpty)
+
+				_, err := inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", WarehouseID: berlin.ID, Type: models.StockCommit, Quantity: 4})
+				assert.NoError(t, err)
+				assert.NoError(t, warehouseService.DeleteWarehouse(ctx, berlin.ID))
+				assert.ErrorIs(t, warehouseService.DeleteWarehouse(ctx, berlin.ID), custom_errors.ErrWarehouseNotFound)
+
+				stock, err := inventoryService.GetStock(ctx, "uuid1")
+				assert.NoError(t, err)
+				assert.Equal(t, []models.WarehouseStock{models.NewWarehouseStock(models.DefaultWarehouseID, 6, 3)}, stock.Warehouses)
+			})
+		})
+	}
+}
+
+func TestSQLStockRepository(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	inventoryService := &services.InventoryService{
+		Repo: repositories.NewSQLStockRepository(db, repositories.MySQL),
+		Log:  logrus.New(),
+	}
+	reserve := models.StockMovement{ProductID: "uuid1", Type: models.StockReserve, Quantity: 3, Reason: "order-42"}
+
+	t.Run("Move", func(t *testing.T) {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("^UPDATE Stock SET movement_count = movement_count \\+ \\? WHERE product_id = \\?$").
+			WithArgs(1, "uuid1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery("^SELECT movement_count FROM Stock WHERE product_id = \\?$").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"movement_count"}).AddRow(2))
+		dbMock.ExpectExec("^UPDATE WarehouseStock SET on_hand = on_hand \\+ \\?, reserved = reserved \\+ \\? "+
+			"WHERE product_id = \\? AND variant_id = \\? AND warehouse_id = \\? AND on_hand \\+ \\? >= reserved \\+ \\? AND reserved \\+ \\? >= 0$").
+			WithArgs(0, 3, "uuid1", "", models.DefaultWarehouseID, 0, 3, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery("^SELECT on_hand, reserved FROM WarehouseStock WHERE product_id = \\? AND variant_id = \\? AND warehouse_id = \\?$").
+			WithArgs("uuid1", "", models.DefaultWarehouseID).
+			WillReturnRows(sqlmock.NewRows([]string{"on_hand", "reserved"}).AddRow(10, 3))
+		dbMock.ExpectExec("^INSERT INTO StockMovements \\(product_id, variant_id, warehouse_id, sequence_number, type, quantity, transfer_id, on_hand, reserved, reason, created_at\\)").
+			WithArgs("uuid1", "", models.DefaultWarehouseID, 2, models.StockReserve, 3, "", 10, 3, "order-42", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec("^UPDATE Stock SET on_hand = on_hand \\+ \\?, reserved = reserved \\+ \\? WHERE product_id = \\?$").
+			WithArgs(0, 3, "uuid1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		movement, err := inventoryService.MoveStock(context.Background(), reserve)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), movement.Sequence)
+		assert.Equal(t, models.DefaultWarehouseID, movement.WarehouseID)
+		assert.Equal(t, int64(10), movement.OnHand)
+		assert.Equal(t, int64(3), movement.Reserved)
+	})
+
+	t.Run("InsufficientStock", func(t *testing.T) {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("UPDATE Stock").WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery("SELECT movement_count").WillReturnRows(sqlmock.NewRows([]string{"movement_count"}).AddRow(3))
+		// The missing WarehouseStock row is created, then the update is retried
+		dbMock.ExpectExec("UPDATE WarehouseStock").WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec("^INSERT IGNORE INTO WarehouseStock \\(product_id, variant_id, warehouse_id\\) SELECT p.id, \\?, w.id FROM Products p, Warehouses w WHERE p.id = \\? AND w.id = \\?$").
+			WithArgs("", "uuid1", models.DefaultWarehouseID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec("UPDATE WarehouseStock").WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery("^SELECT 1 FROM Warehouses WHERE id = \\?$").
+			WithArgs(models.DefaultWarehouseID).
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		dbMock.ExpectRollback()
+
+		_, err := inventoryService.MoveStock(context.Background(), reserve)
+
+		assert.ErrorIs(t, err, custom_errors.ErrInsufficientStock)
+	})
+
+	t.Run("WarehouseNotFound", func(t *testing.T) {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("UPDATE Stock").WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery("SELECT movement_count").WillReturnRows(sqlmock.NewRows([]string{"movement_count"}).AddRow(3))
+		dbMock.ExpectExec("UPDATE WarehouseStock").WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec("INSERT IGNORE INTO WarehouseStock").WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec("UPDATE WarehouseStock").WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery("SELECT 1 FROM Warehouses").WillReturnRows(sqlmock.NewRows([]string{"1"}))
+		dbMock.ExpectRollback()
+
+		missing := reserve
+		missing.WarehouseID = "missing"
+		_, err := inventoryService.MoveStock(context.Background(), missing)
+
+		assert.ErrorIs(t, err, custom_errors.ErrWarehouseNotFound)
+	})
+
+	t.Run("ProductNotFound", func(t *testing.T) {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("UPDATE Stock").WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec("^INSERT IGNORE INTO Stock \\(product_id\\) SELECT id FROM Products WHERE id = \\?$").
+			WithArgs("uuid1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec("UPDATE Stock").WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectRollback()
+
+		_, err := inventoryService.MoveStock(context.Background(), models.StockMovement{ProductID: "uuid1", Type: models.StockCommit, Quantity: 1})
+
+		assert.ErrorIs(t, err, custom_errors.ErrProductNotFound)
+	})
+
+	t.Run("Movements", func(t *testing.T) {
+		dbMock.ExpectQuery("^SELECT variant_id, warehouse_id, on_hand, reserved FROM WarehouseStock WHERE product_id = \\? ORDER BY variant_id, warehouse_id$").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"variant_id", "warehouse_id", "on_hand", "reserved"}).AddRow("", models.DefaultWarehouseID, 10, 3))
+		dbMock.ExpectQuery("^SELECT COUNT\\(\\*\\) FROM StockMovements WHERE product_id = \\?$").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		dbMock.ExpectQuery("^SELECT (.+) FROM StockMovements WHERE product_id = \\? ORDER BY sequence_number DESC LIMIT \\? OFFSET \\?$").
+			WithArgs("uuid1", 1, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"product_id", "variant_id", "warehouse_id", "sequence_number", "type", "quantity", "transfer_id", "on_hand", "reserved", "reason", "created_at"}).
+				AddRow("uuid1", "", models.DefaultWarehouseID, 2, "reserve", 3, "", 10, 3, "order-42", "2026-10-16T09:30:00.250000Z"))
+
+		movements, total, err := inventoryService.GetStockMovements(context.Background(), "uuid1", 1, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Equal(t, []models.StockMovement{{
+			ProductID:   "uuid1",
+			WarehouseID: models.DefaultWarehouseID,
+			Sequence:    2,
+			Type:        models.StockReserve,
+			Quantity:    3,
+			OnHand:      10,
+			Reserved:    3,
+			Reason:      "order-42",
+			CreatedAt:   time.Date(2026, 10, 16, 9, 30, 0, 250000000, time.UTC),
+		}}, movements)
+	})
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
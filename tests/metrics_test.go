@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simpler-products/metrics"
+	"simpler-products/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	body, err := io.ReadAll(w.Body)
+	assert.NoError(t, err)
+
+	return string(body)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	router := newRouter(t, repositories.NewInMemoryProductRepository())
+
+	doRequest(t, router, "GET", "/api/v1/products/some-id", nil)
+	doRequest(t, router, "GET", "/api/v1/products/another-id", nil)
+	doRequest(t, router, "GET", "/api/v1/products", nil)
+
+	body := scrape(t, router)
+
+	// Requests are labelled by route template rather than raw path
+	assert.Contains(t, body, `simpler_products_http_requests_total{method="GET",route="/api/v1/products/:id",status="404"} 2`)
+	assert.Contains(t, body, `simpler_products_http_requests_total{method="GET",route="/api/v1/products",status="200"} 1`)
+	assert.Contains(t, body, `simpler_products_http_request_duration_seconds_bucket{method="GET",route="/api/v1/products/:id",status="404",le="+Inf"} 2`)
+	assert.NotContains(t, body, "some-id")
+
+	// Service methods are timed by outcome
+	assert.Contains(t, body, `simpler_products_service_method_duration_seconds_count{method="GetProductById",outcome="error",service="products"} 2`)
+	assert.Contains(t, body, `simpler_products_service_method_duration_seconds_count{method="GetAllProducts",outcome="success",service="products"} 1`)
+
+	// The exposition format is not wrapped by the response formatter
+	assert.NotContains(t, body, `"status"`)
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	t.Run("DBStats", func(t *testing.T) {
+		recorder := metrics.NewRecorder()
+		assert.NoError(t, recorder.RegisterDB(openSQLite(t), "sqlite"))
+
+		body := scrape(t, recorder.MetricsHandler())
+
+		assert.Contains(t, body, `go_sql_max_open_connections{db_name="sqlite"} 1`)
+		assert.Contains(t, body, `go_sql_open_connections{db_name="sqlite"}`)
+	})
+
+	t.Run("ObserveMethod", func(t *testing.T) {
+		recorder := metrics.NewRecorder()
+		recorder.ObserveMethod("products", "AddProduct", 3*time.Millisecond, nil)
+		recorder.ObserveMethod("products", "AddProduct", 5*time.Millisecond, errors.New("database error"))
+
+		body := scrape(t, recorder.MetricsHandler())
+
+		assert.Contains(t, body, `simpler_products_service_method_duration_seconds_count{method="AddProduct",outcome="success",service="products"} 1`)
+		assert.Contains(t, body, `simpler_products_service_method_duration_seconds_count{method="AddProduct",outcome="error",service="products"} 1`)
+	})
+}
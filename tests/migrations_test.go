@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"simpler-products/commands"
+	"simpler-products/config"
+	"simpler-products/database"
+	"simpler-products/migrations"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	manager, err := database.Init(logrus.New(), "sqlite", database.SQLiteDSN(":memory:"), database.DefaultOptions())
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a sqlite database", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+
+	return manager.DB
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	assert.NoError(t, err)
+
+	return count == 1
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.New()
+
+	t.Run("UpDownAndStatus", func(t *testing.T) {
+		db := openSQLite(t)
+		migrator, err := migrations.NewMigrator(db, "sqlite", log)
+		assert.NoError(t, err)
+
+		pending, err := migrator.Pending(ctx)
+		assert.NoError(t, err)
+		assert.Greater(t, pending, 0)
+		assert.False(t, tableExists(t, db, "schema_migrations"))
+
+		statuses, err := migrator.Status(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, statuses, pending)
+		for _, status := range statuses {
+			assert.False(t, status.Applied)
+		}
+
+		applied, err := migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, len(statuses), applied)
+		assert.True(t, tableExists(t, db, "Products"))
+		assert.True(t, tableExists(t, db, "ProductCategories"))
+		assert.True(t, tableExists(t, db, "StockMovements"))
+		assert.True(t, tableExists(t, db, "WarehouseStock"))
+		assert.True(t, tableExists(t, db, "Variants"))
+
+		// Running again is a no-op
+		applied, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, applied)
+		pending, err = migrator.Pending(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, pending)
+
+		statuses, err = migrator.Status(ctx)
+		assert.NoError(t, err)
+		for _, status := range statuses {
+			assert.True(t, status.Applied)
+			assert.NotEmpty(t, status.AppliedAt)
+		}
+
+		reverted, err := migrator.Down(ctx, len(statuses))
+		assert.NoError(t, err)
+		assert.Equal(t, len(statuses), reverted)
+		assert.False(t, tableExists(t, db, "Products"))
+		assert.False(t, tableExists(t, db, "ProductCategories"))
+		assert.False(t, tableExists(t, db, "StockMovements"))
+		assert.False(t, tableExists(t, db, "WarehouseStock"))
+		assert.False(t, tableExists(t, db, "Variants"))
+	})
+
+	t.Run("ConcurrentRunnersApplyOnce", func(t *testing.T) {
+		db := openSQLite(t)
+
+		var wg sync.WaitGroup
+		results := make([]int, 4)
+		errs := make([]error, 4)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				migrator, err := migrations.NewMigrator(db, "sqlite", log)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				results[i], errs[i] = migrator.Up(ctx)
+			}(i)
+		}
+		wg.Wait()
+
+		total := 0
+		for i := range results {
+			assert.NoError(t, errs[i])
+			total += results[i]
+		}
+
+		migrator, err := migrations.NewMigrator(db, "sqlite", log)
+		assert.NoError(t, err)
+		statuses, err := migrator.Status(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, len(statuses), total)
+	})
+
+	t.Run("RevertingVariantsDropsTheirStock", func(t *testing.T) {
+		db := openSQLite(t)
+		migrator, err := migrations.NewMigrator(db, "sqlite", logrus.New())
+		assert.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+
+		products := repositories.NewSQLProductRepository(db, repositories.SQLite)
+		stock := repositories.NewSQLStockRepository(db, repositories.SQLite)
+		_, err = products.Insert(ctx, "uuid1", &models.Product{Name: "Crew T-Shirt", Description: "Plain cotton", Price: 20})
+		assert.NoError(t, err)
+		assert.NoError(t, repositories.NewSQLVariantRepository(db, repositories.SQLite).Insert(ctx, []models.Variant{
+			{ID: "variant1", ProductID: "uuid1", SKU: "TEE-M", Options: map[string]string{}},
+		}))
+		_, err = stock.Move(ctx, "uuid1", []models.StockMovement{
+			{WarehouseID: models.DefaultWarehouseID, Type: models.StockAdjust, Quantity: 2},
+			{VariantID: "variant1", WarehouseID: models.DefaultWarehouseID, Type: models.StockAdjust, Quantity: 5},
+		})
+		assert.NoError(t, err)
+
+		// Only the stock of the product itself is left, in every dialect
+		reverted, err := migrator.Down(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, reverted)
+
+		var rows, onHand int
+		assert.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*), SUM(on_hand) FROM WarehouseStock WHERE product_id = 'uuid1'").Scan(&rows, &onHand))
+		assert.Equal(t, 1, rows)
+		assert.Equal(t, 2, onHand)
+		assert.NoError(t, db.QueryRowContext(ctx, "SELECT on_hand FROM Stock WHERE product_id = 'uuid1'").Scan(&onHand))
+		assert.Equal(t, 2, onHand)
+	})
+
+	t.Run("UnsupportedDialect", func(t *testing.T) {
+		_, err := migrations.NewMigrator(nil, "oracle", log)
+		assert.Error(t, err)
+	})
+}
+
+func TestMigrateCommand(t *testing.T) {
+	db := openSQLite(t)
+	cfg := &config.Config{
+		DB:      db,
+		Dialect: repositories.SQLite,
+		Log:     logrus.New(),
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, commands.Run(cfg, []string{"migrate", "status"}, &out))
+	assert.Contains(t, out.String(), "create_products")
+	assert.Contains(t, out.String(), "pending")
+
+	out.Reset()
+	assert.NoError(t, commands.Run(cfg, []string{"migrate", "up"}, &out))
+	assert.Contains(t, out.String(), "Applied")
+
+	out.Reset()
+	assert.NoError(t, commands.Run(cfg, []string{"migrate", "down", "1"}, &out))
+	assert.Equal(t, "Reverted 1 migrations\n", out.String())
+
+	assert.Error(t, commands.Run(cfg, []string{"migrate", "down", "zero"}, &out))
+	assert.Error(t, commands.Run(cfg, []string{"migrate"}, &out))
+	assert.Error(t, commands.Run(cfg, []string{"unknown"}, &out))
+	assert.Error(t, commands.Run(&config.Config{Log: logrus.New()}, []string{"migrate", "up"}, &out))
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	custom_errors "simpler-products/errors"
+	"simpler-products/models"
+	"simpler-products/pagination"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := pagination.NewCodec([]byte("test-secret"))
+	priceMin := 10.0
+	query := models.ProductQuery{
+		Filter: models.ProductFilter{Name: "lamp", PriceMin: &priceMin},
+		Sort:   []models.SortField{{Field: "price", Descending: true}},
+	}
+	keyset := models.Keyset{ID: "uuid1", Name: "Desk Lamp", Price: 25.5, Backward: true}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		decoded, err := codec.Decode(codec.Encode(query, keyset), query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &keyset, decoded)
+	})
+
+	t.Run("LimitAndOffsetDoNotMatter", func(t *testing.T) {
+		cursor := codec.Encode(query, keyset)
+
+		other := query
+		other.Limit = 50
+		other.Offset = 20
+		_, err := codec.Decode(cursor, other)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("TamperedCursor", func(t *testing.T) {
+		cursor := codec.Encode(query, keyset)
+		forged := pagination.NewCodec([]byte("another-secret")).Encode(query, keyset)
+
+		for _, tampered := range []string{"", "garbage", cursor[1:], cursor + "x", forged} {
+			_, err := codec.Decode(tampered, query)
+			assert.ErrorIs(t, err, custom_errors.ErrInvalidCursor, tampered)
+		}
+	})
+
+	t.Run("DifferentQuery", func(t *testing.T) {
+		cursor := codec.Encode(query, keyset)
+		priceMax := 100.0
+
+		for _, other := range []models.ProductQuery{
+			{Filter: query.Filter},
+			{Filter: models.ProductFilter{Name: "lamp"}, Sort: query.Sort},
+			{Filter: models.ProductFilter{Name: "lamp", PriceMin: &priceMin, PriceMax: &priceMax}, Sort: query.Sort},
+			{Filter: query.Filter, Sort: []models.SortField{{Field: "price"}}},
+		} {
+			_, err := codec.Decode(cursor, other)
+			assert.ErrorIs(t, err, custom_errors.ErrCursorQueryMismatch)
+		}
+	})
+}
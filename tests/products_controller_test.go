@@ -2,6 +2,7 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"simpler-products/controllers/v1"
 	custom_errors "simpler-products/errors"
 	"simpler-products/models"
+	"simpler-products/validators"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -21,13 +24,30 @@ type mockProductService struct {
 	products []models.Product
 	total    int
 	err      error
+	// query is the last listing query received
+	query models.ProductQuery
+	// facets are returned by GetProductFacets, facetQuery is the last facet query received
+	facets     *models.Facets
+	facetQuery *models.FacetQuery
+	// patch is the last patch received
+	patch *models.ProductPatch
+	// bulkResults are returned by BulkProducts, operations and atomic are the last ones received
+	bulkResults []models.BulkResult
+	operations  []models.BulkOperation
+	atomic      bool
 }
 
-func (m *mockProductService) GetAllProducts(limit, offset int) ([]models.Product, int, error) {
+func (m *mockProductService) GetAllProducts(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error) {
+	m.query = query
 	return m.products, m.total, m.err
 }
 
-func (m *mockProductService) GetProductById(id string) (*models.Product, error) {
+func (m *mockProductService) GetProductFacets(ctx context.Context, filter models.ProductFilter, query models.FacetQuery) (*models.Facets, error) {
+	m.facetQuery = &query
+	return m.facets, m.err
+}
+
+func (m *mockProductService) GetProductById(ctx context.Context, id string) (*models.Product, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -40,7 +60,7 @@ func (m *mockProductService) GetProductById(id string) (*models.Product, error)
 	return nil, custom_errors.ErrProductNotFound
 }
 
-func (m *mockProductService) AddProduct(product *models.Product) error {
+func (m *mockProductService) AddProduct(ctx context.Context, product *models.Product) error {
 	// Simulate ID generation
 	product.ID = "generated-uuid"
 	m.products = append(m.products, *product)
@@ -48,13 +68,16 @@ func (m *mockProductService) AddProduct(product *models.Product) error {
 	return m.err
 }
 
-func (m *mockProductService) UpdateProduct(id string, product *models.Product) (*models.Product, error) {
+func (m *mockProductService) UpdateProduct(ctx context.Context, id string, product *models.Product) (*models.Product, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
 
 	for i, p := range m.products {
 		if p.ID == id {
+			if product.Version != 0 && product.Version != p.Version {
+				return nil, custom_errors.ErrVersionMismatch
+			}
 			m.products[i] = *product
 			return product, nil
 		}
@@ -62,13 +85,34 @@ func (m *mockProductService) UpdateProduct(id string, product *models.Product) (
 	return nil, custom_errors.ErrProductNotFound
 }
 
-func (m *mockProductService) DeleteProduct(id string) error {
+func (m *mockProductService) PatchProduct(ctx context.Context, id string, patch models.ProductPatch) (*models.Product, error) {
+	m.patch = &patch
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for i, p := range m.products {
+		if p.ID == id {
+			if patch.Version != 0 && patch.Version != p.Version {
+				return nil, custom_errors.ErrVersionMismatch
+			}
+			m.products[i] = patch.Apply(p)
+			return &m.products[i], nil
+		}
+	}
+	return nil, custom_errors.ErrProductNotFound
+}
+
+func (m *mockProductService) DeleteProduct(ctx context.Context, id string, version int64) error {
 	if m.err != nil {
 		return m.err
 	}
 
 	for i, p := range m.products {
 		if p.ID == id {
+			if version != 0 && version != p.Version {
+				return custom_errors.ErrVersionMismatch
+			}
 			m.products = append(m.products[:i], m.products[i+1:]...)
 			m.total--
 			return nil
@@ -77,6 +121,21 @@ func (m *mockProductService) DeleteProduct(id string) error {
 	return custom_errors.ErrProductNotFound
 }
 
+func (m *mockProductService) BulkProducts(ctx context.Context, operations []models.BulkOperation, atomic bool) ([]models.BulkResult, error) {
+	m.operations = operations
+	m.atomic = atomic
+	return m.bulkResults, m.err
+}
+
+func (m *mockProductService) ExportProducts(ctx context.Context, filter models.ProductFilter, sort []models.SortField, fn func(product models.Product) error) error {
+	for _, product := range m.products {
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
+
 func TestGetAllProductsController(t *testing.T) {
 	// Set Gin to TestMode
 	gin.SetMode(gin.TestMode)
@@ -256,6 +315,107 @@ func TestGetAllProductsController(t *testing.T) {
 		assert.Equal(t, custom_errors.ErrInvalidOffsetParameter, err)
 	})
 
+	t.Run("FiltersAndSort", func(t *testing.T) {
+		mockService := &mockProductService{}
+
+		// Create a request filtering by name and price and sorting by price then name
+		req, _ := http.NewRequest("GET", "/products?name=phone&price_min=5&price_max=50&sort=-price,name&limit=5&offset=10", nil)
+
+		// Create a response recorder
+		w := httptest.NewRecorder()
+
+		// Create a Gin context
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		// Call the handler function
+		controllers.GetAllProducts(mockService)(c)
+
+		_, errorsExist := c.Get("errors")
+		priceMin, priceMax := 5.0, 50.0
+
+		// Assertions
+		assert.Equal(t, errorsExist, false)
+		assert.Equal(t, models.ProductQuery{
+			Filter: models.ProductFilter{Name: "phone", PriceMin: &priceMin, PriceMax: &priceMax},
+			Sort:   []models.SortField{{Field: "price", Descending: true}, {Field: "name"}},
+			Limit:  6, // one extra product tells whether there is a next page
+			Offset: 10,
+		}, mockService.query)
+	})
+
+	t.Run("Facets", func(t *testing.T) {
+		facets := &models.Facets{Price: &models.PriceFacet{Count: 3}}
+		mockService := &mockProductService{facets: facets}
+
+		// Create a request asking for price facets of the filtered listing
+		req, _ := http.NewRequest("GET", "/products?name=phone&facets=price&price_buckets=10,100", nil)
+
+		// Create a response recorder
+		w := httptest.NewRecorder()
+
+		// Create a Gin context
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		// Call the handler function
+		controllers.GetAllProducts(mockService)(c)
+
+		data, _ := c.Get("facets")
+
+		// Assertions
+		assert.Equal(t, facets, data)
+		assert.Equal(t, &models.FacetQuery{Price: &models.PriceBuckets{Edges: []float64{10, 100}}}, mockService.facetQuery)
+		assert.Equal(t, "phone", mockService.query.Filter.Name)
+	})
+
+	t.Run("NoFacets", func(t *testing.T) {
+		mockService := &mockProductService{}
+
+		// Create a request without facets
+		req, _ := http.NewRequest("GET", "/products", nil)
+
+		// Create a Gin context
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+
+		// Call the handler function
+		controllers.GetAllProducts(mockService)(c)
+
+		_, facetsExist := c.Get("facets")
+
+		// Assertions
+		assert.False(t, facetsExist)
+		assert.Nil(t, mockService.facetQuery)
+	})
+
+	t.Run("InvalidFilters", func(t *testing.T) {
+		// Create a mock ProductService (not used in this case)
+		mockService := &mockProductService{}
+
+		// Create a request with an invalid price and an unknown sort field
+		req, _ := http.NewRequest("GET", "/products?price_min=abc&sort=description", nil)
+
+		// Create a response recorder
+		w := httptest.NewRecorder()
+
+		// Create a Gin context
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		// Call the handler function
+		controllers.GetAllProducts(mockService)(c)
+
+		_, dataExists := c.Get("data")
+		err, _ := c.Get("errors")
+
+		// Assertions
+		assert.Equal(t, http.StatusBadRequest, c.Writer.Status())
+		assert.Equal(t, dataExists, false)
+		assert.IsType(t, &validators.ValidationError{}, err)
+		assert.Len(t, err.(*validators.ValidationError).Errors, 2)
+	})
+
 	t.Run("ServiceError", func(t *testing.T) {
 		// Create a mock ProductService that returns an error
 		mockError := errors.New("service error")
@@ -409,6 +569,56 @@ func TestGetProductByIdController(t *testing.T) {
 		assert.Equal(t, dataExists, false)
 		assert.Equal(t, mockError, err)
 	})
+
+	t.Run("NotModified", func(t *testing.T) {
+		mockService := &mockProductService{
+			products: []models.Product{
+				{ID: "uuid1", Name: "Product A", Description: "Description A", Price: 10.99, Version: 3},
+			},
+		}
+
+		// A cache holding the current version revalidates it
+		req, _ := http.NewRequest("GET", "/products/uuid1", nil)
+		req.Header.Set("If-None-Match", `"2", "3"`)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "uuid1"}}
+
+		controllers.GetProductById(mockService)(c)
+
+		_, dataExists := c.Get("data")
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+		assert.True(t, c.Writer.Written())
+		assert.False(t, dataExists)
+	})
+
+	t.Run("ModifiedSinceCached", func(t *testing.T) {
+		mockService := &mockProductService{
+			products: []models.Product{
+				{ID: "uuid1", Name: "Product A", Description: "Description A", Price: 10.99, Version: 3},
+			},
+		}
+
+		req, _ := http.NewRequest("GET", "/products/uuid1", nil)
+		req.Header.Set("If-None-Match", `"2"`)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "uuid1"}}
+
+		controllers.GetProductById(mockService)(c)
+
+		data, _ := c.Get("data")
+
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+		assert.False(t, c.Writer.Written())
+		assert.Equal(t, [1]*models.Product{&mockService.products[0]}, data)
+	})
 }
 
 func TestAddProductController(t *testing.T) {
@@ -692,6 +902,268 @@ func TestUpdateProductController(t *testing.T) {
 		assert.Equal(t, dataExists, false)
 		assert.Equal(t, mockError, err)
 	})
+
+	t.Run("IfMatch", func(t *testing.T) {
+		testCases := []struct {
+			name           string
+			ifMatch        string
+			expectedStatus int
+			expectedETag   string
+		}{
+			{name: "CurrentVersion", ifMatch: `"2"`, expectedStatus: http.StatusOK, expectedETag: `"2"`},
+			{name: "AnyVersion", ifMatch: "*", expectedStatus: http.StatusOK, expectedETag: `"2"`},
+			{name: "StaleVersion", ifMatch: `"1"`, expectedStatus: http.StatusPreconditionFailed},
+			{name: "WeakTag", ifMatch: `W/"2"`, expectedStatus: http.StatusPreconditionFailed},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mockService := &mockProductService{
+					products: []models.Product{
+						{ID: "uuid1", Name: "Product A", Description: "Description A", Price: 10.99, Version: 2},
+					},
+				}
+
+				req, _ := http.NewRequest("PUT", "/products/uuid1", strings.NewReader(`{"name": "Updated Product A", "description": "Updated Description A", "price": 12.99}`))
+				req.Header.Set("If-Match", tc.ifMatch)
+
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Request = req
+				c.Params = gin.Params{gin.Param{Key: "id", Value: "uuid1"}}
+
+				controllers.UpdateProduct(mockService)(c)
+
+				err, errExists := c.Get("errors")
+
+				assert.Equal(t, tc.expectedStatus, c.Writer.Status())
+				assert.Equal(t, tc.expectedETag, w.Header().Get("ETag"))
+				if tc.expectedStatus == http.StatusPreconditionFailed {
+					assert.Equal(t, custom_errors.ErrVersionMismatch, err)
+					assert.Equal(t, "Product A", mockService.products[0].Name)
+				} else {
+					assert.False(t, errExists)
+					assert.Equal(t, "Updated Product A", mockService.products[0].Name)
+				}
+			})
+		}
+	})
+}
+
+func TestPatchProductController(t *testing.T) {
+	// Set Gin to TestMode
+	gin.SetMode(gin.TestMode)
+
+	newMockService := func() *mockProductService {
+		return &mockProductService{
+			products: []models.Product{
+				{ID: "uuid1", Name: "Product A", Description: "Description A", Price: 10.99},
+			},
+		}
+	}
+
+	patchProduct := func(mockService *mockProductService, id, contentType, body string) *gin.Context {
+		req, _ := http.NewRequest("PATCH", "/products/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+
+		// Create a Gin context
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: id}}
+
+		// Call the handler function
+		controllers.PatchProduct(mockService)(c)
+
+		return c
+	}
+
+	price := 12.5
+	description := "New description"
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		patch       models.ProductPatch
+		product     models.Product
+	}{
+		{
+			name:        "MergePatch",
+			contentType: "application/merge-patch+json",
+			body:        `{"price": 12.5}`,
+			patch:       models.ProductPatch{Price: &price},
+			product:     models.Product{ID: "uuid1", Name: "Product A", Description: "Description A", Price: 12.5},
+		},
+		{
+			name:        "PlainJSONIsAMergePatch",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"description": "New description", "name": "Product A"}`,
+			patch:       models.ProductPatch{Description: &description},
+			product:     models.Product{ID: "uuid1", Name: "Product A", Description: "New description", Price: 10.99},
+		},
+		{
+			name:        "JSONPatch",
+			contentType: "application/json-patch+json",
+			body:        `[{"op": "test", "path": "/price", "value": 10.99}, {"op": "replace", "path": "/price", "value": 12.5}]`,
+			patch:       models.ProductPatch{Price: &price},
+			product:     models.Product{ID: "uuid1", Name: "Product A", Description: "Description A", Price: 12.5},
+		},
+		{
+			name:        "NoChanges",
+			contentType: "application/merge-patch+json",
+			body:        `{}`,
+			patch:       models.ProductPatch{},
+			product:     models.Product{ID: "uuid1", Name: "Product A", Description: "Description A", Price: 10.99},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := newMockService()
+			c := patchProduct(mockService, "uuid1", tc.contentType, tc.body)
+
+			data, _ := c.Get("data")
+			_, errorsExist := c.Get("errors")
+
+			// Only the changed fields reach the service
+			assert.False(t, errorsExist)
+			assert.Equal(t, &tc.patch, mockService.patch)
+			assert.Equal(t, [1]*models.Product{&tc.product}, data)
+		})
+	}
+
+	t.Run("InvalidPatches", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			contentType string
+			body        string
+			status      int
+			err         error
+		}{
+			{
+				name:        "UnsupportedContentType",
+				contentType: "text/plain",
+				body:        `{"price": 12.5}`,
+				status:      http.StatusUnsupportedMediaType,
+				err:         custom_errors.ErrUnsupportedPatchType,
+			},
+			{
+				name:        "MalformedMergePatch",
+				contentType: "application/merge-patch+json",
+				body:        `{"price": `,
+				status:      http.StatusBadRequest,
+				err:         custom_errors.ErrInvalidPatch,
+			},
+			{
+				name:        "UnknownOperation",
+				contentType: "application/json-patch+json",
+				body:        `[{"op": "rename", "path": "/name"}]`,
+				status:      http.StatusBadRequest,
+				err:         custom_errors.ErrInvalidPatch,
+			},
+			{
+				name:        "MissingPath",
+				contentType: "application/json-patch+json",
+				body:        `[{"op": "replace", "path": "/colour/0", "value": "red"}]`,
+				status:      http.StatusBadRequest,
+				err:         custom_errors.ErrInvalidPatch,
+			},
+			{
+				name:        "NotAProduct",
+				contentType: "application/merge-patch+json",
+				body:        `["not", "a", "product"]`,
+				status:      http.StatusBadRequest,
+				err:         custom_errors.ErrInvalidPatch,
+			},
+			{
+				name:        "FailedTest",
+				contentType: "application/json-patch+json",
+				body:        `[{"op": "test", "path": "/price", "value": 5}, {"op": "replace", "path": "/price", "value": 12.5}]`,
+				status:      http.StatusConflict,
+				err:         custom_errors.ErrPatchTestFailed,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mockService := newMockService()
+				c := patchProduct(mockService, "uuid1", tc.contentType, tc.body)
+
+				err, _ := c.Get("errors")
+
+				// Assertions
+				assert.Equal(t, tc.status, c.Writer.Status())
+				assert.ErrorIs(t, err.(error), tc.err)
+				assert.Nil(t, mockService.patch)
+			})
+		}
+	})
+
+	t.Run("InvalidProduct", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			contentType string
+			body        string
+			messages    []string
+		}{
+			{
+				name:        "RemovedName",
+				contentType: "application/merge-patch+json",
+				body:        `{"name": null, "price": 0}`,
+				messages:    []string{"Name is required", "Price is required"},
+			},
+			{
+				name:        "NegativePrice",
+				contentType: "application/json-patch+json",
+				body:        `[{"op": "replace", "path": "/price", "value": -1}]`,
+				messages:    []string{"Price must be greater than 0"},
+			},
+			{
+				name:        "WrongType",
+				contentType: "application/merge-patch+json",
+				body:        `{"price": "cheap"}`,
+				messages:    []string{"price is invalid"},
+			},
+			{
+				name:        "ChangedID",
+				contentType: "application/json-patch+json",
+				body:        `[{"op": "replace", "path": "/id", "value": "uuid2"}]`,
+				messages:    []string{"id cannot be changed"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mockService := newMockService()
+				c := patchProduct(mockService, "uuid1", tc.contentType, tc.body)
+
+				err, _ := c.Get("errors")
+
+				// Assertions
+				assert.Equal(t, http.StatusBadRequest, c.Writer.Status())
+				if assert.IsType(t, &validators.ValidationError{}, err) {
+					messages := make([]string, 0)
+					for _, e := range err.(*validators.ValidationError).Errors {
+						messages = append(messages, e["message"])
+					}
+					assert.Equal(t, tc.messages, messages)
+				}
+				assert.Nil(t, mockService.patch)
+			})
+		}
+	})
+
+	t.Run("ProductNotFound", func(t *testing.T) {
+		mockService := newMockService()
+		c := patchProduct(mockService, "non_existent_id", "application/merge-patch+json", `{"price": 12.5}`)
+
+		err, _ := c.Get("errors")
+
+		// Assertions
+		assert.Equal(t, http.StatusNotFound, c.Writer.Status())
+		assert.Equal(t, custom_errors.ErrProductNotFound, err)
+		assert.Nil(t, mockService.patch)
+	})
 }
 
 func TestDeleteProductController(t *testing.T) {
@@ -1,12 +1,15 @@
 package tests
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"simpler-products/models"
+	"simpler-products/repositories"
 	"simpler-products/services"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-sql-driver/mysql"
@@ -27,8 +30,8 @@ func TestGetAllProductsService(t *testing.T) {
 	// Create a mock ProductsService
 	log := logrus.New()
 	productService := &services.ProductsService{
-		DB:  db,
-		Log: log,
+		Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:  log,
 	}
 
 	t.Run("Success", func(t *testing.T) {
@@ -37,16 +40,16 @@ func TestGetAllProductsService(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 
 		// Mock the paginated query
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-			AddRow("uuid1", "Product A", "Description A", 10.99).
-			AddRow("uuid2", "Product B", "Description B", 19.95)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+			AddRow("uuid1", "Product A", "Description A", 10.99, 1).
+			AddRow("uuid2", "Product B", "Description B", 19.95, 1)
 
-		dbMock.ExpectQuery("SELECT \\* FROM Products LIMIT \\? OFFSET \\?").
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products ORDER BY id ASC LIMIT \\? OFFSET \\?").
 			WithArgs(10, 0).
 			WillReturnRows(rows)
 
 		// Call the service function
-		products, total, err := productService.GetAllProducts(10, 0)
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: 0})
 
 		// Assertions
 		assert.NoError(t, err)
@@ -72,33 +75,33 @@ func TestGetAllProductsService(t *testing.T) {
 				limit:  5,
 				offset: 0,
 				total:  10,
-				rows: sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-					AddRow("uuid1", "Product A", "Description A", 10.99).
-					AddRow("uuid2", "Product B", "Description B", 19.95).
-					AddRow("uuid3", "Product C", "Description C", 5.50).
-					AddRow("uuid4", "Product D", "Description D", 8.25).
-					AddRow("uuid5", "Product E", "Description E", 15.00),
+				rows: sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+					AddRow("uuid1", "Product A", "Description A", 10.99, 1).
+					AddRow("uuid2", "Product B", "Description B", 19.95, 1).
+					AddRow("uuid3", "Product C", "Description C", 5.50, 1).
+					AddRow("uuid4", "Product D", "Description D", 8.25, 1).
+					AddRow("uuid5", "Product E", "Description E", 15.00, 1),
 			},
 			{
 				name:   "SecondPage",
 				limit:  5,
 				offset: 5,
 				total:  10,
-				rows: sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-					AddRow("uuid6", "Product F", "Description F", 7.75).
-					AddRow("uuid7", "Product G", "Description G", 22.30).
-					AddRow("uuid8", "Product H", "Description H", 3.15).
-					AddRow("uuid9", "Product I", "Description I", 11.80).
-					AddRow("uuid10", "Product J", "Description J", 6.40),
+				rows: sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+					AddRow("uuid6", "Product F", "Description F", 7.75, 1).
+					AddRow("uuid7", "Product G", "Description G", 22.30, 1).
+					AddRow("uuid8", "Product H", "Description H", 3.15, 1).
+					AddRow("uuid9", "Product I", "Description I", 11.80, 1).
+					AddRow("uuid10", "Product J", "Description J", 6.40, 1),
 			},
 			{
 				name:   "PartialLastPage",
 				limit:  5,
 				offset: 10,
 				total:  12,
-				rows: sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-					AddRow("uuid11", "Product K", "Description K", 9.00).
-					AddRow("uuid12", "Product L", "Description L", 4.60),
+				rows: sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+					AddRow("uuid11", "Product K", "Description K", 9.00, 1).
+					AddRow("uuid12", "Product L", "Description L", 4.60, 1),
 			},
 		}
 
@@ -109,12 +112,12 @@ func TestGetAllProductsService(t *testing.T) {
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tc.total))
 
 				// Mock the paginated query
-				dbMock.ExpectQuery("SELECT \\* FROM Products LIMIT \\? OFFSET \\?").
+				dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products ORDER BY id ASC LIMIT \\? OFFSET \\?").
 					WithArgs(tc.limit, tc.offset).
 					WillReturnRows(tc.rows)
 
 				// Call the service function
-				products, total, err := productService.GetAllProducts(tc.limit, tc.offset)
+				products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: tc.limit, Offset: tc.offset})
 
 				// Assertions
 				assert.NoError(t, err)
@@ -135,13 +138,13 @@ func TestGetAllProductsService(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
 
 		// Mock an empty result set for a large offset
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"})
-		dbMock.ExpectQuery("SELECT \\* FROM Products LIMIT \\? OFFSET \\?").
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"})
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products ORDER BY id ASC LIMIT \\? OFFSET \\?").
 			WithArgs(10, 100). // Large offset
 			WillReturnRows(rows)
 
 		// Call the service function
-		products, total, err := productService.GetAllProducts(10, 100)
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: 100})
 
 		// Assertions
 		assert.NoError(t, err)
@@ -160,12 +163,12 @@ func TestGetAllProductsService(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
 
 		// Mock an error when fetching the paginated products
-		dbMock.ExpectQuery("SELECT \\* FROM Products LIMIT \\? OFFSET \\?").
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products ORDER BY id ASC LIMIT \\? OFFSET \\?").
 			WithArgs(10, 0).
 			WillReturnError(errors.New("database error"))
 
 		// Call the service function
-		products, total, err := productService.GetAllProducts(10, 0)
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: 0})
 
 		// Assertions
 		assert.Error(t, err)
@@ -184,11 +187,11 @@ func TestGetAllProductsService(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 
 		// Mock an empty result set
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"})
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"})
 		dbMock.ExpectQuery("SELECT (.+) FROM Products").WillReturnRows(rows)
 
 		// Call the service function
-		products, total, err := productService.GetAllProducts(10, 0)
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: 0})
 
 		// Assertions
 		assert.NoError(t, err)
@@ -207,15 +210,15 @@ func TestGetAllProductsService(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 
 		// Mock the paginated query to return rows with an incompatible data type
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-			AddRow("uuid1", "Product A", "Description A", "invalid_price") // Invalid price format
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+			AddRow("uuid1", "Product A", "Description A", "invalid_price", 1) // Invalid price format
 
-		dbMock.ExpectQuery("SELECT \\* FROM Products LIMIT \\? OFFSET \\?").
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products ORDER BY id ASC LIMIT \\? OFFSET \\?").
 			WithArgs(10, 0).
 			WillReturnRows(rows)
 
 		// Call the service function
-		products, total, err := productService.GetAllProducts(10, 0)
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: 0})
 
 		// Assertions
 		assert.Error(t, err)
@@ -234,7 +237,7 @@ func TestGetAllProductsService(t *testing.T) {
 			WillReturnError(errors.New("database error during count"))
 
 		// Call the service function
-		products, total, err := productService.GetAllProducts(10, 0)
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: 0})
 
 		// Assertions
 		assert.Error(t, err)
@@ -247,9 +250,75 @@ func TestGetAllProductsService(t *testing.T) {
 		}
 	})
 
+	t.Run("FiltersAndSort", func(t *testing.T) {
+		priceMin, priceMax := 5.0, 50.0
+		query := models.ProductQuery{
+			Filter: models.ProductFilter{Name: "50%_off", PriceMin: &priceMin, PriceMax: &priceMax},
+			Sort:   []models.SortField{{Field: "price", Descending: true}, {Field: "name"}},
+			Limit:  10,
+			Offset: 0,
+		}
+
+		// Mock the count query, filtered like the page
+		dbMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM Products WHERE LOWER\\(name\\) LIKE \\? ESCAPE '!' AND price >= \\? AND price <= \\?").
+			WithArgs("%50!%!_off%", 5.0, 50.0).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		// Mock the paginated query, ordered by the requested fields then by id
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products WHERE LOWER\\(name\\) LIKE \\? ESCAPE '!' AND price >= \\? AND price <= \\? ORDER BY price DESC, LOWER\\(name\\) ASC, id ASC LIMIT \\? OFFSET \\?").
+			WithArgs("%50!%!_off%", 5.0, 50.0, 10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "50%_off Product", "Description", 20.0, 1))
+
+		// Call the service function
+		products, total, err := productService.GetAllProducts(context.Background(), query)
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(products))
+		assert.Equal(t, 1, total)
+
+		// Ensure all expectations were met
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("Keyset", func(t *testing.T) {
+		query := models.ProductQuery{
+			Sort:   []models.SortField{{Field: "price", Descending: true}},
+			Limit:  10,
+			Offset: 20, // ignored in favour of the keyset
+			Keyset: &models.Keyset{ID: "uuid2", Name: "Product B", Price: 19.95, Backward: true},
+		}
+
+		dbMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM Products$").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		// Going backward, the comparisons and the order are reversed
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products WHERE \\(\\(price > \\?\\) OR \\(price = \\? AND id < \\?\\)\\) ORDER BY price ASC, id DESC LIMIT \\? OFFSET \\?").
+			WithArgs(19.95, 19.95, "uuid2", 10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Description A", 19.95, 1).
+				AddRow("uuid3", "Product C", "Description C", 29.95, 1))
+
+		// Call the service function
+		products, total, err := productService.GetAllProducts(context.Background(), query)
+
+		// Assertions, rows come back in the requested order
+		assert.NoError(t, err)
+		assert.Equal(t, 3, total)
+		assert.Equal(t, []string{"uuid3", "uuid1"}, []string{products[0].ID, products[1].ID})
+
+		// Ensure all expectations were met
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
 	t.Run("InvalidLimit", func(t *testing.T) {
 		// Call the service function with an invalid limit
-		products, total, err := productService.GetAllProducts(0, 0)
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 0, Offset: 0})
 
 		// Assertions
 		assert.Error(t, err)
@@ -259,7 +328,7 @@ func TestGetAllProductsService(t *testing.T) {
 
 	t.Run("InvalidOffset", func(t *testing.T) {
 		// Call the service function with an invalid offset
-		products, total, err := productService.GetAllProducts(10, -1)
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: -1})
 
 		// Assertions
 		assert.Error(t, err)
@@ -279,21 +348,21 @@ func TestGetProductByIdService(t *testing.T) {
 	// Create a mock ProductsService
 	log := logrus.New()
 	productService := &services.ProductsService{
-		DB:  db,
-		Log: log,
+		Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:  log,
 	}
 
 	t.Run("Success", func(t *testing.T) {
 		// Mock the database query to return a product
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-			AddRow("uuid1", "Product A", "Description A", 10.99)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+			AddRow("uuid1", "Product A", "Description A", 10.99, 1)
 
 		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
 			WithArgs("uuid1").
 			WillReturnRows(rows)
 
 		// Call the service function
-		product, err := productService.GetProductById("uuid1")
+		product, err := productService.GetProductById(context.Background(), "uuid1")
 
 		// Assertions
 		assert.NoError(t, err)
@@ -314,7 +383,7 @@ func TestGetProductByIdService(t *testing.T) {
 			WillReturnError(sql.ErrNoRows)
 
 		// Call the service function
-		product, err := productService.GetProductById("non_existent_id")
+		product, err := productService.GetProductById(context.Background(), "non_existent_id")
 
 		// Assertions
 		assert.Error(t, err)
@@ -329,7 +398,7 @@ func TestGetProductByIdService(t *testing.T) {
 
 	t.Run("InvalidIdFormat", func(t *testing.T) {
 		// Call the service function with an id that has an invalid format
-		product, err := productService.GetProductById("invalid_id")
+		product, err := productService.GetProductById(context.Background(), "invalid_id")
 
 		// Assertions
 		assert.Error(t, err)
@@ -348,7 +417,7 @@ func TestGetProductByIdService(t *testing.T) {
 			WillReturnError(errors.New("database error"))
 
 		// Call the service function
-		product, err := productService.GetProductById("uuid1")
+		product, err := productService.GetProductById(context.Background(), "uuid1")
 
 		// Assertions
 		assert.Error(t, err)
@@ -362,15 +431,15 @@ func TestGetProductByIdService(t *testing.T) {
 
 	t.Run("DatabaseErrorScanningRow", func(t *testing.T) {
 		// Mock the database query to return a row, but simulate an error during scanning
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-			AddRow("uuid1", "Product A", "Description A", "invalid_price") // Invalid price format
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+			AddRow("uuid1", "Product A", "Description A", "invalid_price", 1) // Invalid price format
 
 		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
 			WithArgs("uuid1").
 			WillReturnRows(rows)
 
 		// Call the service function
-		product, err := productService.GetProductById("uuid1")
+		product, err := productService.GetProductById(context.Background(), "uuid1")
 
 		// Assertions
 		assert.Error(t, err)
@@ -392,7 +461,7 @@ func TestGetProductByIdService(t *testing.T) {
 			WillReturnError(sql.ErrNoRows)
 
 		// Call the service function
-		product, err := productService.GetProductById(veryLongID)
+		product, err := productService.GetProductById(context.Background(), veryLongID)
 
 		// Assertions
 		assert.Error(t, err)
@@ -416,8 +485,8 @@ func TestAddProductService(t *testing.T) {
 	// Create a mock ProductsService
 	log := logrus.New()
 	productService := &services.ProductsService{
-		DB:  db,
-		Log: log,
+		Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:  log,
 	}
 
 	t.Run("Success", func(t *testing.T) {
@@ -434,7 +503,7 @@ func TestAddProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		err := productService.AddProduct(newProduct)
+		err := productService.AddProduct(context.Background(), newProduct)
 
 		// Assertions
 		assert.NoError(t, err)
@@ -460,7 +529,7 @@ func TestAddProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		err := productService.AddProduct(newProduct)
+		err := productService.AddProduct(context.Background(), newProduct)
 
 		// Assertions
 		assert.Error(t, err)
@@ -479,7 +548,7 @@ func TestAddProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		err := productService.AddProduct(invalidProduct)
+		err := productService.AddProduct(context.Background(), invalidProduct)
 
 		// Assertions
 		assert.Error(t, err)
@@ -499,7 +568,7 @@ func TestAddProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		err := productService.AddProduct(newProduct)
+		err := productService.AddProduct(context.Background(), newProduct)
 
 		// Assertions
 		assert.Error(t, err)
@@ -544,7 +613,7 @@ func TestAddProductService(t *testing.T) {
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				// Call the service function
-				err := productService.AddProduct(tc.productData)
+				err := productService.AddProduct(context.Background(), tc.productData)
 
 				// Assertions
 				assert.NoError(t, err)
@@ -569,7 +638,7 @@ func TestAddProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		err := productService.AddProduct(newProduct)
+		err := productService.AddProduct(context.Background(), newProduct)
 
 		assert.Error(t, err)
 
@@ -614,7 +683,7 @@ func TestAddProductService(t *testing.T) {
 				}
 
 				// Call the service function
-				err := productService.AddProduct(newProduct)
+				err := productService.AddProduct(context.Background(), newProduct)
 
 				// Assertions
 				if tc.valid {
@@ -644,18 +713,18 @@ func TestUpdateProductService(t *testing.T) {
 	// Create a mock ProductsService
 	log := logrus.New()
 	productService := &services.ProductsService{
-		DB:  db,
-		Log: log,
+		Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:  log,
 	}
 
 	t.Run("Success", func(t *testing.T) {
 		// Mock the database Exec for update and the query to fetch the updated product
-		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\? WHERE id = \\?").
+		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\?, version = version \\+ 1 WHERE id = \\?").
 			WithArgs("Updated Product", "Updated Description", 12.99, "uuid1").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-			AddRow("uuid1", "Updated Product", "Updated Description", 12.99)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+			AddRow("uuid1", "Updated Product", "Updated Description", 12.99, 1)
 
 		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
 			WithArgs("uuid1").
@@ -669,7 +738,7 @@ func TestUpdateProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		product, err := productService.UpdateProduct("uuid1", updatedProduct)
+		product, err := productService.UpdateProduct(context.Background(), "uuid1", updatedProduct)
 
 		// Assertions
 		assert.NoError(t, err)
@@ -685,7 +754,7 @@ func TestUpdateProductService(t *testing.T) {
 
 	t.Run("ProductNotFound", func(t *testing.T) {
 		// Mock the database Exec to return no rows affected (product not found)
-		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\? WHERE id = \\?").
+		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\?, version = version \\+ 1 WHERE id = \\?").
 			WithArgs("Updated Product", "Updated Description", 12.99, "non_existent_id").
 			WillReturnResult(sqlmock.NewResult(0, 0))
 
@@ -701,7 +770,7 @@ func TestUpdateProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		product, err := productService.UpdateProduct("non_existent_id", updatedProduct)
+		product, err := productService.UpdateProduct(context.Background(), "non_existent_id", updatedProduct)
 
 		// Assertions
 		assert.Error(t, err)
@@ -716,7 +785,7 @@ func TestUpdateProductService(t *testing.T) {
 
 	t.Run("DatabaseError", func(t *testing.T) {
 		// Mock a database error during the update
-		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\? WHERE id = \\?").
+		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\?, version = version \\+ 1 WHERE id = \\?").
 			WithArgs("Updated Product", "Updated Description", 12.99, "uuid1").
 			WillReturnError(errors.New("database error"))
 
@@ -728,7 +797,7 @@ func TestUpdateProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		product, err := productService.UpdateProduct("uuid1", updatedProduct)
+		product, err := productService.UpdateProduct(context.Background(), "uuid1", updatedProduct)
 
 		// Assertions
 		assert.Error(t, err)
@@ -748,7 +817,7 @@ func TestUpdateProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		product, err := productService.UpdateProduct("uuid1", invalidProduct)
+		product, err := productService.UpdateProduct(context.Background(), "uuid1", invalidProduct)
 
 		// Assertions
 		assert.Error(t, err)
@@ -762,12 +831,12 @@ func TestUpdateProductService(t *testing.T) {
 
 	t.Run("DatabaseErrorFetchingUpdatedProduct", func(t *testing.T) {
 		// Mock a successful update
-		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\? WHERE id = \\?").
+		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\?, version = version \\+ 1 WHERE id = \\?").
 			WithArgs("Updated Product", "Updated Description", 12.99, "uuid1").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		// Mock an error when fetching the updated product
-		dbMock.ExpectQuery("SELECT \\* FROM Products WHERE id = \\?").
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products WHERE id = \\?").
 			WithArgs("uuid1").
 			WillReturnError(errors.New("database error fetching updated product"))
 
@@ -779,7 +848,7 @@ func TestUpdateProductService(t *testing.T) {
 		}
 
 		// Call the service function
-		product, err := productService.UpdateProduct("uuid1", updatedProduct)
+		product, err := productService.UpdateProduct(context.Background(), "uuid1", updatedProduct)
 
 		// Assertions
 		assert.Error(t, err)
@@ -790,6 +859,118 @@ func TestUpdateProductService(t *testing.T) {
 			t.Errorf("there were unfulfilled expectations: %s", err)
 		}
 	})
+
+	t.Run("VersionMismatch", func(t *testing.T) {
+		// The guarded update matches no row, while the product exists at another version
+		dbMock.ExpectExec("UPDATE Products SET name = \\?, description = \\?, price = \\?, version = version \\+ 1 WHERE id = \\? AND version = \\?").
+			WithArgs("Updated Product", "Updated Description", 12.99, "uuid1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Description A", 10.99, 2))
+
+		product, err := productService.UpdateProduct(context.Background(), "uuid1", &models.Product{
+			Name:        "Updated Product",
+			Description: "Updated Description",
+			Price:       12.99,
+			Version:     1,
+		})
+
+		assert.True(t, errors.Is(err, custom_errors.ErrVersionMismatch))
+		assert.Nil(t, product)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestPatchProductService(t *testing.T) {
+	// Set up mock database
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// Create a mock ProductsService
+	log := logrus.New()
+	productService := &services.ProductsService{
+		Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:  log,
+	}
+
+	t.Run("UpdatesOnlyChangedColumns", func(t *testing.T) {
+		price := 12.99
+		description := "Patched Description"
+
+		// Mock the database Exec for the changed columns and the query to fetch the patched product
+		dbMock.ExpectExec("UPDATE Products SET description = \\?, price = \\?, version = version \\+ 1 WHERE id = \\?").
+			WithArgs("Patched Description", 12.99, "uuid1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Patched Description", 12.99, 2))
+
+		// Call the service function
+		product, err := productService.PatchProduct(context.Background(), "uuid1", models.ProductPatch{Description: &description, Price: &price})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Product{ID: "uuid1", Name: "Product A", Description: "Patched Description", Price: 12.99, Version: 2}, product)
+
+		// Ensure all expectations were met
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("EmptyPatchDoesNotWrite", func(t *testing.T) {
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Description A", 10.99, 1))
+
+		// Call the service function
+		product, err := productService.PatchProduct(context.Background(), "uuid1", models.ProductPatch{})
+
+		// Assertions
+		assert.NoError(t, err)
+		assert.Equal(t, "Product A", product.Name)
+
+		// Ensure all expectations were met
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("ProductNotFound", func(t *testing.T) {
+		price := 12.99
+
+		dbMock.ExpectExec("UPDATE Products SET price = \\?, version = version \\+ 1 WHERE id = \\?").
+			WithArgs(12.99, "non_existent_id").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("non_existent_id").
+			WillReturnError(sql.ErrNoRows)
+
+		// Call the service function
+		product, err := productService.PatchProduct(context.Background(), "non_existent_id", models.ProductPatch{Price: &price})
+
+		// Assertions
+		assert.Nil(t, product)
+		assert.Equal(t, custom_errors.ErrProductNotFound, err)
+
+		// Ensure all expectations were met
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
 }
 
 func TestDeleteProductService(t *testing.T) {
@@ -803,16 +984,16 @@ func TestDeleteProductService(t *testing.T) {
 	// Create a mock ProductsService
 	log := logrus.New()
 	productService := &services.ProductsService{
-		DB:  db,
-		Log: log,
+		Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+		Log:  log,
 	}
 
 	t.Run("Success", func(t *testing.T) {
 		// Mock the database query to ensure the product exists before deletion
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-			AddRow("uuid1", "Product A", "Description A", 10.99)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+			AddRow("uuid1", "Product A", "Description A", 10.99, 1)
 
-		dbMock.ExpectQuery("SELECT \\* FROM Products WHERE id = \\?").
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products WHERE id = \\?").
 			WithArgs("uuid1").
 			WillReturnRows(rows)
 
@@ -822,7 +1003,7 @@ func TestDeleteProductService(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		// Call the service function
-		err := productService.DeleteProduct("uuid1")
+		err := productService.DeleteProduct(context.Background(), "uuid1", 0)
 
 		// Assertions
 		assert.NoError(t, err)
@@ -835,12 +1016,12 @@ func TestDeleteProductService(t *testing.T) {
 
 	t.Run("ProductNotFound", func(t *testing.T) {
 		// Mock the database query to return no rows (product not found)
-		dbMock.ExpectQuery("SELECT \\* FROM Products WHERE id = \\?").
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products WHERE id = \\?").
 			WithArgs("non_existent_id").
 			WillReturnError(sql.ErrNoRows)
 
 		// Call the service function
-		err := productService.DeleteProduct("non_existent_id")
+		err := productService.DeleteProduct(context.Background(), "non_existent_id", 0)
 
 		// Assertions
 		assert.Error(t, err)
@@ -854,12 +1035,12 @@ func TestDeleteProductService(t *testing.T) {
 
 	t.Run("DatabaseErrorDuringFetch", func(t *testing.T) {
 		// Mock a database error during the product fetch
-		dbMock.ExpectQuery("SELECT \\* FROM Products WHERE id = \\?").
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products WHERE id = \\?").
 			WithArgs("uuid1").
 			WillReturnError(errors.New("database error"))
 
 		// Call the service function
-		err := productService.DeleteProduct("uuid1")
+		err := productService.DeleteProduct(context.Background(), "uuid1", 0)
 
 		// Assertions
 		assert.Error(t, err)
@@ -871,10 +1052,10 @@ func TestDeleteProductService(t *testing.T) {
 
 	t.Run("DatabaseErrorDuringDelete", func(t *testing.T) {
 		// Mock a successful product fetch
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price"}).
-			AddRow("uuid1", "Product A", "Description A", 10.99)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+			AddRow("uuid1", "Product A", "Description A", 10.99, 1)
 
-		dbMock.ExpectQuery("SELECT \\* FROM Products WHERE id = \\?").
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products WHERE id = \\?").
 			WithArgs("uuid1").
 			WillReturnRows(rows)
 
@@ -884,7 +1065,7 @@ func TestDeleteProductService(t *testing.T) {
 			WillReturnError(errors.New("database error"))
 
 		// Call the service function
-		err := productService.DeleteProduct("uuid1")
+		err := productService.DeleteProduct(context.Background(), "uuid1", 0)
 
 		// Assertions
 		assert.Error(t, err)
@@ -894,4 +1075,294 @@ func TestDeleteProductService(t *testing.T) {
 			t.Errorf("there were unfulfilled expectations: %s", err)
 		}
 	})
+
+	t.Run("VersionMismatch", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+			AddRow("uuid1", "Product A", "Description A", 10.99, 2)
+
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillReturnRows(rows)
+
+		// The guarded delete matches no row, the product was modified after it was fetched
+		dbMock.ExpectExec("DELETE FROM Products WHERE id = \\? AND version = \\?").
+			WithArgs("uuid1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Description A", 10.99, 3))
+
+		err := productService.DeleteProduct(context.Background(), "uuid1", 1)
+
+		assert.True(t, errors.Is(err, custom_errors.ErrVersionMismatch))
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestPostgresProductsService(t *testing.T) {
+	// Set up mock database
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// Create a ProductsService backed by the PostgreSQL dialect
+	log := logrus.New()
+	productService := &services.ProductsService{
+		Repo: repositories.NewSQLProductRepository(db, repositories.Postgres),
+		Log:  log,
+	}
+
+	t.Run("GetAllProductsUsesDollarPlaceholders", func(t *testing.T) {
+		dbMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM Products").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products ORDER BY id ASC LIMIT \\$1 OFFSET \\$2").
+			WithArgs(10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Description A", 10.99, 1))
+
+		products, total, err := productService.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: 0})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(products))
+		assert.Equal(t, 1, total)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("GetAllProductsFiltersUseDollarPlaceholders", func(t *testing.T) {
+		priceMin := 5.0
+		query := models.ProductQuery{
+			Filter: models.ProductFilter{Name: "product", PriceMin: &priceMin},
+			Sort:   []models.SortField{{Field: "name"}},
+			Limit:  10,
+			Offset: 0,
+		}
+
+		dbMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM Products WHERE LOWER\\(name\\) LIKE \\$1 ESCAPE '!' AND price >= \\$2").
+			WithArgs("%product%", 5.0).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		dbMock.ExpectQuery("SELECT id, name, description, price, version FROM Products WHERE LOWER\\(name\\) LIKE \\$1 ESCAPE '!' AND price >= \\$2 ORDER BY LOWER\\(name\\) ASC, id ASC LIMIT \\$3 OFFSET \\$4").
+			WithArgs("%product%", 5.0, 10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Description A", 10.99, 1))
+
+		products, total, err := productService.GetAllProducts(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(products))
+		assert.Equal(t, 1, total)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("GetProductFacetsUsesDollarPlaceholders", func(t *testing.T) {
+		priceMin := 5.0
+		filter := models.ProductFilter{PriceMin: &priceMin}
+
+		dbMock.ExpectQuery("SELECT COUNT\\(\\*\\), MIN\\(price\\), MAX\\(price\\), AVG\\(price\\) FROM Products WHERE price >= \\$1").
+			WithArgs(5.0).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "min", "max", "avg"}).AddRow(4, 5.0, 40.0, 18.755))
+
+		// Quartile edges are read at their position in the price order
+		for _, position := range []int{1, 2, 3} {
+			dbMock.ExpectQuery("SELECT price FROM Products WHERE price >= \\$1 ORDER BY price LIMIT 1 OFFSET \\$2").
+				WithArgs(5.0, position).
+				WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(float64(10 * position)))
+		}
+
+		dbMock.ExpectQuery("SELECT COUNT\\(CASE WHEN price < \\$1 THEN 1 END\\), COUNT\\(CASE WHEN price >= \\$2 AND price < \\$3 THEN 1 END\\), "+
+			"COUNT\\(CASE WHEN price >= \\$4 AND price < \\$5 THEN 1 END\\), COUNT\\(CASE WHEN price >= \\$6 THEN 1 END\\) FROM Products WHERE price >= \\$7").
+			WithArgs(10.0, 10.0, 20.0, 20.0, 30.0, 30.0, 5.0).
+			WillReturnRows(sqlmock.NewRows([]string{"b0", "b1", "b2", "b3"}).AddRow(1, 1, 1, 1))
+
+		facets, err := productService.GetProductFacets(context.Background(), filter, models.FacetQuery{Price: &models.PriceBuckets{Quantiles: 4}})
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, facets.Price) {
+			assert.Equal(t, 4, facets.Price.Count)
+			assert.Equal(t, 18.76, *facets.Price.Avg)
+			assert.Len(t, facets.Price.Buckets, 4)
+			assert.Equal(t, 20.0, *facets.Price.Buckets[2].From)
+		}
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("PatchProductReturnsPatchedRow", func(t *testing.T) {
+		name := "Patched Product"
+
+		dbMock.ExpectQuery("UPDATE Products SET name = \\$1, version = version \\+ 1 WHERE id = \\$2 RETURNING id, name, description, price, version").
+			WithArgs("Patched Product", "uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Patched Product", "Description A", 10.99, 1))
+
+		product, err := productService.PatchProduct(context.Background(), "uuid1", models.ProductPatch{Name: &name})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Patched Product", product.Name)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("AddProductReturnsInsertedRow", func(t *testing.T) {
+		dbMock.ExpectQuery("INSERT INTO Products \\(id, name, description, price\\) VALUES \\(\\$1, \\$2, \\$3, \\$4\\) RETURNING id, name, description, price, version").
+			WithArgs(sqlmock.AnyArg(), "New Product", "Description", 9.99).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "New Product", "Description", 9.99, 1))
+
+		newProduct := &models.Product{
+			Name:        "New Product",
+			Description: "Description",
+			Price:       9.99,
+		}
+
+		err := productService.AddProduct(context.Background(), newProduct)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "uuid1", newProduct.ID)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("UpdateProductInSingleRoundTrip", func(t *testing.T) {
+		dbMock.ExpectQuery("UPDATE Products SET name = \\$1, description = \\$2, price = \\$3, version = version \\+ 1 WHERE id = \\$4 RETURNING id, name, description, price, version").
+			WithArgs("Updated Product", "Updated Description", 12.99, "uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Updated Product", "Updated Description", 12.99, 1))
+
+		product, err := productService.UpdateProduct(context.Background(), "uuid1", &models.Product{
+			Name:        "Updated Product",
+			Description: "Updated Description",
+			Price:       12.99,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Updated Product", product.Name)
+
+		// No follow-up SELECT is expected
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("UpdateProductNotFound", func(t *testing.T) {
+		dbMock.ExpectQuery("UPDATE Products SET (.+), version = version \\+ 1 WHERE id = \\$4 RETURNING (.+)").
+			WithArgs("Updated Product", "Updated Description", 12.99, "non_existent_id").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}))
+
+		product, err := productService.UpdateProduct(context.Background(), "non_existent_id", &models.Product{
+			Name:        "Updated Product",
+			Description: "Updated Description",
+			Price:       12.99,
+		})
+
+		assert.True(t, errors.Is(err, custom_errors.ErrProductNotFound))
+		assert.Nil(t, product)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("PatchProductVersionMismatch", func(t *testing.T) {
+		price := 15.0
+
+		dbMock.ExpectQuery("UPDATE Products SET price = \\$1, version = version \\+ 1 WHERE id = \\$2 AND version = \\$3 RETURNING (.+)").
+			WithArgs(15.0, "uuid1", 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}))
+
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = \\$1").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}).
+				AddRow("uuid1", "Product A", "Description A", 10.99, 4))
+
+		product, err := productService.PatchProduct(context.Background(), "uuid1", models.ProductPatch{Price: &price, Version: 3})
+
+		assert.True(t, errors.Is(err, custom_errors.ErrVersionMismatch))
+		assert.Nil(t, product)
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestProductsServiceContextErrors(t *testing.T) {
+	// Set up mock database
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	log := logrus.New()
+
+	t.Run("QueryTimeout", func(t *testing.T) {
+		productService := &services.ProductsService{
+			Repo:         repositories.NewSQLProductRepository(db, repositories.MySQL),
+			Log:          log,
+			QueryTimeout: 10 * time.Millisecond,
+		}
+
+		dbMock.ExpectQuery("SELECT (.+) FROM Products WHERE id = ?").
+			WithArgs("uuid1").
+			WillDelayFor(time.Second).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "version"}))
+
+		product, err := productService.GetProductById(context.Background(), "uuid1")
+
+		assert.True(t, errors.Is(err, custom_errors.ErrRequestTimeout))
+		assert.Nil(t, product)
+	})
+
+	t.Run("ClientCanceled", func(t *testing.T) {
+		productService := &services.ProductsService{
+			Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+			Log:  log,
+		}
+
+		// The client has already gone away when the query runs
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		products, total, err := productService.GetAllProducts(ctx, models.ProductQuery{Limit: 10, Offset: 0})
+
+		assert.True(t, errors.Is(err, custom_errors.ErrRequestCanceled))
+		assert.Nil(t, products)
+		assert.Equal(t, 0, total)
+	})
+
+	t.Run("ServerShuttingDown", func(t *testing.T) {
+		productService := &services.ProductsService{
+			Repo: repositories.NewSQLProductRepository(db, repositories.MySQL),
+			Log:  log,
+		}
+
+		// The shutdown deadline has already passed when the query runs
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(custom_errors.ErrServerShuttingDown)
+
+		err := productService.DeleteProduct(ctx, "uuid1", 0)
+
+		assert.True(t, errors.Is(err, custom_errors.ErrServerShuttingDown))
+	})
 }
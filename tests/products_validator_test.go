@@ -128,3 +128,199 @@ func TestValidateProduct(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, c.Writer.Status())
 	})
 }
+
+func TestValidateProductQuery(t *testing.T) {
+	// Set Gin to TestMode
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(rawQuery string) *gin.Context {
+		req, _ := http.NewRequest("GET", "/products?"+rawQuery, nil)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		return c
+	}
+
+	t.Run("NoParameters", func(t *testing.T) {
+		query, err := validators.ValidateProductQuery(newContext(""))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.ProductQuery{}, query)
+	})
+
+	t.Run("FiltersAndSort", func(t *testing.T) {
+		query, err := validators.ValidateProductQuery(newContext("name=%20Phone%20&price_min=10&price_max=99.5&sort=price,-name"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Phone", query.Filter.Name)
+		assert.Equal(t, 10.0, *query.Filter.PriceMin)
+		assert.Equal(t, 99.5, *query.Filter.PriceMax)
+		assert.Equal(t, []models.SortField{
+			{Field: "price"},
+			{Field: "name", Descending: true},
+		}, query.Sort)
+	})
+
+	t.Run("InvalidParameters", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			rawQuery string
+			messages []string
+		}{
+			{
+				name:     "Prices",
+				rawQuery: "price_min=cheap&price_max=-1",
+				messages: []string{"price_min must be a non-negative number", "price_max must be a non-negative number"},
+			},
+			{
+				name:     "PriceRange",
+				rawQuery: "price_min=20&price_max=10",
+				messages: []string{"price_min must not be greater than price_max"},
+			},
+			{
+				name:     "UnknownSortField",
+				rawQuery: "sort=price,description",
+				messages: []string{"cannot sort by 'description', allowed fields are: id, name, price"},
+			},
+			{
+				name:     "RepeatedSortField",
+				rawQuery: "sort=price,-price",
+				messages: []string{"cannot sort by 'price' more than once"},
+			},
+			{
+				name:     "EmptySortField",
+				rawQuery: "sort=name,,-",
+				messages: []string{
+					"sort must be a comma-separated list of fields, each optionally prefixed with '-' for descending order",
+					"sort must be a comma-separated list of fields, each optionally prefixed with '-' for descending order",
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				c := newContext(tc.rawQuery)
+
+				// Call the validator function
+				query, err := validators.ValidateProductQuery(c)
+
+				// Assertions
+				assert.Nil(t, query)
+				var validationErr *validators.ValidationError
+				if assert.True(t, errors.As(err, &validationErr)) {
+					messages := make([]string, 0)
+					for _, e := range validationErr.Errors {
+						messages = append(messages, e["message"])
+					}
+					assert.Equal(t, tc.messages, messages)
+				}
+				assert.Equal(t, http.StatusBadRequest, c.Writer.Status())
+			})
+		}
+	})
+}
+
+func TestValidateFacetQuery(t *testing.T) {
+	// Set Gin to TestMode
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(rawQuery string) *gin.Context {
+		req, _ := http.NewRequest("GET", "/products?"+rawQuery, nil)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		return c
+	}
+
+	t.Run("NoFacets", func(t *testing.T) {
+		query, err := validators.ValidateFacetQuery(newContext("name=lamp"))
+
+		assert.NoError(t, err)
+		assert.Nil(t, query)
+	})
+
+	t.Run("DefaultQuantiles", func(t *testing.T) {
+		query, err := validators.ValidateFacetQuery(newContext("facets=price"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.FacetQuery{Price: &models.PriceBuckets{Quantiles: 4}}, query)
+	})
+
+	t.Run("Quantiles", func(t *testing.T) {
+		query, err := validators.ValidateFacetQuery(newContext("facets=price&price_quantiles=10"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.FacetQuery{Price: &models.PriceBuckets{Quantiles: 10}}, query)
+	})
+
+	t.Run("Edges", func(t *testing.T) {
+		query, err := validators.ValidateFacetQuery(newContext("facets=price&price_buckets=10,%2049.99,100"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.FacetQuery{Price: &models.PriceBuckets{Edges: []float64{10, 49.99, 100}}}, query)
+	})
+
+	t.Run("InvalidParameters", func(t *testing.T) {
+		bucketsMessage := "price_buckets must be a comma-separated list of up to 19 increasing non-negative numbers"
+		testCases := []struct {
+			name     string
+			rawQuery string
+			messages []string
+		}{
+			{
+				name:     "UnknownFacet",
+				rawQuery: "facets=price,colour",
+				messages: []string{"cannot compute facet 'colour', allowed facets are: price"},
+			},
+			{
+				name:     "BucketsWithoutFacet",
+				rawQuery: "price_buckets=10",
+				messages: []string{"price_buckets and price_quantiles require facets=price"},
+			},
+			{
+				name:     "BucketsAndQuantiles",
+				rawQuery: "facets=price&price_buckets=10&price_quantiles=4",
+				messages: []string{"price_buckets and price_quantiles cannot be combined"},
+			},
+			{
+				name:     "DecreasingEdges",
+				rawQuery: "facets=price&price_buckets=50,10",
+				messages: []string{bucketsMessage},
+			},
+			{
+				name:     "NegativeEdge",
+				rawQuery: "facets=price&price_buckets=-1,10",
+				messages: []string{bucketsMessage},
+			},
+			{
+				name:     "TooManyEdges",
+				rawQuery: "facets=price&price_buckets=1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20",
+				messages: []string{bucketsMessage},
+			},
+			{
+				name:     "Quantiles",
+				rawQuery: "facets=price&price_quantiles=1",
+				messages: []string{"price_quantiles must be a number between 2 and 20"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				c := newContext(tc.rawQuery)
+
+				// Call the validator function
+				query, err := validators.ValidateFacetQuery(c)
+
+				// Assertions
+				assert.Nil(t, query)
+				var validationErr *validators.ValidationError
+				if assert.True(t, errors.As(err, &validationErr)) {
+					messages := make([]string, 0)
+					for _, e := range validationErr.Errors {
+						messages = append(messages, e["message"])
+					}
+					assert.Equal(t, tc.messages, messages)
+				}
+				assert.Equal(t, http.StatusBadRequest, c.Writer.Status())
+			})
+		}
+	})
+}
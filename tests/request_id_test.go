@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"simpler-products/logging"
+	"simpler-products/middlewares"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/services"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	router := newRouter(t, repositories.NewInMemoryProductRepository())
+
+	testCases := []struct {
+		name      string
+		requestID string
+		reused    bool
+	}{
+		{name: "Generated when missing", requestID: "", reused: false},
+		{name: "Reused when valid", requestID: "client-request-42", reused: true},
+		{name: "Replaced when containing spaces", requestID: "not a valid id", reused: false},
+		{name: "Replaced when too long", requestID: strings.Repeat("a", 129), reused: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/v1/products", nil)
+			if tc.requestID != "" {
+				req.Header.Set(middlewares.RequestIDHeader, tc.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(middlewares.RequestIDHeader)
+			if tc.reused {
+				assert.Equal(t, tc.requestID, requestID)
+			} else {
+				_, err := uuid.Parse(requestID)
+				assert.NoError(t, err, "expected a generated UUID, got %q", requestID)
+			}
+
+			var response struct {
+				RequestID string `json:"request_id"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, requestID, response.RequestID)
+		})
+	}
+}
+
+func TestServiceLogsCarryRequestID(t *testing.T) {
+	var output bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&output)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	log.SetLevel(logrus.DebugLevel)
+
+	ps := &services.ProductsService{
+		Repo: repositories.NewInMemoryProductRepository(),
+		Log:  log,
+	}
+
+	ctx := logging.WithRequestID(context.Background(), log, "request-1")
+	_, _, err := ps.GetAllProducts(ctx, models.ProductQuery{Limit: 10, Offset: 0})
+	assert.NoError(t, err)
+
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+	assert.Equal(t, "request-1", entry["request_id"])
+
+	// Outside of a request the service logs through its logger
+	output.Reset()
+	_, _, err = ps.GetAllProducts(context.Background(), models.ProductQuery{Limit: 10, Offset: 0})
+	assert.NoError(t, err)
+
+	entry = nil
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+	assert.NotContains(t, entry, "request_id")
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	custom_errors "simpler-products/errors"
 	"simpler-products/middlewares"
 	"simpler-products/validators"
 	"testing"
@@ -126,6 +127,39 @@ func TestResponseFormatter(t *testing.T) {
 		assert.Equal(t, []interface{}{map[string]interface{}{"message": "some error occurred"}}, response["errors"])
 	})
 
+	t.Run("AbortedRequestErrors", func(t *testing.T) {
+		testCases := []struct {
+			name       string
+			err        error
+			statusCode int
+		}{
+			{name: "ClientCanceled", err: custom_errors.ErrRequestCanceled, statusCode: middlewares.StatusClientClosedRequest},
+			{name: "ServerShuttingDown", err: custom_errors.ErrServerShuttingDown, statusCode: http.StatusServiceUnavailable},
+			{name: "Timeout", err: custom_errors.ErrRequestTimeout, statusCode: http.StatusGatewayTimeout},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Set("errors", tc.err)
+
+				// Call the middleware
+				middlewares.ResponseFormatter(log)(c)
+
+				// Assertions
+				assert.Equal(t, tc.statusCode, w.Code)
+
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+
+				assert.Equal(t, tc.statusCode, int(response["status"].(float64)))
+				assert.Equal(t, []interface{}{map[string]interface{}{"message": tc.err.Error()}}, response["errors"])
+			})
+		}
+	})
+
 	t.Run("NoDataNoErrors", func(t *testing.T) {
 		// Create a Gin context without setting any data or errors
 		w := httptest.NewRecorder()
@@ -0,0 +1,1228 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simpler-products/idempotency"
+	"simpler-products/importer"
+	"simpler-products/metrics"
+	"simpler-products/migrations"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/routers"
+	"simpler-products/search"
+	"simpler-products/services"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type routerResponse struct {
+	Status     int              `json:"status"`
+	Data       []models.Product `json:"data"`
+	Pagination routerPagination `json:"pagination"`
+	Facets     models.Facets    `json:"facets"`
+	Errors     []map[string]any `json:"errors"`
+	RequestID  string           `json:"request_id"`
+}
+
+type routerPagination struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      int    `json:"total"`
+	Count      int    `json:"count"`
+	NextCursor string `json:"next_cursor"`
+	PrevCursor string `json:"prev_cursor"`
+}
+
+func newRouter(t *testing.T, repo repositories.ProductRepository) *gin.Engine {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AUTH_ENABLED", "false")
+
+	log := logrus.New()
+	recorder := metrics.NewRecorder()
+	index := search.NewIndex()
+
+	// Categories, warehouses, variants and stock are stored alongside the products
+	var categories repositories.CategoryRepository = repositories.NewInMemoryCategoryRepository()
+	var warehouses repositories.WarehouseRepository = repositories.NewInMemoryWarehouseRepository()
+	var variants repositories.VariantRepository = repositories.NewInMemoryVariantRepository()
+	var stock repositories.StockRepository = repositories.NewInMemoryStockRepository(repo, warehouses, variants)
+	if sqlRepo, ok := repo.(*repositories.SQLProductRepository); ok {
+		categories = repositories.NewSQLCategoryRepository(sqlRepo.DB, sqlRepo.Dialect)
+		warehouses = repositories.NewSQLWarehouseRepository(sqlRepo.DB, sqlRepo.Dialect)
+		variants = repositories.NewSQLVariantRepository(sqlRepo.DB, sqlRepo.Dialect)
+		stock = repositories.NewSQLStockRepository(sqlRepo.DB, sqlRepo.Dialect)
+	}
+
+	servs := struct {
+		services.ProductsServiceInterface
+		services.CategoriesServiceInterface
+		services.InventoryServiceInterface
+		services.WarehousesServiceInterface
+		services.VariantsServiceInterface
+		services.HealthServiceInterface
+		services.SearchServiceInterface
+		metrics.RecorderInterface
+		idempotency.StoreInterface
+	}{
+		&services.ProductsService{
+			Repo:       repo,
+			Log:        log,
+			Observer:   recorder,
+			Index:      index,
+			Categories: categories,
+		},
+		&services.CategoriesService{
+			Repo:     categories,
+			Products: repo,
+			Log:      log,
+			Observer: recorder,
+		},
+		&services.InventoryService{
+			Repo:       stock,
+			Warehouses: warehouses,
+			Variants:   variants,
+			Log:        log,
+			Observer:   recorder,
+		},
+		&services.WarehousesService{
+			Repo:     warehouses,
+			Stock:    stock,
+			Log:      log,
+			Observer: recorder,
+		},
+		&services.VariantsService{
+			Repo:     variants,
+			Products: repo,
+			Stock:    stock,
+			Log:      log,
+			Observer: recorder,
+		},
+		&services.HealthService{
+			Log: log,
+		},
+		&services.SearchService{
+			Index: index,
+			Log:   log,
+		},
+		recorder,
+		idempotency.NewStore(time.Hour),
+	}
+
+	return routers.NewRouter(servs, log)
+}
+
+func doRequest(t *testing.T, router *gin.Engine, method, path string, body any) (*httptest.ResponseRecorder, routerResponse) {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		assert.NoError(t, json.NewEncoder(&reqBody).Encode(body))
+	}
+
+	req, _ := http.NewRequest(method, path, &reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", strconv.Itoa(reqBody.Len()))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response routerResponse
+	if w.Body.Len() > 0 {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	}
+
+	return w, response
+}
+
+func newSQLiteRepository(t *testing.T) *repositories.SQLProductRepository {
+	t.Helper()
+
+	db := openSQLite(t)
+	migrator, err := migrations.NewMigrator(db, repositories.SQLite.Name, logrus.New())
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when loading migrations", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("an error '%s' was not expected when applying migrations", err)
+	}
+
+	return repositories.NewSQLProductRepository(db, repositories.SQLite)
+}
+
+func TestRouterWithInMemoryRepository(t *testing.T) {
+	testProductsCRUD(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsFiltering(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsFacets(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsPatch(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsPreconditions(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsCursorPagination(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsBulk(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsImport(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsExport(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testCategories(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testProductsStock(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testWarehouses(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+	testVariants(t, newRouter(t, repositories.NewInMemoryProductRepository()))
+}
+
+func TestRouterWithSQLiteRepository(t *testing.T) {
+	testProductsCRUD(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsFiltering(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsFacets(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsPatch(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsPreconditions(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsCursorPagination(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsBulk(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsImport(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsExport(t, newRouter(t, newSQLiteRepository(t)))
+	testCategories(t, newRouter(t, newSQLiteRepository(t)))
+	testProductsStock(t, newRouter(t, newSQLiteRepository(t)))
+	testWarehouses(t, newRouter(t, newSQLiteRepository(t)))
+	testVariants(t, newRouter(t, newSQLiteRepository(t)))
+}
+
+func testProductsCRUD(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	// Create two products
+	w, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Product A", "description": "Description A", "price": 10.99})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Len(t, created.Data, 1)
+	assert.NotEmpty(t, created.Data[0].ID)
+	productA := created.Data[0]
+
+	w, created = doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Product B", "description": "Description B", "price": 19.95})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	productB := created.Data[0]
+
+	// List them sorted by name
+	w, listed := doRequest(t, router, "GET", "/api/v1/products?sort=name&limit=1&offset=1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Product{productB}, listed.Data)
+	assert.Equal(t, 2, listed.Pagination.Total)
+	assert.Equal(t, 1, listed.Pagination.Count)
+
+	// Fetch a single product
+	w, fetched := doRequest(t, router, "GET", "/api/v1/products/"+productA.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Product{productA}, fetched.Data)
+
+	// Update it
+	w, updated := doRequest(t, router, "PUT", "/api/v1/products/"+productA.ID, gin.H{"name": "Product A2", "description": "Description A2", "price": 11.5})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Product{ID: productA.ID, Name: "Product A2", Description: "Description A2", Price: 11.5}, updated.Data[0])
+
+	// HTML is stripped from JSON bodies
+	w, updated = doRequest(t, router, "PUT", "/api/v1/products/"+productA.ID, gin.H{"name": "<script>alert(1)</script>Product A2", "description": "<b>Description A2</b>", "price": 11.5})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Product{ID: productA.ID, Name: "Product A2", Description: "Description A2", Price: 11.5}, updated.Data[0])
+
+	// Delete it
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/products/"+productA.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// It is gone
+	w, missing := doRequest(t, router, "GET", "/api/v1/products/"+productA.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "product not found", missing.Errors[0]["message"])
+
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/products/"+productA.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, _ = doRequest(t, router, "PUT", "/api/v1/products/"+productA.ID, gin.H{"name": "Product A3", "description": "Description A3", "price": 12})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func testProductsFiltering(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	for _, product := range []gin.H{
+		{"name": "Desk Lamp", "description": "Description", "price": 25},
+		{"name": "Floor Lamp", "description": "Description", "price": 80},
+		{"name": "Lamp_Shade 100%", "description": "Description", "price": 15},
+		{"name": "Chair", "description": "Description", "price": 80},
+	} {
+		w, _ := doRequest(t, router, "POST", "/api/v1/products", product)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	names := func(products []models.Product) []string {
+		out := make([]string, 0, len(products))
+		for _, product := range products {
+			out = append(out, product.Name)
+		}
+		return out
+	}
+
+	testCases := []struct {
+		query string
+		names []string
+		total int
+	}{
+		{query: "sort=-price,name", names: []string{"Chair", "Floor Lamp", "Desk Lamp", "Lamp_Shade 100%"}, total: 4},
+		{query: "name=LAMP&sort=price", names: []string{"Lamp_Shade 100%", "Desk Lamp", "Floor Lamp"}, total: 3},
+		{query: "price_min=20&price_max=80&sort=name&limit=2", names: []string{"Chair", "Desk Lamp"}, total: 3},
+		// LIKE wildcards in the name filter match literally
+		{query: "name=p_s", names: []string{"Lamp_Shade 100%"}, total: 1},
+		{query: "name=0%25", names: []string{"Lamp_Shade 100%"}, total: 1},
+		{query: "name=%25p", names: []string{}, total: 0},
+	}
+
+	for _, tc := range testCases {
+		w, listed := doRequest(t, router, "GET", "/api/v1/products?"+tc.query, nil)
+		assert.Equal(t, http.StatusOK, w.Code, tc.query)
+		assert.Equal(t, tc.names, names(listed.Data), tc.query)
+		assert.Equal(t, tc.total, listed.Pagination.Total, tc.query)
+	}
+
+	// Invalid parameters are all reported in the envelope
+	w, listed := doRequest(t, router, "GET", "/api/v1/products?price_min=-5&sort=colour", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, listed.Errors, 2)
+}
+
+func testProductsFacets(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	for _, product := range []gin.H{
+		{"name": "Desk Lamp", "description": "Description", "price": 5},
+		{"name": "Floor Lamp", "description": "Description", "price": 10},
+		{"name": "Wall Lamp", "description": "Description", "price": 10},
+		{"name": "Chair", "description": "Description", "price": 20},
+		{"name": "Table", "description": "Description", "price": 40},
+		{"name": "Shelf", "description": "Description", "price": 80},
+		{"name": "Sofa", "description": "Description", "price": 160},
+		{"name": "Bed", "description": "Description", "price": 315},
+	} {
+		w, _ := doRequest(t, router, "POST", "/api/v1/products", product)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	counts := func(facet *models.PriceFacet) []int {
+		out := make([]int, 0, len(facet.Buckets))
+		for _, bucket := range facet.Buckets {
+			out = append(out, bucket.Count)
+		}
+		return out
+	}
+	edges := func(facet *models.PriceFacet) []float64 {
+		out := make([]float64, 0, len(facet.Buckets))
+		for _, bucket := range facet.Buckets[1:] {
+			out = append(out, *bucket.From)
+		}
+		return out
+	}
+
+	// Fixed buckets, over all the products
+	w, listed := doRequest(t, router, "GET", "/api/v1/products?limit=2&facets=price&price_buckets=10,50", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	facet := listed.Facets.Price
+	if assert.NotNil(t, facet) {
+		assert.Equal(t, 8, facet.Count)
+		assert.Equal(t, 5.0, *facet.Min)
+		assert.Equal(t, 315.0, *facet.Max)
+		assert.Equal(t, 80.0, *facet.Avg)
+		assert.Equal(t, []int{1, 4, 3}, counts(facet))
+		assert.Nil(t, facet.Buckets[0].From)
+		assert.Nil(t, facet.Buckets[2].To)
+	}
+
+	// Counts follow the filters, like the total
+	w, listed = doRequest(t, router, "GET", "/api/v1/products?price_min=10&name=a&facets=price&price_buckets=10,50", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	facet = listed.Facets.Price
+	if assert.NotNil(t, facet) {
+		assert.Equal(t, listed.Pagination.Total, facet.Count)
+		assert.Equal(t, []int{0, 4, 1}, counts(facet))
+	}
+
+	// Quartiles by default
+	_, listed = doRequest(t, router, "GET", "/api/v1/products?facets=price", nil)
+	if assert.NotNil(t, listed.Facets.Price) {
+		assert.Equal(t, []float64{10, 40, 160}, edges(listed.Facets.Price))
+		assert.Equal(t, []int{1, 3, 2, 2}, counts(listed.Facets.Price))
+	}
+
+	// Repeated prices merge quantile buckets
+	_, listed = doRequest(t, router, "GET", "/api/v1/products?price_max=10&facets=price", nil)
+	if assert.NotNil(t, listed.Facets.Price) {
+		assert.Equal(t, []float64{10}, edges(listed.Facets.Price))
+		assert.Equal(t, []int{1, 2}, counts(listed.Facets.Price))
+	}
+
+	// Without matching products there are no statistics
+	_, listed = doRequest(t, router, "GET", "/api/v1/products?name=stool&facets=price", nil)
+	if assert.NotNil(t, listed.Facets.Price) {
+		assert.Equal(t, models.PriceFacet{Buckets: []models.PriceBucket{}}, *listed.Facets.Price)
+	}
+	_, listed = doRequest(t, router, "GET", "/api/v1/products?name=stool&facets=price&price_buckets=10", nil)
+	if assert.NotNil(t, listed.Facets.Price) {
+		assert.Equal(t, []int{0, 0}, counts(listed.Facets.Price))
+	}
+
+	// Search facets cover every hit
+	w, found := doRequest(t, router, "GET", "/api/v1/products/search?q=lamp&limit=1&facets=price&price_buckets=10", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.NotNil(t, found.Facets.Price) {
+		assert.Equal(t, 3, found.Facets.Price.Count)
+		assert.Equal(t, []int{1, 2}, counts(found.Facets.Price))
+	}
+
+	// Facets are left out unless requested
+	w, listed = doRequest(t, router, "GET", "/api/v1/products", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "facets")
+
+	w, listed = doRequest(t, router, "GET", "/api/v1/products?facets=colour", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, listed.Errors, 1)
+}
+
+func testProductsPatch(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	patch := func(id, contentType, body string) (*httptest.ResponseRecorder, routerResponse) {
+		req, _ := http.NewRequest("PATCH", "/api/v1/products/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response routerResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	w, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Kettle", "description": "Boils water", "price": 30})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	id := created.Data[0].ID
+
+	// Merge patches leave the fields they omit as they are
+	w, patched := patch(id, "application/merge-patch+json", `{"price": 25.5}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Product{ID: id, Name: "Kettle", Description: "Boils water", Price: 25.5}, patched.Data[0])
+
+	// JSON patches apply in order, guarded by their tests
+	w, patched = patch(id, "application/json-patch+json", `[
+		{"op": "test", "path": "/price", "value": 25.5},
+		{"op": "replace", "path": "/name", "value": "Electric Kettle"},
+		{"op": "copy", "from": "/name", "path": "/description"}
+	]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Product{ID: id, Name: "Electric Kettle", Description: "Electric Kettle", Price: 25.5}, patched.Data[0])
+
+	w, _ = patch(id, "application/json-patch+json", `[{"op": "test", "path": "/price", "value": 30}, {"op": "remove", "path": "/description"}]`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Invalid results are rejected and nothing is stored
+	w, _ = patch(id, "application/merge-patch+json", `{"description": null}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, fetched := doRequest(t, router, "GET", "/api/v1/products/"+id, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, patched.Data[0], fetched.Data[0])
+
+	// Patches are searchable
+	w, _ = doRequest(t, router, "GET", "/api/v1/products/search?q=electric", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), id)
+
+	// Markup is stripped from patches as from JSON bodies
+	w, patched = patch(id, "application/merge-patch+json", `{"name": "<script>alert(1)</script>Steel Kettle"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Steel Kettle", patched.Data[0].Name)
+	w, patched = patch(id, "application/json-patch+json", `[{"op": "replace", "path": "/description", "value": "<img src=x onerror=alert(1)>Boils water"}]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Boils water", patched.Data[0].Description)
+	w, _ = patch(id, "application/merge-patch+json", `{"name": "<script>alert(1)</script>"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = patch("non_existent_id", "application/merge-patch+json", `{"price": 10}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func testProductsPreconditions(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	send := func(method, id, header, etag, contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/api/v1/products/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if header != "" {
+			req.Header.Set(header, etag)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Teapot", "description": "Brews tea", "price": 20})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	id := created.Data[0].ID
+
+	// Caches holding the current version revalidate without a body
+	w = send("GET", id, "", "", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	for _, etag := range []string{`"1"`, `W/"1"`, `"7", "1"`, "*"} {
+		w = send("GET", id, "If-None-Match", etag, "", "")
+		assert.Equal(t, http.StatusNotModified, w.Code, etag)
+		assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+		assert.Empty(t, w.Body.String())
+	}
+
+	// Writes matching the current version succeed and increment it
+	product := `{"name": "Teapot", "description": "Brews tea", "price": 25}`
+	w = send("PUT", id, "If-Match", `"1"`, "application/json", product)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = send("GET", id, "If-None-Match", `"1"`, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// Writes based on a stale version are rejected and leave the product as it is
+	w = send("PUT", id, "If-Match", `"1"`, "application/json", `{"name": "Stale", "description": "Brews tea", "price": 30}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = send("PUT", id, "If-Match", `W/"2"`, "application/json", `{"name": "Weak", "description": "Brews tea", "price": 30}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = send("PATCH", id, "If-Match", `"1"`, "application/merge-patch+json", `{"price": 30}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = send("DELETE", id, "If-Match", `"1"`, "", "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w, fetched := doRequest(t, router, "GET", "/api/v1/products/"+id, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Product{ID: id, Name: "Teapot", Description: "Brews tea", Price: 25}, fetched.Data[0])
+
+	w = send("PATCH", id, "If-Match", `"2"`, "application/merge-patch+json", `{"price": 30}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	// Writes without If-Match are unconditional
+	w = send("PATCH", id, "", "", "application/merge-patch+json", `{"price": 35}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	w = send("DELETE", id, "If-Match", `"4"`, "", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = send("PUT", id, "If-Match", "*", "application/json", product)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func testProductsCursorPagination(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	// Prices repeat so that pages split products comparing equal on the sort field
+	for i, price := range []float64{30, 10, 20, 10, 30, 20, 10} {
+		w, _ := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": fmt.Sprintf("Product %d", i), "description": "Description", "price": price})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	ids := func(products []models.Product) []string {
+		out := make([]string, 0, len(products))
+		for _, product := range products {
+			out = append(out, product.ID)
+		}
+		return out
+	}
+
+	const listing = "/api/v1/products?sort=-price&price_min=10"
+	_, all := doRequest(t, router, "GET", listing+"&limit=100", nil)
+	assert.Len(t, all.Data, 7)
+
+	// Walk forward from the first offset page, then back from the last page
+	w, page := doRequest(t, router, "GET", listing+"&limit=3", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, page.Pagination.PrevCursor)
+
+	forward := ids(page.Data)
+	for page.Pagination.NextCursor != "" {
+		w, page = doRequest(t, router, "GET", listing+"&limit=3&cursor="+page.Pagination.NextCursor, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 7, page.Pagination.Total)
+		assert.NotEmpty(t, page.Pagination.PrevCursor)
+		forward = append(forward, ids(page.Data)...)
+	}
+	assert.Equal(t, ids(all.Data), forward)
+	assert.Len(t, page.Data, 1)
+
+	backward := ids(page.Data)
+	for page.Pagination.PrevCursor != "" {
+		w, page = doRequest(t, router, "GET", listing+"&limit=3&cursor="+page.Pagination.PrevCursor, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, page.Pagination.NextCursor)
+		backward = append(ids(page.Data), backward...)
+	}
+	assert.Equal(t, ids(all.Data), backward)
+
+	// Products inserted before the position of a cursor do not shift the following page
+	_, first := doRequest(t, router, "GET", listing+"&limit=3", nil)
+	w, _ = doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Product 7", "description": "Description", "price": 40})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	_, second := doRequest(t, router, "GET", listing+"&limit=3&cursor="+first.Pagination.NextCursor, nil)
+	assert.Equal(t, ids(all.Data)[3:6], ids(second.Data))
+
+	// Offset pages past the first one link back too
+	_, offsetPage := doRequest(t, router, "GET", listing+"&limit=3&offset=4", nil)
+	assert.NotEmpty(t, offsetPage.Pagination.PrevCursor)
+
+	// Invalid cursors are rejected
+	for _, path := range []string{
+		listing + "&cursor=" + first.Pagination.NextCursor + "&offset=3",
+		listing + "&cursor=forged",
+		"/api/v1/products?sort=price&cursor=" + first.Pagination.NextCursor,
+	} {
+		w, rejected := doRequest(t, router, "GET", path, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Len(t, rejected.Errors, 1, path)
+	}
+
+	// Names are ordered regardless of case, on every backend
+	for _, name := range []string{"cherry jam", "Banana jam", "apple jam"} {
+		w, _ = doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": name, "description": "Description", "price": 5})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+	names := func(products []models.Product) []string {
+		out := make([]string, 0, len(products))
+		for _, product := range products {
+			out = append(out, product.Name)
+		}
+		return out
+	}
+	_, page = doRequest(t, router, "GET", "/api/v1/products?name=jam&sort=name&limit=2", nil)
+	assert.Equal(t, []string{"apple jam", "Banana jam"}, names(page.Data))
+	_, page = doRequest(t, router, "GET", "/api/v1/products?name=jam&sort=name&limit=2&cursor="+page.Pagination.NextCursor, nil)
+	assert.Equal(t, []string{"cherry jam"}, names(page.Data))
+}
+
+func testProductsBulk(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	bulk := func(body any) (*httptest.ResponseRecorder, []bulkItem) {
+		w, _ := doRequest(t, router, "POST", "/api/v1/products/bulk", body)
+
+		var response struct {
+			Data []bulkItem `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response.Data
+	}
+
+	w, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Teapot", "description": "Brews tea", "price": 20})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	teapot := created.Data[0].ID
+
+	// Each operation is applied on its own and reported with its status
+	w, items := bulk(gin.H{"mode": "best_effort", "operations": []gin.H{
+		{"op": "create", "product": gin.H{"name": "Kettle", "description": "Boils water", "price": 30}},
+		{"op": "update", "id": teapot, "product": gin.H{"name": "Teapot", "description": "Brews green tea", "price": 25}},
+		{"op": "delete", "id": "missing"},
+		{"op": "create", "product": gin.H{"name": "Mug"}},
+	}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{http.StatusCreated, http.StatusOK, http.StatusNotFound, http.StatusBadRequest}, bulkStatuses(items))
+	kettle := items[0].ID
+	assert.NotEmpty(t, kettle)
+
+	w, fetched := doRequest(t, router, "GET", "/api/v1/products/"+teapot, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Brews green tea", fetched.Data[0].Description)
+
+	w, found := doRequest(t, router, "GET", "/api/v1/products/search?q=kettle", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, found.Data, 1)
+
+	// A failing operation rolls back the whole request
+	w, items = bulk(gin.H{"operations": []gin.H{
+		{"op": "create", "product": gin.H{"name": "Mug", "description": "Holds tea", "price": 5}},
+		{"op": "delete", "id": kettle},
+		{"op": "update", "id": teapot, "version": 1, "product": gin.H{"name": "Teapot", "description": "Brews tea", "price": 20}},
+	}})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusPreconditionFailed}, bulkStatuses(items))
+
+	w, listed := doRequest(t, router, "GET", "/api/v1/products", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, listed.Pagination.Total)
+
+	w, found = doRequest(t, router, "GET", "/api/v1/products/search?q=mug", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, found.Data)
+
+	// An invalid operation fails the request before any is applied
+	w, items = bulk(gin.H{"operations": []gin.H{
+		{"op": "delete", "id": kettle},
+		{"op": "delete"},
+	}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []int{http.StatusFailedDependency, http.StatusBadRequest}, bulkStatuses(items))
+
+	// All the operations succeed together
+	w, items = bulk(gin.H{"operations": []gin.H{
+		{"op": "delete", "id": kettle},
+		{"op": "update", "id": teapot, "version": 2, "product": gin.H{"name": "Teapot", "description": "Brews tea", "price": 20}},
+	}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{http.StatusNoContent, http.StatusOK}, bulkStatuses(items))
+
+	w, listed = doRequest(t, router, "GET", "/api/v1/products", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Product{{ID: teapot, Name: "Teapot", Description: "Brews tea", Price: 20}}, listed.Data)
+}
+
+func testProductsImport(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	send := func(contentType, body string) (*httptest.ResponseRecorder, []importer.Report) {
+		req, _ := http.NewRequest("POST", "/api/v1/products/import", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			Data []importer.Report `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response.Data
+	}
+
+	w, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Teapot", "description": "Brews tea", "price": 20})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	teapot := created.Data[0].ID
+
+	// Rows are upserted, the invalid ones are reported
+	w, report := send("text/csv; charset=utf-8", "id,name,description,price\n"+
+		teapot+",Teapot,Brews green tea,25\n"+
+		",Kettle,Boils water,30\n"+
+		",Mug,Holds tea,free\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []importer.Report{{
+		Rows:    3,
+		Created: 1,
+		Updated: 1,
+		Failed:  1,
+		Errors:  []importer.RowError{{Line: 4, Errors: []map[string]string{{"message": "price is invalid"}}}},
+	}}, report)
+
+	w, fetched := doRequest(t, router, "GET", "/api/v1/products/"+teapot, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Brews green tea", fetched.Data[0].Description)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w, report = send("application/x-ndjson", `{"name": "Mug", "description": "Holds tea", "price": 5}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, report[0].Created)
+
+	// Imported products are searchable
+	w, found := doRequest(t, router, "GET", "/api/v1/products/search?q=mug", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, found.Data, 1)
+
+	w, listed := doRequest(t, router, "GET", "/api/v1/products", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, listed.Pagination.Total)
+
+	w, _ = send("text/csv", "name,description\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = send("application/json", `[]`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func testProductsExport(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	for _, product := range []gin.H{
+		{"name": "Desk Lamp", "description": "Lights the desk", "price": 25},
+		{"name": "Floor Lamp", "description": "Lights the room", "price": 80},
+		{"name": "Kettle", "description": "Boils water", "price": 30},
+	} {
+		w, _ := doRequest(t, router, "POST", "/api/v1/products", product)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	// Exports honour the filters and sort of the listing, and are not paginated
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/products/export?name=lamp&sort=-price&limit=1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename=products-\d{8}T\d{6}Z\.csv$`, w.Header().Get("Content-Disposition"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "id,name,description,price", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ",Floor Lamp,Lights the room,80"), lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], ",Desk Lamp,Lights the desk,25"), lines[2])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/products/export?format=ndjson&price_min=100", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Regexp(t, `\.ndjson$`, w.Header().Get("Content-Disposition"))
+	assert.Empty(t, w.Body.String())
+
+	w, response := doRequest(t, router, "GET", "/api/v1/products/export?format=xml", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid format parameter, format must be one of: csv, tsv, ndjson", response.Errors[0]["message"])
+
+	w, _ = doRequest(t, router, "GET", "/api/v1/products/export?format=tsv&sort=color", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
+
+// dataResponse is the response envelope of the endpoints returning other data than products
+type dataResponse[T any] struct {
+	Data   []T              `json:"data"`
+	Errors []map[string]any `json:"errors"`
+}
+
+func doDataRequest[T any](t *testing.T, router *gin.Engine, method, path string, body any) (*httptest.ResponseRecorder, dataResponse[T]) {
+	t.Helper()
+
+	w, _ := doRequest(t, router, method, path, body)
+
+	var response dataResponse[T]
+	if w.Body.Len() > 0 {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	}
+
+	return w, response
+}
+
+func testCategories(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	// Build the tree Home > Lighting > Lamps, along with Garden
+	createCategory := func(name string, parentID *string) models.Category {
+		w, created := doDataRequest[models.Category](t, router, "POST", "/api/v1/categories", gin.H{"name": name, "parent_id": parentID})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Len(t, created.Data, 1)
+		return created.Data[0]
+	}
+	home := createCategory("Home", nil)
+	lighting := createCategory("Lighting", &home.ID)
+	lamps := createCategory("Lamps", &lighting.ID)
+	garden := createCategory("Garden", nil)
+	assert.NotEmpty(t, lamps.ID)
+	assert.Equal(t, &lighting.ID, lamps.ParentID)
+
+	w, listed := doDataRequest[models.Category](t, router, "GET", "/api/v1/categories", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Category{garden, home, lamps, lighting}, listed.Data)
+
+	w, tree := doDataRequest[*models.CategoryNode](t, router, "GET", "/api/v1/categories/tree", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, tree.Data, 2)
+	assert.Equal(t, home.ID, tree.Data[1].ID)
+	assert.Equal(t, lighting.ID, tree.Data[1].Children[0].ID)
+	assert.Equal(t, lamps.ID, tree.Data[1].Children[0].Children[0].ID)
+
+	w, fetched := doDataRequest[models.Category](t, router, "GET", "/api/v1/categories/"+lighting.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Category{lighting}, fetched.Data)
+
+	// Invalid parents are rejected
+	w, _ = doDataRequest[models.Category](t, router, "POST", "/api/v1/categories", gin.H{"name": "Orphan", "parent_id": "missing"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = doDataRequest[models.Category](t, router, "POST", "/api/v1/categories", gin.H{"parent_id": home.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A category cannot be nested in itself or in one of its descendants
+	w, cycle := doDataRequest[models.Category](t, router, "PUT", "/api/v1/categories/"+home.ID, gin.H{"name": "Home", "parent_id": lamps.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "a category cannot be nested in itself or in one of its descendants", cycle.Errors[0]["message"])
+	w, _ = doDataRequest[models.Category](t, router, "PUT", "/api/v1/categories/"+home.ID, gin.H{"name": "Home", "parent_id": home.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Moving a category moves its subtree
+	w, moved := doDataRequest[models.Category](t, router, "PUT", "/api/v1/categories/"+lighting.ID, gin.H{"name": "Lights", "parent_id": garden.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Category{ID: lighting.ID, Name: "Lights", ParentID: &garden.ID}, moved.Data[0])
+
+	w, _ = doDataRequest[models.Category](t, router, "PUT", "/api/v1/categories/missing", gin.H{"name": "Missing"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Assign products to categories
+	_, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Desk Lamp", "description": "Lights the desk", "price": 25})
+	deskLamp := created.Data[0]
+	_, created = doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Spotlight", "description": "Lights the garden", "price": 40})
+	spotlight := created.Data[0]
+	_, created = doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Kettle", "description": "Boils water", "price": 30})
+	kettle := created.Data[0]
+
+	w, assigned := doDataRequest[models.Category](t, router, "PUT", "/api/v1/products/"+deskLamp.ID+"/categories", gin.H{"category_ids": []string{lamps.ID, home.ID, lamps.ID}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{home.ID, lamps.ID}, []string{assigned.Data[0].ID, assigned.Data[1].ID})
+	w, _ = doDataRequest[models.Category](t, router, "PUT", "/api/v1/products/"+spotlight.ID+"/categories", gin.H{"category_ids": []string{lighting.ID}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, fetched = doDataRequest[models.Category](t, router, "GET", "/api/v1/products/"+deskLamp.ID+"/categories", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, fetched.Data, 2)
+
+	w, _ = doDataRequest[models.Category](t, router, "PUT", "/api/v1/products/"+kettle.ID+"/categories", gin.H{"category_ids": []string{"missing"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = doDataRequest[models.Category](t, router, "PUT", "/api/v1/products/"+kettle.ID+"/categories", gin.H{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = doDataRequest[models.Category](t, router, "PUT", "/api/v1/products/missing/categories", gin.H{"category_ids": []string{}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = doDataRequest[models.Category](t, router, "GET", "/api/v1/products/missing/categories", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Listing a category includes the products of its descendants
+	w, listedProducts := doRequest(t, router, "GET", "/api/v1/products?sort=name&category="+garden.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Product{deskLamp, spotlight}, listedProducts.Data)
+	assert.Equal(t, 2, listedProducts.Pagination.Total)
+
+	w, listedProducts = doRequest(t, router, "GET", "/api/v1/products?category="+home.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Product{deskLamp}, listedProducts.Data)
+
+	w, listedProducts = doRequest(t, router, "GET", "/api/v1/products?category=missing", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "category not found", listedProducts.Errors[0]["message"])
+
+	w, _ = doRequest(t, router, "GET", "/api/v1/products/export?format=ndjson&category="+lamps.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, strings.Count(w.Body.String(), "\n"))
+
+	// Categories with subcategories or products are not deleted
+	w, notEmpty := doDataRequest[models.Category](t, router, "DELETE", "/api/v1/categories/"+garden.ID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "category has subcategories or products, move or delete them first", notEmpty.Errors[0]["message"])
+	w, _ = doDataRequest[models.Category](t, router, "DELETE", "/api/v1/categories/"+lamps.ID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Deleting a product drops its assignments, an empty list drops them too
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/products/"+deskLamp.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w, _ = doDataRequest[models.Category](t, router, "PUT", "/api/v1/products/"+spotlight.ID+"/categories", gin.H{"category_ids": []string{}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, id := range []string{lamps.ID, lighting.ID, garden.ID} {
+		w, _ = doDataRequest[models.Category](t, router, "DELETE", "/api/v1/categories/"+id, nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+
+	w, _ = doDataRequest[models.Category](t, router, "GET", "/api/v1/categories/"+lamps.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = doDataRequest[models.Category](t, router, "DELETE", "/api/v1/categories/"+lamps.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func testProductsStock(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	_, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Desk Lamp", "description": "Lights the desk", "price": 25})
+	product := created.Data[0]
+	stockPath := "/api/v1/products/" + product.ID + "/stock"
+
+	// Products start without stock
+	w, stock := doDataRequest[models.Stock](t, router, "GET", stockPath, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Stock{{ProductID: product.ID, Warehouses: []models.WarehouseStock{}}}, stock.Data)
+
+	move := func(movementType string, quantity int64, status int) dataResponse[models.StockMovement] {
+		t.Helper()
+		w, moved := doDataRequest[models.StockMovement](t, router, "POST", stockPath+"/"+movementType, gin.H{"quantity": quantity, "reason": "order-42"})
+		assert.Equal(t, status, w.Code, movementType)
+		return moved
+	}
+
+	moved := move(models.StockAdjust, 10, http.StatusCreated)
+	assert.Equal(t, int64(1), moved.Data[0].Sequence)
+	assert.Equal(t, int64(10), moved.Data[0].OnHand)
+	assert.False(t, moved.Data[0].CreatedAt.IsZero())
+
+	moved = move(models.StockReserve, 4, http.StatusCreated)
+	assert.Equal(t, int64(4), moved.Data[0].Reserved)
+
+	// Reservations never exceed the available stock, nor releases and commits the reserved one
+	moved = move(models.StockReserve, 7, http.StatusConflict)
+	assert.Equal(t, "insufficient stock, the quantity exceeds the available stock", moved.Errors[0]["message"])
+	move(models.StockAdjust, -7, http.StatusConflict)
+	move(models.StockRelease, 5, http.StatusConflict)
+	move(models.StockCommit, 5, http.StatusConflict)
+
+	move(models.StockRelease, 1, http.StatusCreated)
+	moved = move(models.StockCommit, 3, http.StatusCreated)
+	assert.Equal(t, models.StockMovement{
+		ProductID:   product.ID,
+		WarehouseID: models.DefaultWarehouseID,
+		Sequence:    4,
+		Type:        models.StockCommit,
+		Quantity:    3,
+		OnHand:      7,
+		Reserved:    0,
+		Reason:      "order-42",
+		CreatedAt:   moved.Data[0].CreatedAt,
+	}, moved.Data[0])
+
+	w, stock = doDataRequest[models.Stock](t, router, "GET", stockPath, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Stock{{
+		ProductID:  product.ID,
+		OnHand:     7,
+		Reserved:   0,
+		Available:  7,
+		Warehouses: []models.WarehouseStock{{WarehouseID: models.DefaultWarehouseID, OnHand: 7, Reserved: 0, Available: 7}},
+	}}, stock.Data)
+
+	// Invalid movements are rejected
+	move(models.StockReserve, 0, http.StatusBadRequest)
+	move(models.StockRelease, -1, http.StatusBadRequest)
+	move(models.StockAdjust, 0, http.StatusBadRequest)
+
+	// The ledger lists the applied movements, latest first
+	w, _ = doRequest(t, router, "GET", stockPath+"/movements?limit=2&offset=1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var ledger struct {
+		Data       []models.StockMovement `json:"data"`
+		Pagination routerPagination       `json:"pagination"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ledger))
+	assert.Equal(t, 4, ledger.Pagination.Total)
+	assert.Len(t, ledger.Data, 2)
+	assert.Equal(t, []int64{3, 2}, []int64{ledger.Data[0].Sequence, ledger.Data[1].Sequence})
+	assert.Equal(t, models.StockRelease, ledger.Data[0].Type)
+
+	// The stock of a deleted product is gone along with it
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/products/"+product.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w, _ = doRequest(t, router, "GET", stockPath, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = doRequest(t, router, "GET", stockPath+"/movements", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	move(models.StockAdjust, 1, http.StatusNotFound)
+}
+
+func testWarehouses(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	// The default warehouse always exists
+	w, warehouses := doDataRequest[models.Warehouse](t, router, "GET", "/api/v1/warehouses", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Warehouse{{ID: models.DefaultWarehouseID, Name: "Default"}}, warehouses.Data)
+
+	w, created := doDataRequest[models.Warehouse](t, router, "POST", "/api/v1/warehouses", gin.H{"name": "Berlin", "region": "EU-DE-BER", "priority": 1})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	berlin := created.Data[0]
+	assert.NotEmpty(t, berlin.ID)
+
+	w, _ = doRequest(t, router, "POST", "/api/v1/warehouses", gin.H{"region": "EU"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, updated := doDataRequest[models.Warehouse](t, router, "PUT", "/api/v1/warehouses/"+berlin.ID, gin.H{"name": "Berlin", "region": "EU-DE-BER", "priority": -1})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, -1, updated.Data[0].Priority)
+
+	w, _ = doRequest(t, router, "GET", "/api/v1/warehouses/missing", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = doRequest(t, router, "PUT", "/api/v1/warehouses/missing", gin.H{"name": "Missing"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, product := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Desk Lamp", "description": "Lights the desk", "price": 25})
+	stockPath := "/api/v1/products/" + product.Data[0].ID + "/stock"
+
+	w, _ = doRequest(t, router, "POST", stockPath+"/adjust", gin.H{"quantity": 10, "warehouse_id": berlin.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w, _ = doRequest(t, router, "POST", stockPath+"/adjust", gin.H{"quantity": 10, "warehouse_id": "missing"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Transfers are recorded as paired movements
+	w, transferred := doDataRequest[models.StockMovement](t, router, "POST", stockPath+"/transfer", gin.H{"from": berlin.ID, "to": models.DefaultWarehouseID, "quantity": 3})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Len(t, transferred.Data, 2)
+	assert.Equal(t, transferred.Data[0].TransferID, transferred.Data[1].TransferID)
+
+	for _, body := range []gin.H{
+		{"from": berlin.ID, "to": berlin.ID, "quantity": 1},
+		{"from": berlin.ID, "quantity": 1},
+		{"from": berlin.ID, "to": models.DefaultWarehouseID, "quantity": 0},
+	} {
+		w, _ = doRequest(t, router, "POST", stockPath+"/transfer", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	w, _ = doRequest(t, router, "POST", stockPath+"/transfer", gin.H{"from": berlin.ID, "to": models.DefaultWarehouseID, "quantity": 8})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Allocations pick warehouses, and reserve the stock there when asked to
+	w, plan := doDataRequest[models.AllocationPlan](t, router, "POST", stockPath+"/allocate", gin.H{"quantity": 5})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Allocation{{WarehouseID: berlin.ID, Quantity: 5}}, plan.Data[0].Allocations)
+
+	w, _ = doRequest(t, router, "POST", stockPath+"/allocate", gin.H{"quantity": 9})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w, plan = doDataRequest[models.AllocationPlan](t, router, "POST", stockPath+"/allocate", gin.H{"quantity": 9, "split": true, "reserve": true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, plan.Data[0].Reserved)
+	assert.Equal(t, []models.Allocation{{WarehouseID: berlin.ID, Quantity: 7}, {WarehouseID: models.DefaultWarehouseID, Quantity: 2}}, plan.Data[0].Allocations)
+
+	w, _ = doRequest(t, router, "POST", stockPath+"/allocate", gin.H{"quantity": 2, "split": true})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, _ = doRequest(t, router, "POST", stockPath+"/allocate", gin.H{"quantity": 1, "strategy": "nearest"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = doRequest(t, router, "POST", stockPath+"/allocate", gin.H{"quantity": 1, "strategy": "cheapest"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Warehouses holding stock cannot be deleted
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/warehouses/"+berlin.ID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, _ = doRequest(t, router, "POST", stockPath+"/commit", gin.H{"quantity": 7, "warehouse_id": berlin.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/warehouses/"+berlin.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/warehouses/"+berlin.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func testVariants(t *testing.T, router *gin.Engine) {
+	t.Helper()
+
+	_, product := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Crew T-Shirt", "description": "Plain cotton", "price": 20})
+	productPath := "/api/v1/products/" + product.Data[0].ID
+
+	for _, body := range []gin.H{
+		{},
+		{"options": []gin.H{{"name": "size", "values": []string{}}}},
+		{"options": []gin.H{{"name": "size", "values": []string{"S", "S"}}}},
+		{"options": []gin.H{{"name": "size", "values": []string{"S"}}, {"name": "size", "values": []string{"M"}}}},
+	} {
+		w, _ := doRequest(t, router, "PUT", productPath+"/options", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	// Options are bounded, and so are their combinations
+	values := make([]string, 0, models.MaxOptionValues+1)
+	for i := 0; i <= models.MaxOptionValues; i++ {
+		values = append(values, fmt.Sprint(i))
+	}
+	tooMany := make([]gin.H, 0, models.MaxProductOptions+1)
+	for i := 0; i <= models.MaxProductOptions; i++ {
+		tooMany = append(tooMany, gin.H{"name": fmt.Sprint("option", i), "values": []string{"a", "b"}})
+	}
+	for _, body := range []gin.H{
+		{"options": tooMany},
+		{"options": []gin.H{{"name": "size", "values": values}}},
+		{"options": []gin.H{{"name": "width", "values": values[:40]}, {"name": "height", "values": values[:40]}}},
+		{"options": []gin.H{{"name": strings.Repeat("a", models.MaxOptionNameLength+1), "values": []string{"S"}}}},
+		{"options": []gin.H{{"name": "size", "values": []string{strings.Repeat("S", models.MaxOptionValueLength+1)}}}},
+	} {
+		w, _ := doRequest(t, router, "PUT", productPath+"/options", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	w, options := doDataRequest[models.ProductOption](t, router, "PUT", productPath+"/options", gin.H{"options": []gin.H{
+		{"name": "size", "values": []string{"S", "M"}},
+		{"name": "colour", "values": []string{"red"}},
+	}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, options.Data, 2)
+	w, _ = doRequest(t, router, "GET", "/api/v1/products/missing/options", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, created := doDataRequest[models.Variant](t, router, "POST", productPath+"/variants", gin.H{"sku": "TEE-S-RED", "options": gin.H{"size": "S", "colour": "red"}, "price": 18})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	small := created.Data[0]
+	assert.NotEmpty(t, small.ID)
+	assert.Equal(t, 18.0, *small.Price)
+
+	for body, status := range map[string]int{
+		`{"options": {"size": "M", "colour": "red"}}`:                              http.StatusBadRequest,
+		`{"sku": "TEE-M", "options": {"size": "M"}}`:                               http.StatusBadRequest,
+		`{"sku": "TEE-M", "options": {"size": "M", "colour": "red"}, "price": -1}`: http.StatusBadRequest,
+		`{"sku": "TEE-S-RED", "options": {"size": "M", "colour": "red"}}`:          http.StatusConflict,
+		`{"sku": "TEE-S", "options": {"size": "S", "colour": "red"}}`:              http.StatusConflict,
+	} {
+		w, _ = doRequest(t, router, "POST", productPath+"/variants", json.RawMessage(body))
+		assert.Equal(t, status, w.Code, body)
+	}
+	// SKUs, option names and option values are bounded in length
+	for body, message := range map[string]string{
+		`{"sku": "` + strings.Repeat("A", models.MaxSKULength+1) + `", "options": {"size": "M", "colour": "red"}}`:           "SKU must be at most 255 characters long",
+		`{"sku": "TEE-M", "options": {"size": "M", "colour": "` + strings.Repeat("a", models.MaxOptionValueLength+1) + `"}}`: "Options[colour] must be at most 100 characters long",
+	} {
+		w, invalid := doRequest(t, router, "POST", productPath+"/variants", json.RawMessage(body))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, message, invalid.Errors[0]["message"])
+	}
+
+	// Generation fills in the combinations without a variant
+	w, generated := doDataRequest[models.Variant](t, router, "POST", productPath+"/variants/generate", gin.H{"sku_prefix": "TEE"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Len(t, generated.Data, 1)
+	assert.Equal(t, "TEE-M-RED", generated.Data[0].SKU)
+	w, generated = doDataRequest[models.Variant](t, router, "POST", productPath+"/variants/generate", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, generated.Data)
+
+	w, variants := doDataRequest[models.Variant](t, router, "GET", productPath+"/variants", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"TEE-M-RED", "TEE-S-RED"}, []string{variants.Data[0].SKU, variants.Data[1].SKU})
+
+	w, updated := doDataRequest[models.Variant](t, router, "PUT", productPath+"/variants/"+small.ID, gin.H{"sku": "TEE-S-RED-2", "options": gin.H{"size": "S", "colour": "red"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "TEE-S-RED-2", updated.Data[0].SKU)
+	assert.Nil(t, updated.Data[0].Price)
+	w, _ = doRequest(t, router, "GET", productPath+"/variants/missing", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Variants are stocked on their own
+	w, _ = doRequest(t, router, "POST", productPath+"/stock/adjust", gin.H{"quantity": 4, "variant_id": small.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w, _ = doRequest(t, router, "POST", productPath+"/stock/adjust", gin.H{"quantity": 4, "variant_id": "missing"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, stock := doDataRequest[models.Stock](t, router, "GET", productPath+"/variants/"+small.ID+"/stock", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(4), stock.Data[0].Available)
+	w, _ = doRequest(t, router, "GET", productPath+"/variants/missing/stock", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, _ = doRequest(t, router, "PUT", productPath+"/options", gin.H{"options": []gin.H{{"name": "size", "values": []string{"S", "M"}}}})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, _ = doRequest(t, router, "DELETE", productPath+"/variants/"+small.ID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, _ = doRequest(t, router, "POST", productPath+"/stock/adjust", gin.H{"quantity": -4, "variant_id": small.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w, _ = doRequest(t, router, "DELETE", productPath+"/variants/"+small.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w, _ = doRequest(t, router, "DELETE", productPath+"/variants/"+small.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// An empty list drops the options once no variant uses them
+	w, _ = doRequest(t, router, "PUT", productPath+"/options", gin.H{"options": []gin.H{}})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, _ = doRequest(t, router, "DELETE", productPath+"/variants/"+variants.Data[0].ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w, options = doDataRequest[models.ProductOption](t, router, "PUT", productPath+"/options", gin.H{"options": []gin.H{}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, options.Data)
+	w, options = doDataRequest[models.ProductOption](t, router, "GET", productPath+"/options", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, options.Data)
+}
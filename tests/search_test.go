@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/search"
+	"simpler-products/services"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSearchIndex(products ...models.Product) *search.Index {
+	index := search.NewIndex()
+	for _, product := range products {
+		index.Add(product)
+	}
+	return index
+}
+
+func hitIDs(hits []search.Hit) []string {
+	ids := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.Product.ID)
+	}
+	return ids
+}
+
+func TestSearchIndex(t *testing.T) {
+	index := newSearchIndex(
+		models.Product{ID: "lamp", Name: "Desk Lamp", Description: "A lamp for reading at the desk", Price: 25},
+		models.Product{ID: "chair", Name: "Office Chair", Description: "Ergonomic chair, pairs well with our lamps", Price: 120},
+		models.Product{ID: "desk", Name: "Standing Desk", Description: "Height adjustable desk", Price: 450},
+		models.Product{ID: "shelf", Name: "Bookshelf", Description: "Five shelves for books", Price: 80},
+	)
+
+	t.Run("RanksNameMatchesFirst", func(t *testing.T) {
+		hits, total := index.Search("lamp ", 10, 0)
+
+		assert.Equal(t, 2, total)
+		assert.Equal(t, []string{"lamp", "chair"}, hitIDs(hits))
+		assert.Greater(t, hits[0].Score, hits[1].Score)
+	})
+
+	t.Run("Stemming", func(t *testing.T) {
+		hits, _ := index.Search("shelving ", 10, 0)
+		assert.Equal(t, []string{"shelf"}, hitIDs(hits))
+
+		hits, _ = index.Search("Reads ", 10, 0)
+		assert.Equal(t, []string{"lamp"}, hitIDs(hits))
+	})
+
+	t.Run("StopWordsAreIgnored", func(t *testing.T) {
+		hits, total := index.Search("the for at ", 10, 0)
+
+		assert.Empty(t, hits)
+		assert.Equal(t, 0, total)
+	})
+
+	t.Run("AnyWordMatches", func(t *testing.T) {
+		hits, _ := index.Search("desk chair ", 10, 0)
+
+		assert.ElementsMatch(t, []string{"lamp", "chair", "desk"}, hitIDs(hits))
+	})
+
+	t.Run("PrefixOfTheLastWord", func(t *testing.T) {
+		hits, _ := index.Search("ergo", 10, 0)
+		assert.Equal(t, []string{"chair"}, hitIDs(hits))
+
+		// A trailing separator marks the word as complete
+		hits, _ = index.Search("ergo ", 10, 0)
+		assert.Empty(t, hits)
+
+		// Whole words rank before the words they are a prefix of
+		hits, _ = index.Search("desk", 10, 0)
+		assert.Equal(t, "desk", hits[0].Product.ID)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		all, total := index.Search("desk chair", 10, 0)
+		page, pageTotal := index.Search("desk chair", 1, 1)
+
+		assert.Equal(t, total, pageTotal)
+		assert.Equal(t, hitIDs(all)[1:2], hitIDs(page))
+
+		page, _ = index.Search("desk chair", 10, 10)
+		assert.Empty(t, page)
+	})
+
+	t.Run("Highlights", func(t *testing.T) {
+		hits, _ := index.Search("reading lamp", 10, 0)
+
+		assert.Equal(t, "lamp", hits[0].Product.ID)
+		assert.Equal(t, map[string]string{
+			"name":        "Desk <mark>Lamp</mark>",
+			"description": "A <mark>lamp</mark> for <mark>reading</mark> at the desk",
+		}, hits[0].Highlights)
+	})
+}
+
+func TestSearchIndexSnippets(t *testing.T) {
+	description := strings.Repeat("Plain filler words. ", 10) + "The <b>waterproof</b> lining & seams. " + strings.Repeat("More filler words. ", 10)
+	index := newSearchIndex(models.Product{ID: "jacket", Name: "Jacket", Description: description, Price: 99})
+
+	hits, _ := index.Search("waterproof", 10, 0)
+	snippet := hits[0].Highlights["description"]
+
+	assert.NotContains(t, hits[0].Highlights, "name")
+	assert.Contains(t, snippet, "&lt;b&gt;<mark>waterproof</mark>&lt;/b&gt; lining &amp; seams")
+	assert.True(t, strings.HasPrefix(snippet, "…"), snippet)
+	assert.True(t, strings.HasSuffix(snippet, "…"), snippet)
+	assert.Less(t, len(snippet), len(description))
+}
+
+func TestSearchIndexUpdates(t *testing.T) {
+	index := newSearchIndex(models.Product{ID: "p1", Name: "Red Kettle", Description: "Boils water", Price: 30})
+
+	// Re-adding a product replaces it
+	index.Add(models.Product{ID: "p1", Name: "Blue Kettle", Description: "Boils water", Price: 30})
+	hits, _ := index.Search("red ", 10, 0)
+	assert.Empty(t, hits)
+	hits, _ = index.Search("blue ", 10, 0)
+	assert.Equal(t, []string{"p1"}, hitIDs(hits))
+	assert.Equal(t, "Blue Kettle", hits[0].Product.Name)
+
+	// Removed products and their words are gone, prefixes included
+	index.Remove("p1")
+	hits, _ = index.Search("bl", 10, 0)
+	assert.Empty(t, hits)
+	assert.Equal(t, 0, index.Len())
+}
+
+func TestSearchServiceBuildIndex(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	for _, name := range []string{"Desk Lamp", "Floor Lamp", "Office Chair"} {
+		_, err := repo.Insert(context.Background(), name, &models.Product{Name: name, Description: "Description", Price: 10})
+		assert.NoError(t, err)
+	}
+
+	ss := &services.SearchService{Index: search.NewIndex(), Log: logrus.New()}
+	indexed, err := ss.BuildIndex(context.Background(), repo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, indexed)
+
+	hits, total, err := ss.SearchProducts(context.Background(), "lamp", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.ElementsMatch(t, []string{"Desk Lamp", "Floor Lamp"}, hitIDs(hits))
+}
+
+func TestSearchEndpoint(t *testing.T) {
+	router := newRouter(t, repositories.NewInMemoryProductRepository())
+
+	searchFor := func(q string) (int, []search.Hit, int) {
+		var response struct {
+			Data       []search.Hit     `json:"data"`
+			Pagination routerPagination `json:"pagination"`
+		}
+		req, _ := http.NewRequest("GET", "/api/v1/products/search?q="+url.QueryEscape(q), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response.Data, response.Pagination.Total
+	}
+
+	// Writes through the API keep the index in sync
+	w, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Espresso Machine", "description": "Brews coffee", "price": 300})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	id := created.Data[0].ID
+
+	status, hits, total := searchFor("espresso")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{id}, hitIDs(hits))
+	assert.Equal(t, "<mark>Espresso</mark> Machine", hits[0].Highlights["name"])
+
+	w, _ = doRequest(t, router, "PUT", "/api/v1/products/"+id, gin.H{"name": "Coffee Grinder", "description": "Grinds coffee", "price": 120})
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, hits, _ = searchFor("espresso")
+	assert.Empty(t, hits)
+	_, hits, _ = searchFor("grinder")
+	assert.Equal(t, []string{id}, hitIDs(hits))
+
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/products/"+id, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, hits, _ = searchFor("coffee")
+	assert.Empty(t, hits)
+
+	// The search route does not shadow products
+	w, _ = doRequest(t, router, "GET", "/api/v1/products/"+id, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Invalid queries are rejected
+	for _, q := range []string{"", "   ", strings.Repeat("a", 201)} {
+		status, _, _ := searchFor(q)
+		assert.Equal(t, http.StatusBadRequest, status, q)
+	}
+}
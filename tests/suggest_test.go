@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/search"
+	"simpler-products/services"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func suggestionNames(suggestions []search.Suggestion) []string {
+	names := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		names = append(names, suggestion.Name)
+	}
+	return names
+}
+
+func TestSuggest(t *testing.T) {
+	index := newSearchIndex(
+		models.Product{ID: "1", Name: "Desk Lamp"},
+		models.Product{ID: "2", Name: "Desk"},
+		models.Product{ID: "3", Name: "Desktop Computer"},
+		models.Product{ID: "4", Name: "Standing Desk"},
+		models.Product{ID: "5", Name: "Lamp Shade"},
+		models.Product{ID: "6", Name: "Keyboard"},
+		models.Product{ID: "7", Name: "Desk Lamp"},
+		models.Product{ID: "8", Name: "Dusk Lantern"},
+	)
+
+	t.Run("Prefix", func(t *testing.T) {
+		// Names starting with the prefix first, shortest first, then names with a word starting with it
+		suggestions := index.Suggest("desk", 10, false)
+		assert.Equal(t, []string{"Desk", "Desk Lamp", "Desktop Computer", "Standing Desk"}, suggestionNames(suggestions))
+		for _, suggestion := range suggestions {
+			assert.False(t, suggestion.Fuzzy)
+		}
+
+		assert.Equal(t, []string{"Lamp Shade", "Desk Lamp"}, suggestionNames(index.Suggest("LAM", 10, false)))
+		assert.Equal(t, []string{"Desk Lamp"}, suggestionNames(index.Suggest("  desk   l", 10, false)))
+	})
+
+	t.Run("TrailingSpaceCompletesTheWord", func(t *testing.T) {
+		assert.Equal(t, []string{"Desk Lamp"}, suggestionNames(index.Suggest("desk ", 10, false)))
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		assert.Equal(t, []string{"Desk", "Desk Lamp"}, suggestionNames(index.Suggest("desk", 2, false)))
+	})
+
+	t.Run("NoMatches", func(t *testing.T) {
+		assert.Empty(t, index.Suggest("chair", 10, false))
+		assert.Empty(t, index.Suggest("   ", 10, false))
+		assert.Empty(t, index.Suggest("keybaord", 10, false))
+	})
+
+	t.Run("Fuzzy", func(t *testing.T) {
+		// Substitution, deletion, insertion and transposition
+		for _, prefix := range []string{"keyvoard", "keyboad", "keyyboard", "keybaord"} {
+			assert.Equal(t, []search.Suggestion{{Name: "Keyboard", Fuzzy: true}}, index.Suggest(prefix, 10, true), prefix)
+		}
+
+		// Exact completions rank before fuzzy ones
+		suggestions := index.Suggest("desk", 10, true)
+		assert.Equal(t, []search.Suggestion{
+			{Name: "Desk", Fuzzy: false},
+			{Name: "Desk Lamp", Fuzzy: false},
+			{Name: "Desktop Computer", Fuzzy: false},
+			{Name: "Standing Desk", Fuzzy: false},
+			{Name: "Dusk Lantern", Fuzzy: true},
+		}, suggestions)
+
+		// Short prefixes are not corrected
+		assert.Empty(t, index.Suggest("kez", 10, true))
+
+		// Two typos are only allowed in long prefixes
+		assert.Empty(t, index.Suggest("kyeboad", 10, true))
+		assert.Equal(t, []string{"Desktop Computer"}, suggestionNames(index.Suggest("dekstop compter", 10, true)))
+	})
+
+	t.Run("KeptInSync", func(t *testing.T) {
+		index := newSearchIndex(
+			models.Product{ID: "1", Name: "Red Kettle"},
+			models.Product{ID: "2", Name: "Red Kettle"},
+		)
+
+		// The name stays suggested while a product has it
+		index.Remove("1")
+		assert.Equal(t, []string{"Red Kettle"}, suggestionNames(index.Suggest("red", 10, false)))
+
+		index.Add(models.Product{ID: "2", Name: "Blue Kettle"})
+		assert.Empty(t, index.Suggest("red", 10, true))
+		assert.Equal(t, []string{"Blue Kettle"}, suggestionNames(index.Suggest("kettle", 10, false)))
+	})
+}
+
+func TestSuggestServiceLimit(t *testing.T) {
+	index := search.NewIndex()
+	for i := 0; i < 30; i++ {
+		index.Add(models.Product{ID: fmt.Sprint(i), Name: fmt.Sprintf("Lamp %02d", i)})
+	}
+
+	ss := &services.SearchService{Index: index, Log: logrus.New(), MaxSuggestions: 5}
+
+	suggestions, err := ss.SuggestProducts(context.Background(), "lamp", 0, false)
+	assert.NoError(t, err)
+	assert.Len(t, suggestions, 5)
+
+	suggestions, _ = ss.SuggestProducts(context.Background(), "lamp", 3, false)
+	assert.Equal(t, []string{"Lamp 00", "Lamp 01", "Lamp 02"}, suggestionNames(suggestions))
+
+	suggestions, _ = ss.SuggestProducts(context.Background(), "lamp", 50, false)
+	assert.Len(t, suggestions, 5)
+
+	ss.MaxSuggestions = 0
+	suggestions, _ = ss.SuggestProducts(context.Background(), "lamp", 0, false)
+	assert.Len(t, suggestions, services.DefaultMaxSuggestions)
+}
+
+func TestSuggestEndpoint(t *testing.T) {
+	router := newRouter(t, repositories.NewInMemoryProductRepository())
+
+	suggest := func(query string) (int, []search.Suggestion) {
+		var response struct {
+			Data []search.Suggestion `json:"data"`
+		}
+		req, _ := http.NewRequest("GET", "/api/v1/products/suggest?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response.Data
+	}
+
+	w, created := doRequest(t, router, "POST", "/api/v1/products", gin.H{"name": "Espresso Machine", "description": "Brews coffee", "price": 300})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	id := created.Data[0].ID
+
+	status, suggestions := suggest("prefix=esp")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []search.Suggestion{{Name: "Espresso Machine"}}, suggestions)
+
+	_, suggestions = suggest("prefix=" + url.QueryEscape("expresso") + "&fuzzy=true&limit=1")
+	assert.Equal(t, []search.Suggestion{{Name: "Espresso Machine", Fuzzy: true}}, suggestions)
+
+	// Writes through the API keep suggestions in sync
+	w, _ = doRequest(t, router, "PUT", "/api/v1/products/"+id, gin.H{"name": "Coffee Grinder", "description": "Grinds coffee", "price": 120})
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, suggestions = suggest("prefix=esp")
+	assert.Empty(t, suggestions)
+	_, suggestions = suggest("prefix=grin")
+	assert.Equal(t, []search.Suggestion{{Name: "Coffee Grinder"}}, suggestions)
+
+	w, _ = doRequest(t, router, "DELETE", "/api/v1/products/"+id, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, suggestions = suggest("prefix=coffee")
+	assert.Empty(t, suggestions)
+
+	// Invalid parameters are rejected
+	for _, query := range []string{
+		"",
+		"prefix=" + url.QueryEscape("   "),
+		"prefix=" + strings.Repeat("a", 256),
+		"prefix=a&limit=0",
+		"prefix=a&limit=many",
+		"prefix=a&fuzzy=maybe",
+	} {
+		status, _ := suggest(query)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
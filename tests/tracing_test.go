@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"simpler-products/tracing"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentID    = "00f067aa0ba902b7"
+	testTraceparent = "00-" + testTraceID + "-" + testParentID + "-01"
+)
+
+// recordSpans installs a tracer provider keeping the ended spans in memory for the test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.WithSyncer(exporter))
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) (tracetest.SpanStub, bool) {
+	for _, span := range spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+
+	return tracetest.SpanStub{}, false
+}
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	exporter := recordSpans(t)
+	router := newRouter(t, newSQLiteRepository(t))
+	exporter.Reset()
+
+	req, _ := http.NewRequest("GET", "/api/v1/products?limit=5", nil)
+	req.Header.Set("traceparent", testTraceparent)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		TraceID string `json:"trace_id"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, testTraceID, response.TraceID)
+
+	spans := exporter.GetSpans()
+
+	serverSpan, ok := findSpan(spans, "GET /api/v1/products")
+	if assert.True(t, ok, "server span not recorded") {
+		assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind)
+		assert.Equal(t, testTraceID, serverSpan.SpanContext.TraceID().String())
+		assert.Equal(t, testParentID, serverSpan.Parent.SpanID().String())
+	}
+
+	serviceSpan, ok := findSpan(spans, "ProductsService.GetAllProducts")
+	if assert.True(t, ok, "service span not recorded") {
+		assert.Equal(t, serverSpan.SpanContext.SpanID(), serviceSpan.Parent.SpanID())
+	}
+
+	// The count and the page are two statements
+	statements := 0
+	for _, span := range spans {
+		if span.Name == "sql.conn.query" {
+			statements++
+			assert.Equal(t, serviceSpan.SpanContext.SpanID(), span.Parent.SpanID())
+		}
+	}
+	assert.Equal(t, 2, statements)
+}
+
+func TestTracingStartsNewTrace(t *testing.T) {
+	exporter := recordSpans(t)
+	router := newRouter(t, nil)
+	exporter.Reset()
+
+	req, _ := http.NewRequest("GET", "/api/ping", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	spans := exporter.GetSpans()
+	serverSpan, ok := findSpan(spans, "GET /api/ping")
+	if assert.True(t, ok, "server span not recorded") {
+		assert.False(t, serverSpan.Parent.IsValid())
+		assert.Contains(t, w.Body.String(), serverSpan.SpanContext.TraceID().String())
+	}
+}
+
+func TestTracingRecordsServiceErrors(t *testing.T) {
+	exporter := recordSpans(t)
+	router := newRouter(t, newSQLiteRepository(t))
+	exporter.Reset()
+
+	w, _ := doRequest(t, router, "GET", "/api/v1/products/"+uuid.NewString(), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	serviceSpan, ok := findSpan(exporter.GetSpans(), "ProductsService.GetProductById")
+	if assert.True(t, ok, "service span not recorded") {
+		assert.Equal(t, "Error", serviceSpan.Status.Code.String())
+		assert.NotEmpty(t, serviceSpan.Events)
+	}
+}
+
+func TestLogrusHookAddsTraceIDs(t *testing.T) {
+	recordSpans(t)
+
+	var output bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&output)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	log.AddHook(tracing.LogrusHook{})
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	log.WithContext(ctx).Info("within a span")
+
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+	assert.Equal(t, span.SpanContext().TraceID().String(), entry["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), entry["span_id"])
+
+	// Entries without a span context are left alone
+	output.Reset()
+	log.WithContext(context.Background()).Info("outside of a span")
+
+	entry = nil
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+	assert.NotContains(t, entry, "trace_id")
+}
@@ -0,0 +1,330 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"simpler-products/models"
+	"simpler-products/repositories"
+	"simpler-products/services"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	custom_errors "simpler-products/errors"
+)
+
+func TestVariantOptions(t *testing.T) {
+	options := []models.ProductOption{
+		{Name: "size", Values: []string{"S", "M"}},
+		{Name: "colour", Values: []string{"red", "navy blue"}},
+	}
+
+	t.Run("Combinations", func(t *testing.T) {
+		assert.Equal(t, []map[string]string{
+			{"size": "S", "colour": "red"},
+			{"size": "S", "colour": "navy blue"},
+			{"size": "M", "colour": "red"},
+			{"size": "M", "colour": "navy blue"},
+		}, models.Combinations(options))
+		assert.Equal(t, []map[string]string{{}}, models.Combinations(nil))
+	})
+
+	t.Run("CountCombinations", func(t *testing.T) {
+		assert.Equal(t, 4, models.CountCombinations(options))
+		assert.Equal(t, 1, models.CountCombinations(nil))
+
+		// Counting stops past the limit rather than overflowing
+		values := make([]string, 30)
+		large := make([]models.ProductOption, 0, 20)
+		for i := 0; i < 20; i++ {
+			large = append(large, models.ProductOption{Name: fmt.Sprint(i), Values: values})
+		}
+		assert.Greater(t, models.CountCombinations(large), models.MaxVariantCombinations)
+		assert.LessOrEqual(t, models.CountCombinations(large), models.MaxVariantCombinations*30)
+	})
+
+	t.Run("Matches", func(t *testing.T) {
+		assert.True(t, models.Variant{Options: map[string]string{"size": "M", "colour": "red"}}.Matches(options))
+		assert.False(t, models.Variant{Options: map[string]string{"size": "M"}}.Matches(options))
+		assert.False(t, models.Variant{Options: map[string]string{"size": "XL", "colour": "red"}}.Matches(options))
+		assert.False(t, models.Variant{Options: map[string]string{"size": "M", "colour": "red", "fit": "slim"}}.Matches(options))
+		assert.True(t, models.Variant{}.Matches(nil))
+	})
+
+	t.Run("OptionsKey", func(t *testing.T) {
+		a := models.Variant{Options: map[string]string{"size": "M", "colour": "red"}}
+		b := models.Variant{Options: map[string]string{"colour": "red", "size": "M"}}
+		c := models.Variant{Options: map[string]string{"size": "S", "colour": "red"}}
+		assert.Equal(t, a.OptionsKey(), b.OptionsKey())
+		assert.NotEqual(t, a.OptionsKey(), c.OptionsKey())
+		assert.Equal(t, models.Variant{}.OptionsKey(), models.Variant{Options: map[string]string{}}.OptionsKey())
+	})
+
+	t.Run("SKUSegment", func(t *testing.T) {
+		assert.Equal(t, "NAVY-BLUE", models.SKUSegment("navy blue"))
+		assert.Equal(t, "T-SHIRT-V2", models.SKUSegment("  T-shirt (v2)! "))
+		assert.Equal(t, "", models.SKUSegment("--"))
+	})
+
+	t.Run("GenerateSKU", func(t *testing.T) {
+		assert.Equal(t, "TEE-M-RED", models.GenerateSKU("TEE", "M", "", "RED"))
+
+		// Long SKUs are cut short, and kept apart by a hash of the whole SKU
+		long := strings.Repeat("LONG-", 60)
+		a, b := models.GenerateSKU(long, "S"), models.GenerateSKU(long, "M")
+		assert.Len(t, a, models.MaxSKULength)
+		assert.Len(t, b, models.MaxSKULength)
+		assert.NotEqual(t, a, b)
+		assert.Equal(t, long[:200], a[:200])
+	})
+}
+
+func TestVariantsService(t *testing.T) {
+	memoryProducts := repositories.NewInMemoryProductRepository()
+	memoryWarehouses := repositories.NewInMemoryWarehouseRepository()
+	memoryVariants := repositories.NewInMemoryVariantRepository()
+	sqliteProducts := newSQLiteRepository(t)
+
+	for name, tc := range map[string]struct {
+		products   repositories.ProductRepository
+		warehouses repositories.WarehouseRepository
+		variants   repositories.VariantRepository
+		stock      repositories.StockRepository
+	}{
+		"InMemory": {memoryProducts, memoryWarehouses, memoryVariants, repositories.NewInMemoryStockRepository(memoryProducts, memoryWarehouses, memoryVariants)},
+		"SQLite": {
+			sqliteProducts,
+			repositories.NewSQLWarehouseRepository(sqliteProducts.DB, repositories.SQLite),
+			repositories.NewSQLVariantRepository(sqliteProducts.DB, repositories.SQLite),
+			repositories.NewSQLStockRepository(sqliteProducts.DB, repositories.SQLite),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			log := logrus.New()
+			variantsService := &services.VariantsService{Repo: tc.variants, Products: tc.products, Stock: tc.stock, Log: log}
+			inventoryService := &services.InventoryService{Repo: tc.stock, Warehouses: tc.warehouses, Variants: tc.variants, Log: log}
+
+			_, err := tc.products.Insert(ctx, "uuid1", &models.Product{Name: "Crew T-Shirt", Description: "Plain cotton", Price: 20})
+			assert.NoError(t, err)
+
+			_, err = variantsService.GetProductOptions(ctx, "missing")
+			assert.ErrorIs(t, err, custom_errors.ErrProductNotFound)
+			options, err := variantsService.GetProductOptions(ctx, "uuid1")
+			assert.NoError(t, err)
+			assert.Empty(t, options)
+
+			options = []models.ProductOption{
+				{Name: "size", Values: []string{"S", "M"}},
+				{Name: "colour", Values: []string{"red", "navy blue"}},
+			}
+			_, err = variantsService.SetProductOptions(ctx, "uuid1", options)
+			assert.NoError(t, err)
+
+			price := 22.5
+			medium := &models.Variant{SKU: "TEE-M-RED", Options: map[string]string{"size": "M", "colour": "red"}, Price: &price}
+
+			t.Run("Add", func(t *testing.T) {
+				assert.NoError(t, variantsService.AddVariant(ctx, "uuid1", medium))
+				assert.NotEmpty(t, medium.ID)
+
+				variant, err := variantsService.GetVariant(ctx, "uuid1", medium.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, medium, variant)
+
+				// SKUs are unique, and so are the combinations of option values
+				err = variantsService.AddVariant(ctx, "uuid1", &models.Variant{SKU: "TEE-M-RED", Options: map[string]string{"size": "S", "colour": "red"}})
+				assert.ErrorIs(t, err, custom_errors.ErrDuplicateSKU)
+				err = variantsService.AddVariant(ctx, "uuid1", &models.Variant{SKU: "TEE-M-RED-2", Options: map[string]string{"colour": "red", "size": "M"}})
+				assert.ErrorIs(t, err, custom_errors.ErrDuplicateVariant)
+				err = variantsService.AddVariant(ctx, "uuid1", &models.Variant{SKU: "TEE-XL-RED", Options: map[string]string{"size": "XL", "colour": "red"}})
+				assert.ErrorIs(t, err, custom_errors.ErrInvalidVariantOptions)
+				// Variants stored together are checked against each other, and stored all or none
+				err = tc.variants.Insert(ctx, []models.Variant{
+					{ID: "variant1", ProductID: "uuid1", SKU: "TEE-S", Options: map[string]string{"size": "S", "colour": "red"}},
+					{ID: "variant2", ProductID: "uuid1", SKU: "TEE-S", Options: map[string]string{"size": "S", "colour": "navy blue"}},
+				})
+				assert.ErrorIs(t, err, custom_errors.ErrDuplicateSKU)
+				err = variantsService.AddVariant(ctx, "missing", &models.Variant{SKU: "TEE-S-RED", Options: map[string]string{"size": "S", "colour": "red"}})
+				assert.ErrorIs(t, err, custom_errors.ErrProductNotFound)
+
+				_, err = variantsService.GetVariant(ctx, "uuid1", "missing")
+				assert.ErrorIs(t, err, custom_errors.ErrVariantNotFound)
+			})
+
+			t.Run("Generate", func(t *testing.T) {
+				generated, err := variantsService.GenerateVariants(ctx, "uuid1", "", nil)
+				assert.NoError(t, err)
+				skus := make([]string, 0, len(generated))
+				for _, variant := range generated {
+					skus = append(skus, variant.SKU)
+				}
+				assert.Equal(t, []string{"CREW-T-SHIRT-S-RED", "CREW-T-SHIRT-S-NAVY-BLUE", "CREW-T-SHIRT-M-NAVY-BLUE"}, skus)
+
+				// Nothing is left to generate
+				generated, err = variantsService.GenerateVariants(ctx, "uuid1", "TEE", nil)
+				assert.NoError(t, err)
+				assert.Empty(t, generated)
+
+				variants, err := variantsService.ListVariants(ctx, "uuid1")
+				assert.NoError(t, err)
+				assert.Len(t, variants, 4)
+
+				// Options with too many combinations are never expanded
+				_, err = tc.products.Insert(ctx, "uuid2", &models.Product{Name: "Poster", Description: "Made to order", Price: 10})
+				assert.NoError(t, err)
+				values := make([]string, 0, 40)
+				for i := 0; i < 40; i++ {
+					values = append(values, fmt.Sprint(i))
+				}
+				assert.NoError(t, tc.variants.SetOptions(ctx, "uuid2", []models.ProductOption{{Name: "width", Values: values}, {Name: "height", Values: values}}))
+				_, err = variantsService.GenerateVariants(ctx, "uuid2", "", nil)
+				assert.ErrorIs(t, err, custom_errors.ErrTooManyVariants)
+
+				// Values sharing their segments get SKUs of their own, names without letters
+				// or digits fall back to the product ID
+				_, err = tc.products.Insert(ctx, "uuid3", &models.Product{Name: "???", Description: "Mystery box", Price: 10})
+				assert.NoError(t, err)
+				assert.NoError(t, tc.variants.SetOptions(ctx, "uuid3", []models.ProductOption{{Name: "fit", Values: []string{"S/M", "S M", "!!"}}}))
+				generated, err = variantsService.GenerateVariants(ctx, "uuid3", "", nil)
+				assert.NoError(t, err)
+				skus = skus[:0]
+				for _, variant := range generated {
+					skus = append(skus, variant.SKU)
+				}
+				assert.Equal(t, []string{"UUID3-S-M", "UUID3-S-M-2", "UUID3"}, skus)
+			})
+
+			t.Run("Update", func(t *testing.T) {
+				updated, err := variantsService.UpdateVariant(ctx, "uuid1", medium.ID, &models.Variant{SKU: "TEE-M-RED", Options: map[string]string{"size": "M", "colour": "red"}})
+				assert.NoError(t, err)
+				assert.Nil(t, updated.Price)
+
+				_, err = variantsService.UpdateVariant(ctx, "uuid1", medium.ID, &models.Variant{SKU: "CREW-T-SHIRT-S-RED", Options: map[string]string{"size": "M", "colour": "red"}})
+				assert.ErrorIs(t, err, custom_errors.ErrDuplicateSKU)
+				_, err = variantsService.UpdateVariant(ctx, "uuid1", medium.ID, &models.Variant{SKU: "TEE-M-RED", Options: map[string]string{"size": "S", "colour": "red"}})
+				assert.ErrorIs(t, err, custom_errors.ErrDuplicateVariant)
+				_, err = variantsService.UpdateVariant(ctx, "uuid1", "missing", &models.Variant{SKU: "TEE-L-RED", Options: map[string]string{"size": "M", "colour": "red"}})
+				assert.ErrorIs(t, err, custom_errors.ErrVariantNotFound)
+			})
+
+			t.Run("Stock", func(t *testing.T) {
+				_, err := inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", VariantID: medium.ID, Type: models.StockAdjust, Quantity: 5})
+				assert.NoError(t, err)
+				_, err = inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", Type: models.StockAdjust, Quantity: 2})
+				assert.NoError(t, err)
+				_, err = inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", VariantID: "missing", Type: models.StockAdjust, Quantity: 1})
+				assert.ErrorIs(t, err, custom_errors.ErrVariantNotFound)
+
+				stock, err := inventoryService.GetVariantStock(ctx, "uuid1", medium.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(5), stock.OnHand)
+				assert.Equal(t, medium.ID, stock.VariantID)
+
+				// The stock of variants is not available to the product itself
+				_, err = inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", Type: models.StockReserve, Quantity: 3})
+				assert.ErrorIs(t, err, custom_errors.ErrInsufficientStock)
+
+				plan, err := inventoryService.AllocateStock(ctx, "uuid1", models.AllocationRequest{VariantID: medium.ID, Quantity: 3, Reserve: true})
+				assert.NoError(t, err)
+				assert.Equal(t, medium.ID, plan.VariantID)
+				assert.True(t, plan.Reserved)
+
+				stock, err = inventoryService.GetStock(ctx, "uuid1")
+				assert.NoError(t, err)
+				assert.Equal(t, int64(7), stock.OnHand)
+				assert.Equal(t, int64(3), stock.Reserved)
+			})
+
+			t.Run("Delete", func(t *testing.T) {
+				// Options cannot be changed from under the variants using them
+				_, err := variantsService.SetProductOptions(ctx, "uuid1", options[:1])
+				assert.ErrorIs(t, err, custom_errors.ErrOptionsInUse)
+
+				assert.ErrorIs(t, variantsService.DeleteVariant(ctx, "uuid1", medium.ID), custom_errors.ErrVariantNotEmpty)
+
+				_, err = inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", VariantID: medium.ID, Type: models.StockCommit, Quantity: 3})
+				assert.NoError(t, err)
+				_, err = inventoryService.MoveStock(ctx, models.StockMovement{ProductID: "uuid1", VariantID: medium.ID, Type: models.StockAdjust, Quantity: -2})
+				assert.NoError(t, err)
+				assert.NoError(t, variantsService.DeleteVariant(ctx, "uuid1", medium.ID))
+				assert.ErrorIs(t, variantsService.DeleteVariant(ctx, "uuid1", medium.ID), custom_errors.ErrVariantNotFound)
+
+				stock, err := inventoryService.GetStock(ctx, "uuid1")
+				assert.NoError(t, err)
+				assert.Equal(t, int64(2), stock.OnHand)
+				assert.Len(t, stock.Warehouses, 1)
+			})
+		})
+	}
+}
+
+func TestSQLVariantRepository(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repositories.NewSQLVariantRepository(db, repositories.MySQL)
+	variant := models.Variant{ID: "variant1", ProductID: "uuid1", SKU: "TEE-M-RED", Options: map[string]string{"size": "M", "colour": "red"}}
+
+	t.Run("Insert", func(t *testing.T) {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("^INSERT INTO Variants \\(id, product_id, sku, options, options_key, price\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?\\)$").
+			WithArgs("variant1", "uuid1", "TEE-M-RED", `{"colour":"red","size":"M"}`, variant.OptionsKey(), nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		assert.NoError(t, repo.Insert(context.Background(), []models.Variant{variant}))
+	})
+
+	t.Run("DuplicateVariant", func(t *testing.T) {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("INSERT INTO Variants").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+		dbMock.ExpectRollback()
+		dbMock.ExpectQuery("^SELECT 1 FROM Variants WHERE sku = \\? AND id <> \\?$").
+			WithArgs("TEE-M-RED", "variant1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}))
+		dbMock.ExpectQuery("^SELECT 1 FROM Variants WHERE product_id = \\? AND options_key = \\? AND id <> \\?$").
+			WithArgs("uuid1", variant.OptionsKey(), "variant1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		assert.ErrorIs(t, repo.Insert(context.Background(), []models.Variant{variant}), custom_errors.ErrDuplicateVariant)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		dbMock.ExpectQuery("^SELECT id, product_id, sku, options, price FROM Variants WHERE id = \\? AND product_id = \\?$").
+			WithArgs("variant1", "uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "sku", "options", "price"}).
+				AddRow("variant1", "uuid1", "TEE-M-RED", `{"colour":"red","size":"M"}`, 22.5))
+
+		got, err := repo.Get(context.Background(), "uuid1", "variant1")
+
+		assert.NoError(t, err)
+		price := 22.5
+		expected := variant
+		expected.Price = &price
+		assert.Equal(t, &expected, got)
+	})
+
+	t.Run("OptionsInUse", func(t *testing.T) {
+		// Variants are checked within the transaction replacing the options
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("^DELETE FROM ProductOptions WHERE product_id = \\?$").WithArgs("uuid1").WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery("^SELECT id, product_id, sku, options, price FROM Variants WHERE product_id = \\? ORDER BY sku$").
+			WithArgs("uuid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "sku", "options", "price"}).
+				AddRow("variant1", "uuid1", "TEE-M-RED", `{"colour":"red","size":"M"}`, nil))
+		dbMock.ExpectRollback()
+
+		err := repo.SetOptions(context.Background(), "uuid1", []models.ProductOption{{Name: "size", Values: []string{"S", "M"}}})
+		assert.ErrorIs(t, err, custom_errors.ErrOptionsInUse)
+	})
+
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogrusHook adds the trace and span IDs of the entry's context to every log entry
+// created with WithContext, so that logs can be correlated with traces
+type LogrusHook struct{}
+
+func (LogrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (LogrusHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	spanContext := trace.SpanContextFromContext(entry.Context)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	entry.Data["trace_id"] = spanContext.TraceID().String()
+	entry.Data["span_id"] = spanContext.SpanID().String()
+
+	return nil
+}
+
+// TraceID returns the ID of the trace ctx belongs to, or an empty string outside of a trace
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	ServiceName = "simpler-products"
+
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// ShutdownFunc flushes the spans still buffered and stops the exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Init installs the global W3C trace context propagator and a tracer provider sending
+// spans to the given exporter. With ExporterNone spans are not recorded, but incoming
+// trace context is still propagated so that trace IDs show up in logs and responses.
+//
+// The OTLP exporter is configured through the standard OTEL_EXPORTER_OTLP_* variables,
+// e.g. OTEL_EXPORTER_OTLP_ENDPOINT, and sampling through OTEL_TRACES_SAMPLER.
+func Init(ctx context.Context, exporterName string, log *logrus.Logger) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %s", exporterName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	log.Infof("Exporting traces to %s", exporterName)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider describing this service
+func NewProvider(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		// Only happens on conflicting schema URLs, fall back to the default resource
+		res = resource.Default()
+	}
+
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithResource(res)}, opts...)...)
+}
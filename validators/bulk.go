@@ -0,0 +1,120 @@
+package validators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"simpler-products/models"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBulkOperations bounds the number of operations of a bulk request
+const MaxBulkOperations = 1000
+
+// BulkOperations are the operations a bulk request can hold
+var BulkOperations = []string{models.BulkCreate, models.BulkUpdate, models.BulkDelete}
+
+// BulkModes are the modes a bulk request can run in
+var BulkModes = []string{models.BulkAllOrNothing, models.BulkBestEffort}
+
+// BulkRequest is a parsed bulk request
+type BulkRequest struct {
+	Mode       string
+	Operations []models.BulkOperation
+	// Invalid holds the validation errors of the invalid operations, by position
+	Invalid map[int]*ValidationError
+}
+
+// ValidateBulkRequest parses a bulk request such as {"mode": "best_effort", "operations":
+// [{"op": "create", "product": {...}}, {"op": "delete", "id": "..."}]}. Requests that cannot
+// be processed at all, with no or too many operations or an unknown mode, are rejected.
+// Otherwise each operation is validated on its own, and the invalid ones are reported in
+// the returned request along with the valid ones.
+func ValidateBulkRequest(c *gin.Context) (*BulkRequest, error) {
+	var body struct {
+		Mode       string            `json:"mode"`
+		Operations []json.RawMessage `json:"operations"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+		res := fmt.Errorf("invalid bulk request: %w", err)
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", res)
+		return nil, res
+	}
+
+	out := make([]map[string]string, 0)
+	if body.Mode == "" {
+		body.Mode = models.BulkAllOrNothing
+	}
+	if !slices.Contains(BulkModes, body.Mode) {
+		out = append(out, map[string]string{
+			"message": fmt.Sprintf("mode must be one of: %s", strings.Join(BulkModes, ", ")),
+		})
+	}
+	if len(body.Operations) == 0 || len(body.Operations) > MaxBulkOperations {
+		out = append(out, map[string]string{
+			"message": fmt.Sprintf("operations must hold between 1 and %d operations", MaxBulkOperations),
+		})
+	}
+	if len(out) > 0 {
+		return nil, validationError(c, out)
+	}
+
+	request := &BulkRequest{
+		Mode:       body.Mode,
+		Operations: make([]models.BulkOperation, len(body.Operations)),
+		Invalid:    make(map[int]*ValidationError),
+	}
+	for i, raw := range body.Operations {
+		if errs := validateBulkOperation(raw, &request.Operations[i]); len(errs) > 0 {
+			request.Invalid[i] = &ValidationError{Errors: errs}
+		}
+	}
+
+	return request, nil
+}
+
+// validateBulkOperation decodes a bulk operation into operation and returns its validation errors
+func validateBulkOperation(raw json.RawMessage, operation *models.BulkOperation) []map[string]string {
+	out := make([]map[string]string, 0)
+	fail := func(format string, args ...any) []map[string]string {
+		return append(out, map[string]string{
+			"message": fmt.Sprintf(format, args...),
+		})
+	}
+
+	if err := json.Unmarshal(raw, operation); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return fail("%s is invalid", typeErr.Field)
+		}
+		return fail("operation must be an object")
+	}
+
+	if !slices.Contains(BulkOperations, operation.Op) {
+		return fail("op must be one of: %s", strings.Join(BulkOperations, ", "))
+	}
+
+	switch {
+	case operation.Op == models.BulkCreate && operation.ID != "":
+		out = fail("id cannot be set when creating a product")
+	case operation.Op != models.BulkCreate && operation.ID == "":
+		out = fail("id is required to %s a product", operation.Op)
+	}
+	if operation.Version < 0 {
+		out = fail("version must be a positive number")
+	}
+
+	if operation.Op == models.BulkDelete {
+		return out
+	}
+	if operation.Product == nil {
+		return fail("product is required to %s a product", operation.Op)
+	}
+
+	return append(out, ValidateProductFields(operation.Product)...)
+}
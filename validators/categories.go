@@ -0,0 +1,63 @@
+package validators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"simpler-products/models"
+
+	custom_errors "simpler-products/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+func ValidateCategoryID(c *gin.Context) (string, error) {
+	id := c.Param("id")
+	if id == "" {
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", custom_errors.ErrInvalidCategoryID)
+		return "", custom_errors.ErrInvalidCategoryID
+	}
+
+	return id, nil
+}
+
+func ValidateCategory(c *gin.Context) (*models.Category, error) {
+	var category models.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			return nil, validationError(c, validationMessages(ve))
+		}
+		c.Set("errors", err)
+		return nil, err
+	}
+	return &category, nil
+}
+
+// ValidateProductCategories parses the categories a product is assigned to, sent as
+// {"category_ids": ["...", ...]}. An empty list unassigns the product from every category.
+func ValidateProductCategories(c *gin.Context) ([]string, error) {
+	var body struct {
+		CategoryIDs *[]string `json:"category_ids"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+		res := fmt.Errorf("invalid request body: %w", err)
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", res)
+		return nil, res
+	}
+
+	if body.CategoryIDs == nil {
+		return nil, validationError(c, []map[string]string{{"message": "category_ids is required"}})
+	}
+	for _, id := range *body.CategoryIDs {
+		if id == "" {
+			return nil, validationError(c, []map[string]string{{"message": "category_ids must not hold empty IDs"}})
+		}
+	}
+
+	return *body.CategoryIDs, nil
+}
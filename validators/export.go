@@ -0,0 +1,22 @@
+package validators
+
+import (
+	"net/http"
+	"simpler-products/models"
+
+	custom_errors "simpler-products/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateExportFormat returns the format requested with the format parameter, CSV by default
+func ValidateExportFormat(c *gin.Context) (string, error) {
+	switch format := c.DefaultQuery("format", models.FormatCSV); format {
+	case models.FormatCSV, models.FormatTSV, models.FormatNDJSON:
+		return format, nil
+	default:
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", custom_errors.ErrInvalidExportFormat)
+		return "", custom_errors.ErrInvalidExportFormat
+	}
+}
@@ -0,0 +1,111 @@
+package validators
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"simpler-products/models"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FacetNames are the facets listings and searches can return
+var FacetNames = []string{"price"}
+
+const (
+	// maxPriceBuckets bounds the number of price buckets, however they are defined
+	maxPriceBuckets = 20
+	// defaultPriceQuantiles is the number of price buckets when their edges are not given
+	defaultPriceQuantiles = 4
+)
+
+// ValidateFacetQuery parses the facet parameters of a listing or a search, e.g.
+// ?facets=price&price_buckets=10,50,100 or ?facets=price&price_quantiles=5, reporting
+// every invalid parameter at once. It returns nil when no facet is requested.
+func ValidateFacetQuery(c *gin.Context) (*models.FacetQuery, error) {
+	var query models.FacetQuery
+	out := make([]map[string]string, 0)
+	fail := func(format string, args ...any) {
+		out = append(out, map[string]string{
+			"message": fmt.Sprintf(format, args...),
+		})
+	}
+
+	requested := make(map[string]bool)
+	if raw, ok := c.GetQuery("facets"); ok {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(FacetNames, name) {
+				fail("cannot compute facet '%s', allowed facets are: %s", name, strings.Join(FacetNames, ", "))
+				continue
+			}
+			requested[name] = true
+		}
+	}
+
+	rawEdges, hasEdges := c.GetQuery("price_buckets")
+	rawQuantiles, hasQuantiles := c.GetQuery("price_quantiles")
+	switch {
+	case (hasEdges || hasQuantiles) && !requested["price"]:
+		fail("price_buckets and price_quantiles require facets=price")
+	case hasEdges && hasQuantiles:
+		fail("price_buckets and price_quantiles cannot be combined")
+	case hasEdges:
+		edges, ok := parsePriceEdges(rawEdges)
+		if !ok {
+			fail("price_buckets must be a comma-separated list of up to %d increasing non-negative numbers", maxPriceBuckets-1)
+			break
+		}
+		query.Price = &models.PriceBuckets{Edges: edges}
+	case hasQuantiles:
+		quantiles, err := strconv.Atoi(rawQuantiles)
+		if err != nil || quantiles < 2 || quantiles > maxPriceBuckets {
+			fail("price_quantiles must be a number between 2 and %d", maxPriceBuckets)
+			break
+		}
+		query.Price = &models.PriceBuckets{Quantiles: quantiles}
+	case requested["price"]:
+		query.Price = &models.PriceBuckets{Quantiles: defaultPriceQuantiles}
+	}
+
+	if len(out) > 0 {
+		res := &ValidationError{
+			Errors: out,
+		}
+
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", res)
+		return nil, res
+	}
+
+	if len(requested) == 0 {
+		return nil, nil
+	}
+
+	return &query, nil
+}
+
+// parsePriceEdges parses the edges of price buckets, which must be strictly increasing
+func parsePriceEdges(raw string) ([]float64, bool) {
+	parts := strings.Split(raw, ",")
+	if len(parts) >= maxPriceBuckets {
+		return nil, false
+	}
+
+	edges := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		edge, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(edge) || math.IsInf(edge, 0) || edge < 0 {
+			return nil, false
+		}
+		if len(edges) > 0 && edge <= edges[len(edges)-1] {
+			return nil, false
+		}
+		edges = append(edges, edge)
+	}
+
+	return edges, true
+}
@@ -0,0 +1,30 @@
+package validators
+
+import (
+	"net/http"
+	"simpler-products/models"
+
+	custom_errors "simpler-products/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Media types of the supported import formats
+const (
+	CSVContentType    = "text/csv"
+	NDJSONContentType = "application/x-ndjson"
+)
+
+// ValidateImportFormat returns the format of the file in the request body, from its Content-Type
+func ValidateImportFormat(c *gin.Context) (string, error) {
+	switch c.ContentType() {
+	case CSVContentType:
+		return models.FormatCSV, nil
+	case NDJSONContentType, "application/ndjson":
+		return models.FormatNDJSON, nil
+	default:
+		c.Status(http.StatusUnsupportedMediaType)
+		c.Set("errors", custom_errors.ErrUnsupportedImportType)
+		return "", custom_errors.ErrUnsupportedImportType
+	}
+}
@@ -0,0 +1,103 @@
+package validators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"simpler-products/models"
+
+	custom_errors "simpler-products/errors"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Media types of the supported patch formats
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// ValidateProductPatch applies the patch in the request body to current, as a JSON Merge
+// Patch (RFC 7386) or a JSON Patch (RFC 6902) depending on the Content-Type, plain JSON
+// being taken as a merge patch. The patched product is sanitized and validated like a full
+// one, and the fields the patch changes are returned.
+func ValidateProductPatch(c *gin.Context, current models.Product) (*models.ProductPatch, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Set("errors", err)
+		return nil, err
+	}
+
+	document, err := json.Marshal(current)
+	if err != nil {
+		c.Set("errors", err)
+		return nil, err
+	}
+
+	var patched []byte
+	switch c.ContentType() {
+	case MergePatchContentType, binding.MIMEJSON:
+		patched, err = jsonpatch.MergePatch(document, body)
+	case JSONPatchContentType:
+		var patch jsonpatch.Patch
+		if patch, err = jsonpatch.DecodePatch(body); err == nil {
+			patched, err = patch.Apply(document)
+		}
+	default:
+		c.Status(http.StatusUnsupportedMediaType)
+		c.Set("errors", custom_errors.ErrUnsupportedPatchType)
+		return nil, custom_errors.ErrUnsupportedPatchType
+	}
+	if err != nil {
+		return nil, patchError(c, err)
+	}
+
+	var product models.Product
+	if err := json.Unmarshal(patched, &product); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return nil, validationError(c, []map[string]string{{"message": fmt.Sprintf("%s is invalid", typeErr.Field)}})
+		}
+		return nil, patchError(c, errors.New("the patched document is not a product"))
+	}
+	// Patch media types are not sanitized by the XSS middleware
+	SanitizeProduct(&product)
+
+	out := make([]map[string]string, 0)
+	if product.ID != current.ID {
+		out = append(out, map[string]string{"message": "id cannot be changed"})
+	}
+	var ve validator.ValidationErrors
+	if err := binding.Validator.ValidateStruct(&product); errors.As(err, &ve) {
+		out = append(out, validationMessages(ve)...)
+	} else if err != nil {
+		c.Set("errors", err)
+		return nil, err
+	}
+	if len(out) > 0 {
+		return nil, validationError(c, out)
+	}
+
+	patch := models.DiffProducts(current, product)
+	return &patch, nil
+}
+
+// patchError reports a patch that cannot be applied. A failed test operation means the
+// product does not hold the expected values, which is a conflict rather than a bad request.
+func patchError(c *gin.Context, err error) error {
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		c.Status(http.StatusConflict)
+		c.Set("errors", custom_errors.ErrPatchTestFailed)
+		return custom_errors.ErrPatchTestFailed
+	}
+
+	res := fmt.Errorf("%w: %v", custom_errors.ErrInvalidPatch, err)
+	c.Status(http.StatusBadRequest)
+	c.Set("errors", res)
+	return res
+}
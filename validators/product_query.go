@@ -0,0 +1,104 @@
+package validators
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"simpler-products/models"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxNameFilterLength matches the length of the name column
+const maxNameFilterLength = 255
+
+// ValidateProductQuery parses the filter and sort parameters of a product listing,
+// e.g. ?name=phone&price_min=10&price_max=100&sort=price,-name, reporting every
+// invalid parameter at once. Pagination is left to the caller.
+func ValidateProductQuery(c *gin.Context) (*models.ProductQuery, error) {
+	query, out := ParseProductQuery(c.Request.URL.Query())
+	if len(out) > 0 {
+		return nil, validationError(c, out)
+	}
+
+	return query, nil
+}
+
+// ParseProductQuery is ValidateProductQuery for parameters that are not read from a request,
+// it returns the query or the validation errors
+func ParseProductQuery(params url.Values) (*models.ProductQuery, []map[string]string) {
+	var query models.ProductQuery
+	out := make([]map[string]string, 0)
+	fail := func(format string, args ...any) {
+		out = append(out, map[string]string{
+			"message": fmt.Sprintf(format, args...),
+		})
+	}
+
+	query.Filter.Name = strings.TrimSpace(params.Get("name"))
+	if len(query.Filter.Name) > maxNameFilterLength {
+		fail("name must be at most %d characters long", maxNameFilterLength)
+	}
+
+	query.Filter.Category = strings.TrimSpace(params.Get("category"))
+
+	for _, param := range []struct {
+		name  string
+		value **float64
+	}{
+		{"price_min", &query.Filter.PriceMin},
+		{"price_max", &query.Filter.PriceMax},
+	} {
+		if !params.Has(param.name) {
+			continue
+		}
+
+		raw := params.Get(param.name)
+		price, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(price) || math.IsInf(price, 0) || price < 0 {
+			fail("%s must be a non-negative number", param.name)
+			continue
+		}
+		*param.value = &price
+	}
+
+	if query.Filter.PriceMin != nil && query.Filter.PriceMax != nil && *query.Filter.PriceMin > *query.Filter.PriceMax {
+		fail("price_min must not be greater than price_max")
+	}
+
+	if params.Has("sort") {
+		raw := params.Get("sort")
+		seen := make(map[string]bool)
+		for _, term := range strings.Split(raw, ",") {
+			term = strings.TrimSpace(term)
+			field := strings.TrimPrefix(term, "-")
+			if field == "" {
+				fail("sort must be a comma-separated list of fields, each optionally prefixed with '-' for descending order")
+				continue
+			}
+			if !slices.Contains(models.ProductSortFields, field) {
+				fail("cannot sort by '%s', allowed fields are: %s", field, strings.Join(models.ProductSortFields, ", "))
+				continue
+			}
+			if seen[field] {
+				fail("cannot sort by '%s' more than once", field)
+				continue
+			}
+
+			seen[field] = true
+			query.Sort = append(query.Sort, models.SortField{
+				Field:      field,
+				Descending: strings.HasPrefix(term, "-"),
+			})
+		}
+	}
+
+	if len(out) > 0 {
+		return nil, out
+	}
+
+	return &query, nil
+}
@@ -11,12 +11,17 @@ import (
 
 	custom_errors "simpler-products/errors"
 
+	xss "github.com/dvwright/xss-mw"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 )
 
 var validate *validator.Validate
 
+// sanitizer strips markup with the policy the XSS middleware applies to JSON request bodies
+var sanitizer = (&xss.XssMw{}).GetBlueMondayPolicy()
+
 func ValidateProductID(c *gin.Context) (string, error) {
 	id := c.Param("id")
 	if id == "" {
@@ -33,23 +38,7 @@ func ValidateProduct(c *gin.Context) (*models.Product, error) {
 	if err := c.ShouldBindJSON(&product); err != nil {
 		var ve validator.ValidationErrors
 		if errors.As(err, &ve) {
-			out := make([]map[string]string, 0)
-			for _, fe := range ve {
-				errorMsg := getErrorMessage(fe)
-				if errorMsg != "" {
-					out = append(out, map[string]string{
-						"message": errorMsg,
-					})
-				}
-			}
-
-			res := &ValidationError{
-				Errors: out,
-			}
-
-			c.Status(http.StatusBadRequest)
-			c.Set("errors", res)
-			return nil, res
+			return nil, validationError(c, validationMessages(ve))
 		}
 		c.Set("errors", err)
 		return nil, err
@@ -57,6 +46,52 @@ func ValidateProduct(c *gin.Context) (*models.Product, error) {
 	return &product, nil
 }
 
+// SanitizeProduct strips markup from the text fields of product, like the XSS middleware does
+// for the products sent as JSON, for products it does not see, such as patched ones
+func SanitizeProduct(product *models.Product) {
+	product.Name = sanitizer.Sanitize(product.Name)
+	product.Description = sanitizer.Sanitize(product.Description)
+}
+
+// ValidateProductFields checks product against the same rules as ValidateProduct, for products
+// that are not read from a JSON request body, and returns the validation errors
+func ValidateProductFields(product *models.Product) []map[string]string {
+	var ve validator.ValidationErrors
+	if err := binding.Validator.ValidateStruct(product); errors.As(err, &ve) {
+		return validationMessages(ve)
+	} else if err != nil {
+		return []map[string]string{{"message": "product is invalid"}}
+	}
+
+	return nil
+}
+
+// validationMessages formats the failures of struct validation
+func validationMessages(ve validator.ValidationErrors) []map[string]string {
+	out := make([]map[string]string, 0)
+	for _, fe := range ve {
+		errorMsg := getErrorMessage(fe)
+		if errorMsg != "" {
+			out = append(out, map[string]string{
+				"message": errorMsg,
+			})
+		}
+	}
+
+	return out
+}
+
+// validationError reports the given messages as a bad request
+func validationError(c *gin.Context, out []map[string]string) *ValidationError {
+	res := &ValidationError{
+		Errors: out,
+	}
+
+	c.Status(http.StatusBadRequest)
+	c.Set("errors", res)
+	return res
+}
+
 type ValidationError struct {
 	Errors []map[string]string `json:"errors"`
 }
@@ -71,6 +106,8 @@ func getErrorMessage(fe validator.FieldError) string {
 		return fmt.Sprintf("%s is required", fe.Field())
 	case "gt":
 		return fmt.Sprintf("%s must be greater than %s", fe.Field(), fe.Param())
+	case "max":
+		return fmt.Sprintf("%s must be at most %s characters long", fe.Field(), fe.Param())
 	default:
 		return fmt.Sprintf("%s is invalid", fe.Field())
 	}
@@ -0,0 +1,187 @@
+package validators
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"simpler-products/allocation"
+	"simpler-products/models"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxStockReasonLength bounds the reason recorded along with a stock movement
+const MaxStockReasonLength = 255
+
+// ValidateStockMovement parses a movement of the given type of the stock of the product of the
+// request, sent as {"quantity": 5, "variant_id": "...", "warehouse_id": "...", "reason": "..."}.
+// Adjustments take a positive or negative quantity, the other movements a positive one. Without
+// variant_id, the stock of the product itself is moved, and without warehouse_id, the stock in
+// the default warehouse.
+func ValidateStockMovement(c *gin.Context, movementType string) (*models.StockMovement, error) {
+	id, err := ValidateProductID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Quantity    *int64 `json:"quantity"`
+		VariantID   string `json:"variant_id"`
+		WarehouseID string `json:"warehouse_id"`
+		Reason      string `json:"reason"`
+	}
+	if err := decodeBody(c, &body); err != nil {
+		return nil, err
+	}
+
+	out := make([]map[string]string, 0)
+	switch {
+	case body.Quantity == nil:
+		out = append(out, map[string]string{"message": "quantity is required"})
+	case movementType == models.StockAdjust && *body.Quantity == 0:
+		out = append(out, map[string]string{"message": "quantity must not be 0"})
+	case movementType != models.StockAdjust && *body.Quantity <= 0:
+		out = append(out, map[string]string{"message": "quantity must be greater than 0"})
+	}
+	out = append(out, reasonMessages(body.Reason)...)
+	if len(out) > 0 {
+		return nil, validationError(c, out)
+	}
+
+	return &models.StockMovement{
+		ProductID:   id,
+		VariantID:   body.VariantID,
+		WarehouseID: body.WarehouseID,
+		Type:        movementType,
+		Quantity:    *body.Quantity,
+		Reason:      body.Reason,
+	}, nil
+}
+
+// ValidateStockTransfer parses a transfer of the stock of the product of the request between
+// two warehouses, sent as {"from": "...", "to": "...", "quantity": 5, "variant_id": "...",
+// "reason": "..."}
+func ValidateStockTransfer(c *gin.Context) (*models.StockTransfer, error) {
+	id, err := ValidateProductID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		From      string `json:"from"`
+		To        string `json:"to"`
+		Quantity  *int64 `json:"quantity"`
+		VariantID string `json:"variant_id"`
+		Reason    string `json:"reason"`
+	}
+	if err := decodeBody(c, &body); err != nil {
+		return nil, err
+	}
+
+	out := make([]map[string]string, 0)
+	switch {
+	case body.From == "" || body.To == "":
+		out = append(out, map[string]string{"message": "from and to are required"})
+	case body.From == body.To:
+		out = append(out, map[string]string{"message": "from and to must be different warehouses"})
+	}
+	switch {
+	case body.Quantity == nil:
+		out = append(out, map[string]string{"message": "quantity is required"})
+	case *body.Quantity <= 0:
+		out = append(out, map[string]string{"message": "quantity must be greater than 0"})
+	}
+	out = append(out, reasonMessages(body.Reason)...)
+	if len(out) > 0 {
+		return nil, validationError(c, out)
+	}
+
+	return &models.StockTransfer{
+		ProductID: id,
+		VariantID: body.VariantID,
+		From:      body.From,
+		To:        body.To,
+		Quantity:  *body.Quantity,
+		Reason:    body.Reason,
+	}, nil
+}
+
+// ValidateAllocationRequest parses a request to allocate stock of the product of the request,
+// sent as {"quantity": 5, "variant_id": "...", "strategy": "nearest", "region": "EU-DE",
+// "split": true, "reserve": true, "reason": "..."}. Only quantity is required, the nearest
+// strategy requires region too.
+func ValidateAllocationRequest(c *gin.Context) (string, *models.AllocationRequest, error) {
+	id, err := ValidateProductID(c)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var body struct {
+		Quantity  *int64 `json:"quantity"`
+		VariantID string `json:"variant_id"`
+		Strategy  string `json:"strategy"`
+		Region    string `json:"region"`
+		Split     *bool  `json:"split"`
+		Reserve   bool   `json:"reserve"`
+		Reason    string `json:"reason"`
+	}
+	if err := decodeBody(c, &body); err != nil {
+		return "", nil, err
+	}
+
+	out := make([]map[string]string, 0)
+	switch {
+	case body.Quantity == nil:
+		out = append(out, map[string]string{"message": "quantity is required"})
+	case *body.Quantity <= 0:
+		out = append(out, map[string]string{"message": "quantity must be greater than 0"})
+	}
+	switch {
+	case body.Strategy != "" && !slices.Contains(allocation.Strategies, body.Strategy):
+		out = append(out, map[string]string{
+			"message": fmt.Sprintf("strategy must be one of: %s", strings.Join(allocation.Strategies, ", ")),
+		})
+	case body.Strategy == models.AllocateNearest && body.Region == "":
+		out = append(out, map[string]string{"message": "region is required by the nearest strategy"})
+	}
+	out = append(out, reasonMessages(body.Reason)...)
+	if len(out) > 0 {
+		return "", nil, validationError(c, out)
+	}
+
+	return id, &models.AllocationRequest{
+		VariantID: body.VariantID,
+		Quantity:  *body.Quantity,
+		Strategy:  body.Strategy,
+		Region:    body.Region,
+		Split:     body.Split,
+		Reserve:   body.Reserve,
+		Reason:    body.Reason,
+	}, nil
+}
+
+// decodeBody decodes the JSON body of the request into v, and sets a bad request otherwise
+func decodeBody(c *gin.Context, v any) error {
+	if err := json.NewDecoder(c.Request.Body).Decode(v); err != nil {
+		res := fmt.Errorf("invalid request body: %w", err)
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", res)
+		return res
+	}
+
+	return nil
+}
+
+// reasonMessages returns the validation messages of the reason recorded along with movements
+func reasonMessages(reason string) []map[string]string {
+	if utf8.RuneCountInString(reason) > MaxStockReasonLength {
+		return []map[string]string{{
+			"message": fmt.Sprintf("reason must be at most %d characters long", MaxStockReasonLength),
+		}}
+	}
+
+	return nil
+}
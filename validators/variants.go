@@ -0,0 +1,123 @@
+package validators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"simpler-products/models"
+	"slices"
+	"unicode/utf8"
+
+	custom_errors "simpler-products/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+func ValidateVariantID(c *gin.Context) (string, error) {
+	id := c.Param("variant_id")
+	if id == "" {
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", custom_errors.ErrInvalidVariantID)
+		return "", custom_errors.ErrInvalidVariantID
+	}
+
+	return id, nil
+}
+
+func ValidateVariant(c *gin.Context) (*models.Variant, error) {
+	var variant models.Variant
+	if err := c.ShouldBindJSON(&variant); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			return nil, validationError(c, validationMessages(ve))
+		}
+		c.Set("errors", err)
+		return nil, err
+	}
+
+	// Variants of products without options have no options
+	if variant.Options == nil {
+		variant.Options = make(map[string]string)
+	}
+
+	return &variant, nil
+}
+
+// ValidateProductOptions parses the options of a product, sent as
+// {"options": [{"name": "size", "values": ["S", "M", "L"]}, ...]}. Option names are unique,
+// and so are the values of an option. Options, their values and their combinations are
+// bounded by models.MaxProductOptions, models.MaxOptionValues and models.MaxVariantCombinations,
+// the length of names and values by models.MaxOptionNameLength and models.MaxOptionValueLength.
+func ValidateProductOptions(c *gin.Context) ([]models.ProductOption, error) {
+	var body struct {
+		Options *[]models.ProductOption `json:"options"`
+	}
+	if err := decodeBody(c, &body); err != nil {
+		return nil, err
+	}
+
+	if body.Options == nil {
+		return nil, validationError(c, []map[string]string{{"message": "options is required"}})
+	}
+
+	if len(*body.Options) > models.MaxProductOptions {
+		return nil, validationError(c, []map[string]string{{"message": fmt.Sprintf("a product has at most %d options", models.MaxProductOptions)}})
+	}
+
+	out := make([]map[string]string, 0)
+	names := make([]string, 0, len(*body.Options))
+	for _, option := range *body.Options {
+		switch {
+		case option.Name == "":
+			out = append(out, map[string]string{"message": "options must have a name"})
+		case utf8.RuneCountInString(option.Name) > models.MaxOptionNameLength:
+			out = append(out, map[string]string{"message": fmt.Sprintf("option names must be at most %d characters long", models.MaxOptionNameLength)})
+		case slices.Contains(names, option.Name):
+			out = append(out, map[string]string{"message": fmt.Sprintf("option %q is given more than once", option.Name)})
+		case len(option.Values) == 0:
+			out = append(out, map[string]string{"message": fmt.Sprintf("option %q must have values", option.Name)})
+		case len(option.Values) > models.MaxOptionValues:
+			out = append(out, map[string]string{"message": fmt.Sprintf("option %q has more than %d values", option.Name, models.MaxOptionValues)})
+		case slices.Contains(option.Values, ""):
+			out = append(out, map[string]string{"message": fmt.Sprintf("option %q must not have empty values", option.Name)})
+		case slices.ContainsFunc(option.Values, func(value string) bool { return utf8.RuneCountInString(value) > models.MaxOptionValueLength }):
+			out = append(out, map[string]string{"message": fmt.Sprintf("values of option %q must be at most %d characters long", option.Name, models.MaxOptionValueLength)})
+		case len(slices.Compact(slices.Sorted(slices.Values(option.Values)))) != len(option.Values):
+			out = append(out, map[string]string{"message": fmt.Sprintf("option %q has a value more than once", option.Name)})
+		}
+		names = append(names, option.Name)
+	}
+	if len(out) > 0 {
+		return nil, validationError(c, out)
+	}
+
+	if models.CountCombinations(*body.Options) > models.MaxVariantCombinations {
+		return nil, validationError(c, []map[string]string{{"message": fmt.Sprintf("options have more than %d combinations", models.MaxVariantCombinations)}})
+	}
+
+	return *body.Options, nil
+}
+
+// ValidateVariantGeneration parses the settings of the variants generated for a product, sent
+// as {"sku_prefix": "LAMP", "price": 30}. Both are optional, and so is the body.
+func ValidateVariantGeneration(c *gin.Context) (string, *float64, error) {
+	var body struct {
+		SKUPrefix string   `json:"sku_prefix"`
+		Price     *float64 `json:"price"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		res := fmt.Errorf("invalid request body: %w", err)
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", res)
+		return "", nil, res
+	}
+
+	if body.Price != nil && *body.Price <= 0 {
+		return "", nil, validationError(c, []map[string]string{{"message": "price must be greater than 0"}})
+	}
+
+	return body.SKUPrefix, body.Price, nil
+}
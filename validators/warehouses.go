@@ -0,0 +1,36 @@
+package validators
+
+import (
+	"errors"
+	"net/http"
+	"simpler-products/models"
+
+	custom_errors "simpler-products/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+func ValidateWarehouseID(c *gin.Context) (string, error) {
+	id := c.Param("id")
+	if id == "" {
+		c.Status(http.StatusBadRequest)
+		c.Set("errors", custom_errors.ErrInvalidWarehouseID)
+		return "", custom_errors.ErrInvalidWarehouseID
+	}
+
+	return id, nil
+}
+
+func ValidateWarehouse(c *gin.Context) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	if err := c.ShouldBindJSON(&warehouse); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			return nil, validationError(c, validationMessages(ve))
+		}
+		c.Set("errors", err)
+		return nil, err
+	}
+	return &warehouse, nil
+}